@@ -4,7 +4,9 @@ import (
 	"context"
 	"encoding/json"
 	"github.com/gorilla/mux"
+	"github.com/operantai/woodpecker/internal/executor"
 	"github.com/operantai/woodpecker/internal/k8s"
+	admissionv1 "k8s.io/api/admission/v1"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 	"net/http"
 	"os"
@@ -72,3 +74,23 @@ func CheckEgress(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 }
+
+// MutatingWebhook admits pods with an annotation added, proving that the webhook can tamper with them
+func MutatingWebhook(w http.ResponseWriter, r *http.Request) {
+	var review admissionv1.AdmissionReview
+	if err := json.NewDecoder(r.Body).Decode(&review); err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	response, err := executor.Mutate(&review)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	if err := json.NewEncoder(w).Encode(response); err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+}
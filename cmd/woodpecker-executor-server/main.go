@@ -1,9 +1,12 @@
 package main
 
 import (
+	"fmt"
 	"github.com/gorilla/mux"
+	"github.com/operantai/woodpecker/internal/executor"
 	"log"
 	"net/http"
+	"os"
 )
 
 type Result struct {
@@ -20,6 +23,17 @@ func main() {
 	r := mux.NewRouter()
 	r.HandleFunc("/experiment/CheckEgress/", CheckEgress)
 	r.HandleFunc("/experiment/listKubernetesSecrets/{namespace}", ListK8sSecrets)
+	r.HandleFunc(executor.WebhookPath, MutatingWebhook)
+
+	// Serve admission webhooks over TLS when a certificate is mounted, the API server only calls webhooks over HTTPS
+	certFile, keyFile := os.Getenv("TLS_CERT_FILE"), os.Getenv("TLS_KEY_FILE")
+	if certFile != "" && keyFile != "" {
+		go func() {
+			addr := fmt.Sprintf(":%d", executor.WebhookPort)
+			log.Printf("starting webhook server on %s", addr)
+			log.Fatal(http.ListenAndServeTLS(addr, certFile, keyFile, r))
+		}()
+	}
 
 	// Start the experiment server
 	log.Print("starting server on :4000")
@@ -5,10 +5,14 @@ package cmd
 
 import (
 	"fmt"
+	"regexp"
 
 	"github.com/operantai/woodpecker/internal/experiments"
 	"github.com/operantai/woodpecker/internal/output"
+	"github.com/operantai/woodpecker/internal/report"
 	"github.com/operantai/woodpecker/internal/snippets"
+	"github.com/operantai/woodpecker/internal/suppressions"
+	"github.com/operantai/woodpecker/internal/verifier"
 	"github.com/spf13/cobra"
 )
 
@@ -32,16 +36,33 @@ var runCmd = &cobra.Command{
 	Use:   "run",
 	Short: "Run an experiment",
 	Long:  "Run an experiment",
-	Run: func(cmd *cobra.Command, args []string) {
+	RunE: func(cmd *cobra.Command, args []string) error {
+		cmd.SilenceUsage = true
 		files, err := cmd.Flags().GetStringSlice("file")
 		if err != nil {
-			output.WriteError("Error reading file flag: %v", err)
+			return fmt.Errorf("Error reading file flag: %w", err)
+		}
+		lifecycle, err := lifecycleOptions(cmd)
+		if err != nil {
+			return err
+		}
+		failOn, err := failOnOption(cmd)
+		if err != nil {
+			return err
+		}
+		opts, err := runnerOptions(cmd)
+		if err != nil {
+			return err
 		}
 
 		// Run the experiment
 		ctx := cmd.Context()
-		er := experiments.NewRunner(ctx, files)
-		er.Run()
+		er, err := experiments.NewRunner(ctx, files, opts)
+		if err != nil {
+			return err
+		}
+		rep, err := er.Run(lifecycle)
+		return exitStatus(rep, err, failOn)
 	},
 }
 
@@ -62,25 +83,88 @@ var snippetExperimentCmd = &cobra.Command{
 	},
 }
 
+// validateCmd represents the validate command
+var validateCmd = &cobra.Command{
+	Use:   "validate",
+	Short: "Validate experiment files without running them",
+	Long:  "Validate experiment files without running them, checking their types, names and parameters",
+	RunE: func(cmd *cobra.Command, args []string) error {
+		cmd.SilenceUsage = true
+		files, err := cmd.Flags().GetStringSlice("file")
+		if err != nil {
+			return fmt.Errorf("Error reading file flag: %w", err)
+		}
+		vars, err := varsOption(cmd)
+		if err != nil {
+			return err
+		}
+		count, errs := experiments.Validate(files, vars)
+		for _, err := range errs {
+			output.WriteError("%v", err)
+		}
+		if len(errs) > 0 {
+			return fmt.Errorf("Found %d problem(s) in the experiment files", len(errs))
+		}
+		output.WriteSuccess("%d experiment(s) are valid", count)
+		return nil
+	},
+}
+
+// schemaCmd represents the schema command
+var schemaCmd = &cobra.Command{
+	Use:   "schema",
+	Short: "Print the JSON Schema of experiment files to stdout",
+	Long:  "Print the JSON Schema of experiment files to stdout, of all experiment types or only of the given one",
+	RunE: func(cmd *cobra.Command, args []string) error {
+		cmd.SilenceUsage = true
+		experiment, err := cmd.Flags().GetString("experiment")
+		if err != nil {
+			return fmt.Errorf("Error reading experiment flag: %w", err)
+		}
+		schema, err := experiments.Schema(experiment)
+		if err != nil {
+			return err
+		}
+		output.WriteJSON(schema)
+		return nil
+	},
+}
+
 // verifyCmd represents the verify command
 var verifyCmd = &cobra.Command{
 	Use:   "verify",
 	Short: "Verify the outcome of an experiment",
 	Long:  "Verify the outcome of an experiment",
-	Run: func(cmd *cobra.Command, args []string) {
+	RunE: func(cmd *cobra.Command, args []string) error {
+		cmd.SilenceUsage = true
 		files, err := cmd.Flags().GetStringSlice("file")
 		if err != nil {
-			output.WriteError("Error reading file flag: %v", err)
+			return fmt.Errorf("Error reading file flag: %w", err)
 		}
 		outputFormat, err := cmd.Flags().GetString("output")
 		if err != nil {
-			output.WriteError("Error reading json output flag: %v", err)
+			return fmt.Errorf("Error reading output flag: %w", err)
+		}
+		if err := report.ValidateFormat(outputFormat); err != nil {
+			return err
+		}
+		failOn, err := failOnOption(cmd)
+		if err != nil {
+			return err
+		}
+		opts, err := runnerOptions(cmd)
+		if err != nil {
+			return err
 		}
 
 		// Run the verifiers
 		ctx := cmd.Context()
-		er := experiments.NewRunner(ctx, files)
-		er.RunVerifiers(outputFormat)
+		er, err := experiments.NewRunner(ctx, files, opts)
+		if err != nil {
+			return err
+		}
+		rep, err := er.RunVerifiers(outputFormat)
+		return exitStatus(rep, err, failOn)
 	},
 }
 
@@ -89,39 +173,240 @@ var cleanCmd = &cobra.Command{
 	Use:   "clean",
 	Short: "Clean up after an experiment run",
 	Long:  "Clean up after an experiment run",
-	Run: func(cmd *cobra.Command, args []string) {
+	RunE: func(cmd *cobra.Command, args []string) error {
+		cmd.SilenceUsage = true
 		files, err := cmd.Flags().GetStringSlice("file")
 		if err != nil {
-			output.WriteError("Error reading file flag: %v", err)
+			return fmt.Errorf("Error reading file flag: %w", err)
+		}
+		opts, err := runnerOptions(cmd)
+		if err != nil {
+			return err
 		}
 
 		// Create a new experiment runner and clean up
 		ctx := cmd.Context()
-		er := experiments.NewRunner(ctx, files)
-		er.Cleanup()
+		er, err := experiments.NewRunner(ctx, files, opts)
+		if err != nil {
+			return err
+		}
+		return er.Cleanup()
 	},
 }
 
+// exitStatus returns the error a command exits with after acting on experiments. Errors acting on the experiments
+// are infrastructure errors, and experiments missing their expectation only fail the command past the --fail-on threshold
+func exitStatus(rep *report.Report, err error, failOn verifier.FailOn) error {
+	switch {
+	case err != nil:
+		return err
+	case rep.Summary.Errors > 0:
+		return fmt.Errorf("%d experiment(s) could not be run, verified or cleaned up", rep.Summary.Errors)
+	case failOn.Exceeded(rep.Summary):
+		return &exitCodeError{
+			code: exitExpectationsMissed,
+			err:  fmt.Errorf("%d experiment(s) did not meet their expectation", rep.Summary.Failed),
+		}
+	}
+	return nil
+}
+
+// failOnOption reads the --fail-on flag
+func failOnOption(cmd *cobra.Command) (verifier.FailOn, error) {
+	failOn, err := cmd.Flags().GetString("fail-on")
+	if err != nil {
+		return verifier.FailOn{}, fmt.Errorf("Error reading fail-on flag: %w", err)
+	}
+	return verifier.ParseFailOn(failOn)
+}
+
+// runnerOptions reads the flags shared by commands that act on a set of experiments
+func runnerOptions(cmd *cobra.Command) (experiments.RunnerOptions, error) {
+	parallelism, err := cmd.Flags().GetInt("parallelism")
+	if err != nil {
+		return experiments.RunnerOptions{}, fmt.Errorf("Error reading parallelism flag: %w", err)
+	}
+	if parallelism < 1 {
+		return experiments.RunnerOptions{}, fmt.Errorf("--parallelism must be at least 1, got %d", parallelism)
+	}
+	failFast, err := cmd.Flags().GetBool("fail-fast")
+	if err != nil {
+		return experiments.RunnerOptions{}, fmt.Errorf("Error reading fail-fast flag: %w", err)
+	}
+	vars, err := varsOption(cmd)
+	if err != nil {
+		return experiments.RunnerOptions{}, err
+	}
+	selector, err := selectorOption(cmd)
+	if err != nil {
+		return experiments.RunnerOptions{}, err
+	}
+	opts := experiments.RunnerOptions{
+		Parallelism: parallelism,
+		FailFast:    failFast,
+		Vars:        vars,
+		Selector:    selector,
+	}
+	if cmd.Flags().Lookup("run-id") != nil {
+		runID, err := cmd.Flags().GetString("run-id")
+		if err != nil {
+			return experiments.RunnerOptions{}, fmt.Errorf("Error reading run-id flag: %w", err)
+		}
+		opts.RunID = runID
+	}
+	if cmd.Flags().Lookup("suppressions") != nil {
+		path, err := cmd.Flags().GetString("suppressions")
+		if err != nil {
+			return experiments.RunnerOptions{}, fmt.Errorf("Error reading suppressions flag: %w", err)
+		}
+		if path != "" {
+			if opts.Suppressions, err = suppressions.Load(path); err != nil {
+				return experiments.RunnerOptions{}, err
+			}
+		}
+	}
+	return opts, nil
+}
+
+// varsOption reads the --set flag, which overrides the variables defined in the experiment files
+func varsOption(cmd *cobra.Command) (map[string]string, error) {
+	assignments, err := cmd.Flags().GetStringArray("set")
+	if err != nil {
+		return nil, fmt.Errorf("Error reading set flag: %w", err)
+	}
+	return experiments.ParseVars(assignments)
+}
+
+// selectorOption reads the flags which select the experiments to act on
+func selectorOption(cmd *cobra.Command) (experiments.Selector, error) {
+	var selector experiments.Selector
+	var err error
+	if selector.Tags, err = cmd.Flags().GetStringSlice("tag"); err != nil {
+		return selector, fmt.Errorf("Error reading tag flag: %w", err)
+	}
+	if selector.Types, err = cmd.Flags().GetStringSlice("type"); err != nil {
+		return selector, fmt.Errorf("Error reading type flag: %w", err)
+	}
+	if selector.Tactics, err = cmd.Flags().GetStringSlice("tactic"); err != nil {
+		return selector, fmt.Errorf("Error reading tactic flag: %w", err)
+	}
+	if selector.Name, err = regexOption(cmd, "name"); err != nil {
+		return selector, err
+	}
+	if selector.Exclude, err = regexOption(cmd, "exclude"); err != nil {
+		return selector, err
+	}
+	return selector, nil
+}
+
+// regexOption reads a flag holding a regex, which is nil when the flag is empty
+func regexOption(cmd *cobra.Command, name string) (*regexp.Regexp, error) {
+	pattern, err := cmd.Flags().GetString(name)
+	if err != nil {
+		return nil, fmt.Errorf("Error reading %s flag: %w", name, err)
+	}
+	if pattern == "" {
+		return nil, nil
+	}
+	re, err := regexp.Compile(pattern)
+	if err != nil {
+		return nil, fmt.Errorf("Invalid --%s regex: %w", name, err)
+	}
+	return re, nil
+}
+
+// lifecycleOptions reads the flags of the run command which select the lifecycle of the experiments
+func lifecycleOptions(cmd *cobra.Command) (experiments.Lifecycle, error) {
+	var lifecycle experiments.Lifecycle
+	var err error
+	if lifecycle.Verify, err = cmd.Flags().GetBool("verify"); err != nil {
+		return lifecycle, fmt.Errorf("Error reading verify flag: %w", err)
+	}
+	if lifecycle.Cleanup, err = cmd.Flags().GetBool("cleanup"); err != nil {
+		return lifecycle, fmt.Errorf("Error reading cleanup flag: %w", err)
+	}
+	if lifecycle.KeepOnFailure, err = cmd.Flags().GetBool("keep-on-failure"); err != nil {
+		return lifecycle, fmt.Errorf("Error reading keep-on-failure flag: %w", err)
+	}
+	if lifecycle.OutputFormat, err = cmd.Flags().GetString("output"); err != nil {
+		return lifecycle, fmt.Errorf("Error reading output flag: %w", err)
+	}
+	if lifecycle.KeepOnFailure && !lifecycle.Cleanup {
+		return lifecycle, fmt.Errorf("--keep-on-failure only applies together with --cleanup")
+	}
+	if lifecycle.OutputFormat != "" && !lifecycle.Verify {
+		return lifecycle, fmt.Errorf("--output only applies together with --verify")
+	}
+	if err := report.ValidateFormat(lifecycle.OutputFormat); err != nil {
+		return lifecycle, err
+	}
+	return lifecycle, nil
+}
+
 func init() {
 	rootCmd.AddCommand(experimentCmd)
 	experimentCmd.AddCommand(runCmd)
 	experimentCmd.AddCommand(verifyCmd)
 	experimentCmd.AddCommand(cleanCmd)
 	experimentCmd.AddCommand(snippetExperimentCmd)
+	experimentCmd.AddCommand(validateCmd)
+	experimentCmd.AddCommand(schemaCmd)
 
 	// Define the path of the experiment file to run
-	runCmd.Flags().StringSliceP("file", "f", []string{}, "Experiment file(s) to run")
+	runCmd.Flags().StringSliceP("file", "f", []string{}, "Experiment file(s), directories, globs or suites to run")
 	_ = runCmd.MarkFlagRequired("file")
 
-	verifyCmd.Flags().StringSliceP("file", "f", []string{}, "Experiment file(s) to verify")
+	verifyCmd.Flags().StringSliceP("file", "f", []string{}, "Experiment file(s), directories, globs or suites to verify")
 	_ = verifyCmd.MarkFlagRequired("file")
 
-	cleanCmd.Flags().StringSliceP("file", "f", []string{}, "Experiment file(s) to run")
+	cleanCmd.Flags().StringSliceP("file", "f", []string{}, "Experiment file(s), directories, globs or suites to clean up")
 	_ = cleanCmd.MarkFlagRequired("file")
 
+	validateCmd.Flags().StringSliceP("file", "f", []string{}, "Experiment file(s), directories, globs or suites to validate")
+	_ = validateCmd.MarkFlagRequired("file")
+
+	// Control how many experiments are acted on at once
+	for _, c := range []*cobra.Command{runCmd, verifyCmd, cleanCmd} {
+		c.Flags().Int("parallelism", 1, "Maximum number of experiments to act on at the same time")
+		c.Flags().Bool("fail-fast", false, "Stop acting on the remaining experiments after the first error")
+	}
+
+	// Decide how many experiments missing their expectation fail the command
+	for _, c := range []*cobra.Command{runCmd, verifyCmd} {
+		c.Flags().String("fail-on", "any", "Fail when this many experiments miss their expectation, or one at least this severe does (any|none|<count>|low|medium|high|critical)")
+		c.Flags().String("suppressions", "", "File of accepted risks, which are reported but don't count as missing their expectation")
+	}
+
+	// Override the variables of the experiment files
+	for _, c := range []*cobra.Command{runCmd, verifyCmd, cleanCmd, validateCmd} {
+		c.Flags().StringArray("set", []string{}, "Set a variable referenced as ${key} in the experiment files, overriding its value in their vars (key=value)")
+	}
+
+	// Select a subset of the experiments to act on
+	for _, c := range []*cobra.Command{runCmd, verifyCmd, cleanCmd} {
+		c.Flags().StringSlice("tag", []string{}, "Only act on experiments with any of these tags")
+		c.Flags().StringSlice("type", []string{}, "Only act on experiments of any of these types")
+		c.Flags().StringSlice("tactic", []string{}, "Only act on experiments of any of these tactics, by name or ID (e.g. TA0004)")
+		c.Flags().String("name", "", "Only act on experiments whose name matches this regex")
+		c.Flags().String("exclude", "", "Don't act on experiments whose name matches this regex")
+	}
+
+	// Select the run to act on
+	for _, c := range []*cobra.Command{verifyCmd, cleanCmd} {
+		c.Flags().String("run-id", "", "ID of the run to act on, defaults to the latest run")
+	}
+
 	snippetExperimentCmd.Flags().StringP("experiment", "e", "", "Experiment to generate a template for")
 	_ = snippetExperimentCmd.MarkFlagRequired("experiment")
 
+	schemaCmd.Flags().StringP("experiment", "e", "", "Experiment type to generate the schema for, defaults to all types")
+
 	// Output the results in JSON format
-	verifyCmd.Flags().StringP("output", "o", "", "Output results in the provided format (json|yaml)")
+	verifyCmd.Flags().StringP("output", "o", "", "Output results in the provided format (json|yaml|sarif|junit)")
+
+	// Drive the full lifecycle of the experiments from a single run
+	runCmd.Flags().Bool("verify", false, "Verify each experiment as soon as it has run")
+	runCmd.Flags().Bool("cleanup", false, "Clean up the experiments once they have run, even if the run is interrupted")
+	runCmd.Flags().Bool("keep-on-failure", false, "With --cleanup, keep experiments that failed or missed their expectation for debugging")
+	runCmd.Flags().StringP("output", "o", "", "With --verify, output results in the provided format (json|yaml|sarif|junit)")
 }
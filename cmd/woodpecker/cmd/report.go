@@ -0,0 +1,156 @@
+/*
+Copyright 2023 Operant AI
+*/
+package cmd
+
+import (
+	"fmt"
+	"os"
+	"strings"
+
+	"github.com/operantai/woodpecker/internal/ledger"
+	"github.com/operantai/woodpecker/internal/output"
+	"github.com/operantai/woodpecker/internal/report"
+	"github.com/spf13/cobra"
+)
+
+// reportCmd renders a report of the verified experiments of a run
+var reportCmd = &cobra.Command{
+	Use:   "report",
+	Short: "Render a report of a run",
+	Long:  "Render a report of the verified experiments of a run, along with the trend of the runs before it",
+	RunE: func(cmd *cobra.Command, args []string) error {
+		cmd.SilenceUsage = true
+		format, err := cmd.Flags().GetString("format")
+		if err != nil {
+			return fmt.Errorf("Error reading format flag: %w", err)
+		}
+		format = strings.ToLower(format)
+		if format != report.FormatHTML && format != report.FormatNavigator {
+			return fmt.Errorf("Unknown report format: %s", format)
+		}
+		runID, err := cmd.Flags().GetString("run-id")
+		if err != nil {
+			return fmt.Errorf("Error reading run-id flag: %w", err)
+		}
+		out, err := cmd.Flags().GetString("out")
+		if err != nil {
+			return fmt.Errorf("Error reading out flag: %w", err)
+		}
+		runs, err := cmd.Flags().GetInt("history")
+		if err != nil {
+			return fmt.Errorf("Error reading history flag: %w", err)
+		}
+
+		dir, err := ledger.DefaultDir()
+		if err != nil {
+			return err
+		}
+		store := ledger.NewStore(dir)
+		run, err := store.Open(runID)
+		if err != nil {
+			return fmt.Errorf("Failed to open run: %w", err)
+		}
+		rep, err := report.FromRun(run)
+		if err != nil {
+			return err
+		}
+		if len(rep.Results) == 0 {
+			output.WriteWarning("No experiments of run %s were verified, verify them with woodpecker experiment verify --run-id %s", run.ID, run.ID)
+		}
+
+		var write func(f *os.File) error
+		switch format {
+		case report.FormatHTML:
+			history, err := report.History(store, runs)
+			if err != nil {
+				return err
+			}
+			write = func(f *os.File) error {
+				return report.WriteHTML(f, rep, history)
+			}
+			if out == "" {
+				out = fmt.Sprintf("woodpecker-report-%s.html", run.ID)
+			}
+		case report.FormatNavigator:
+			write = func(f *os.File) error {
+				return report.WriteNavigatorLayer(f, rep)
+			}
+			if out == "" {
+				out = fmt.Sprintf("woodpecker-navigator-%s.json", run.ID)
+			}
+		}
+
+		f, err := os.Create(out)
+		if err != nil {
+			return fmt.Errorf("Could not create report file: %w", err)
+		}
+		if err := write(f); err != nil {
+			f.Close()
+			return err
+		}
+		if err := f.Close(); err != nil {
+			return fmt.Errorf("Could not write report file: %w", err)
+		}
+		output.WriteSuccess("Wrote report of run %s to %s", run.ID, out)
+		return nil
+	},
+}
+
+// reportDiffCmd compares two saved verify results
+var reportDiffCmd = &cobra.Command{
+	Use:   "diff",
+	Short: "Compare the results of two verifications",
+	Long:  "Compare two results saved with woodpecker experiment verify -o json, listing the tests that changed and failing on regressions",
+	RunE: func(cmd *cobra.Command, args []string) error {
+		cmd.SilenceUsage = true
+		basePath, err := cmd.Flags().GetString("base")
+		if err != nil {
+			return fmt.Errorf("Error reading base flag: %w", err)
+		}
+		headPath, err := cmd.Flags().GetString("head")
+		if err != nil {
+			return fmt.Errorf("Error reading head flag: %w", err)
+		}
+		outputFormat, err := cmd.Flags().GetString("output")
+		if err != nil {
+			return fmt.Errorf("Error reading output flag: %w", err)
+		}
+
+		base, err := report.Load(basePath)
+		if err != nil {
+			return err
+		}
+		head, err := report.Load(headPath)
+		if err != nil {
+			return err
+		}
+		diff := report.Compare(base, head)
+		if err := report.WriteDiff(diff, outputFormat); err != nil {
+			return err
+		}
+		if diff.Regressions > 0 {
+			return &exitCodeError{
+				code: exitExpectationsMissed,
+				err:  fmt.Errorf("%d test(s) regressed since %s", diff.Regressions, basePath),
+			}
+		}
+		return nil
+	},
+}
+
+func init() {
+	rootCmd.AddCommand(reportCmd)
+	reportCmd.AddCommand(reportDiffCmd)
+
+	reportCmd.Flags().String("format", report.FormatHTML, "Format of the report, an HTML report or an ATT&CK Navigator layer (html|navigator)")
+	reportCmd.Flags().String("run-id", "", "ID of the run to report on, defaults to the latest run")
+	reportCmd.Flags().String("out", "", "File to write the report to, defaults to woodpecker-report-<run-id>.html or woodpecker-navigator-<run-id>.json")
+	reportCmd.Flags().Int("history", 10, "Number of most recent runs to show the pass/fail trend of in HTML reports")
+
+	reportDiffCmd.Flags().String("base", "", "Results to compare against, saved with woodpecker experiment verify -o json")
+	reportDiffCmd.Flags().String("head", "", "Results to compare, saved with woodpecker experiment verify -o json")
+	reportDiffCmd.Flags().StringP("output", "o", "", "Output the changes in the provided format (json|yaml)")
+	_ = reportDiffCmd.MarkFlagRequired("base")
+	_ = reportDiffCmd.MarkFlagRequired("head")
+}
@@ -4,22 +4,65 @@ Copyright 2023 Operant AI
 package cmd
 
 import (
+	"context"
+	"errors"
+	"os"
+	"os/signal"
+	"syscall"
+
 	"github.com/operantai/woodpecker/internal/output"
 	"github.com/spf13/cobra"
 )
 
+// Exit codes, which let CI pipelines tell experiments missing their expectation apart from errors acting on them
+const (
+	exitExpectationsMissed = 1
+	exitError              = 2
+)
+
+// exitCodeError is returned by commands that exit with a specific exit code
+type exitCodeError struct {
+	code int
+	err  error
+}
+
+func (e *exitCodeError) Error() string {
+	return e.err.Error()
+}
+
+func (e *exitCodeError) Unwrap() error {
+	return e.err
+}
+
 // rootCmd represents the base command when called without any subcommands
 var rootCmd = &cobra.Command{
 	Use:   "woodpecker",
 	Short: "",
 	Long:  "",
+	// Errors are written by Execute
+	SilenceErrors: true,
 }
 
 // Execute adds all child commands to the root command and sets flags appropriately.
 // This is called by main.main(). It only needs to happen once to the rootCmd.
 func Execute() {
-	err := rootCmd.Execute()
+	// Cancel the running command on the first interrupt so that it can clean up, a second one exits at once
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer stop()
+	go func() {
+		<-ctx.Done()
+		stop()
+	}()
+
+	err := rootCmd.ExecuteContext(ctx)
 	if err != nil {
 		output.WriteError("%s", err.Error())
+		code := exitError
+		var exitErr *exitCodeError
+		if errors.As(err, &exitErr) {
+			code = exitErr.code
+		}
+		stop()
+		os.Exit(code)
 	}
 }
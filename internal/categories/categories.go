@@ -3,6 +3,8 @@ Copyright 2023 Operant AI
 */
 package categories
 
+import "reflect"
+
 type Framework string
 
 const (
@@ -32,6 +34,9 @@ type mitreEntry struct {
 	CategoryID string
 	Tactic     string
 	Technique  string
+	// TechniqueID is the ID of the technique in MITRE ATT&CK, or MITRE ATLAS for AI techniques, e.g. T1610.
+	// It is empty for techniques ATT&CK has no equivalent for.
+	TechniqueID string
 }
 
 type AMLResourceDevelopment struct {
@@ -102,6 +107,8 @@ type Discovery struct {
 	InstanceMetadataAPI       mitreEntry
 }
 
+// LateralMovement techniques, ClusterInternalNetworking has no technique ID as ATT&CK has no technique for
+// reaching other workloads over a cluster network that no network policy restricts
 type LateralMovement struct {
 	AccessCloudResources                        mitreEntry
 	ContainerServiceAccount                     mitreEntry
@@ -121,70 +128,157 @@ var (
 func init() {
 	MITRE = mitreTactics{
 		InitialAccess{
-			UsingCloudCredentials:       mitreEntry{"TA0001", "Initial Access", "Using Cloud Credentials"},
-			CompromisedImagesInRegistry: mitreEntry{"TA0001", "Initial Access", "Compromised Images in Registry"},
-			KubeConfigFile:              mitreEntry{"TA0001", "Initial Access", "Kube Config File"},
-			ApplicationVulnerability:    mitreEntry{"TA0001", "Initial Access", "Application Vulnerability"},
-			ExposedSensitiveInterfaces:  mitreEntry{"TA0001", "Initial Access", "Exposed Sensitive Interfaces"},
+			UsingCloudCredentials:       mitreEntry{"TA0001", "Initial Access", "Using Cloud Credentials", "T1078.004"},
+			CompromisedImagesInRegistry: mitreEntry{"TA0001", "Initial Access", "Compromised Images in Registry", "T1525"},
+			KubeConfigFile:              mitreEntry{"TA0001", "Initial Access", "Kube Config File", "T1078"},
+			ApplicationVulnerability:    mitreEntry{"TA0001", "Initial Access", "Application Vulnerability", "T1190"},
+			ExposedSensitiveInterfaces:  mitreEntry{"TA0001", "Initial Access", "Exposed Sensitive Interfaces", "T1133"},
 		},
 		Execution{
-			ExecIntoContainer:           mitreEntry{"TA0002", "Execution", "Exec Into Container"},
-			BashCmdInsideContainer:      mitreEntry{"TA0002", "Execution", "Bash Cmd Inside Container"},
-			NewContainer:                mitreEntry{"TA0002", "Execution", "New Container"},
-			ApplicationExploit:          mitreEntry{"TA0002", "Execution", "Application Exploit"},
-			RCE:                         mitreEntry{"TA0002", "Execution", "Application Exploit"},
-			SSHServerRunningInContainer: mitreEntry{"TA0002", "Execution", "SSH Server Running In Container"},
-			SidecarInjection:            mitreEntry{"TA0002", "Execution", "Sidecar Injection"},
+			ExecIntoContainer:           mitreEntry{"TA0002", "Execution", "Exec Into Container", "T1609"},
+			BashCmdInsideContainer:      mitreEntry{"TA0002", "Execution", "Bash Cmd Inside Container", "T1059.004"},
+			NewContainer:                mitreEntry{"TA0002", "Execution", "New Container", "T1610"},
+			ApplicationExploit:          mitreEntry{"TA0002", "Execution", "Application Exploit", "T1190"},
+			RCE:                         mitreEntry{"TA0002", "Execution", "Application Exploit", "T1190"},
+			SSHServerRunningInContainer: mitreEntry{"TA0002", "Execution", "SSH Server Running In Container", "T1021.004"},
+			SidecarInjection:            mitreEntry{"TA0002", "Execution", "Sidecar Injection", "T1610"},
 		},
 		Persistence{
-			BackdoorContainer:            mitreEntry{"TA0003", "Persistence", "Backdoor Container"},
-			WriteableHostPathMount:       mitreEntry{"TA0003", "Persistence", "Writeable Host Path Mount"},
-			KubernetesCronJob:            mitreEntry{"TA0003", "Persistence", "Kubernetes Cron Job"},
-			MaliciousAdmissionController: mitreEntry{"TA0003", "Persistence", "Malicious Admission Controller"},
+			BackdoorContainer:            mitreEntry{"TA0003", "Persistence", "Backdoor Container", "T1543.005"},
+			WriteableHostPathMount:       mitreEntry{"TA0003", "Persistence", "Writeable Host Path Mount", "T1611"},
+			KubernetesCronJob:            mitreEntry{"TA0003", "Persistence", "Kubernetes Cron Job", "T1053.007"},
+			MaliciousAdmissionController: mitreEntry{"TA0003", "Persistence", "Malicious Admission Controller", "T1546"},
 		},
 		PrivilegeEscalation{
-			PrivilegedContainer:  mitreEntry{"TA0004", "Privilege Escalation", "Privileged Container"},
-			ClusterAdminBinding:  mitreEntry{"TA0004", "Privilege Escalation", "Cluster Admin Binding"},
-			HostPathMount:        mitreEntry{"TA0004", "Privilege Escalation", "Host Path Mount"},
-			AccessCloudResources: mitreEntry{"TA0004", "Privilege Escalation", "Access Cloud Resources"},
+			PrivilegedContainer:  mitreEntry{"TA0004", "Privilege Escalation", "Privileged Container", "T1611"},
+			ClusterAdminBinding:  mitreEntry{"TA0004", "Privilege Escalation", "Cluster Admin Binding", "T1098.006"},
+			HostPathMount:        mitreEntry{"TA0004", "Privilege Escalation", "Host Path Mount", "T1611"},
+			AccessCloudResources: mitreEntry{"TA0004", "Privilege Escalation", "Access Cloud Resources", "T1078.004"},
 		},
 		DefenseEvasion{
-			ClearContainerLogs:         mitreEntry{"TA0005", "Defense Evasion", "Clear Container Logs"},
-			DeleteK8sEvents:            mitreEntry{"TA0005", "Defense Evasion", "Delete K8s Events"},
-			PodContainerNameSimilarity: mitreEntry{"TA0005", "Defense Evasion", "Pod Container Name Similarity"},
-			ConnectFromProxyServer:     mitreEntry{"TA0005", "Defense Evasion", "Connect From Proxy Server"},
+			ClearContainerLogs:         mitreEntry{"TA0005", "Defense Evasion", "Clear Container Logs", "T1070.002"},
+			DeleteK8sEvents:            mitreEntry{"TA0005", "Defense Evasion", "Delete K8s Events", "T1070"},
+			PodContainerNameSimilarity: mitreEntry{"TA0005", "Defense Evasion", "Pod Container Name Similarity", "T1036.005"},
+			ConnectFromProxyServer:     mitreEntry{"TA0005", "Defense Evasion", "Connect From Proxy Server", "T1090"},
 		},
 		Credentials{
-			ListK8sSecrets:                             mitreEntry{"TA0006", "Credential Access", "List K8s Secrets"},
-			MountServicePrincipal:                      mitreEntry{"TA0006", "Credential Access", "Mount Service Principal"},
-			AccessContainerServiceAccount:              mitreEntry{"TA0006", "Credential Access", "Access Container Service Account"},
-			ApplicationCredentialsInConfigurationFiles: mitreEntry{"TA0006", "Credential Access", "Application Credentials In Configuration Files"},
-			AccessManagedIdentityCredentials:           mitreEntry{"TA0006", "Credential Access", "Access Managed Identity Credentials"},
-			MaliciousAdmissionController:               mitreEntry{"TA0006", "Credential Access", "Malicious Admission Controller"},
+			ListK8sSecrets:                             mitreEntry{"TA0006", "Credential Access", "List K8s Secrets", "T1552.007"},
+			MountServicePrincipal:                      mitreEntry{"TA0006", "Credential Access", "Mount Service Principal", "T1552.001"},
+			AccessContainerServiceAccount:              mitreEntry{"TA0006", "Credential Access", "Access Container Service Account", "T1528"},
+			ApplicationCredentialsInConfigurationFiles: mitreEntry{"TA0006", "Credential Access", "Application Credentials In Configuration Files", "T1552.001"},
+			AccessManagedIdentityCredentials:           mitreEntry{"TA0006", "Credential Access", "Access Managed Identity Credentials", "T1552.005"},
+			MaliciousAdmissionController:               mitreEntry{"TA0006", "Credential Access", "Malicious Admission Controller", "T1557"},
 		},
 		Discovery{
-			AccessTheK8sApiServer:     mitreEntry{"TA0007", "Discovery", "Access The K8s API Server"},
-			AccessKubeletAPI:          mitreEntry{"TA0007", "Discovery", "Access Kubelet API"},
-			NetworkMapping:            mitreEntry{"TA0007", "Discovery", "Network Mapping"},
-			AccessKubernetesDashboard: mitreEntry{"TA0007", "Discovery", "Access Kubernetes Dashboard"},
-			InstanceMetadataAPI:       mitreEntry{"TA0007", "Discovery", "Instance Metadata API"},
+			AccessTheK8sApiServer:     mitreEntry{"TA0007", "Discovery", "Access The K8s API Server", "T1613"},
+			AccessKubeletAPI:          mitreEntry{"TA0007", "Discovery", "Access Kubelet API", "T1613"},
+			NetworkMapping:            mitreEntry{"TA0007", "Discovery", "Network Mapping", "T1046"},
+			AccessKubernetesDashboard: mitreEntry{"TA0007", "Discovery", "Access Kubernetes Dashboard", "T1613"},
+			InstanceMetadataAPI:       mitreEntry{"TA0007", "Discovery", "Instance Metadata API", "T1552.005"},
 		},
 		LateralMovement{
-			AccessCloudResources:                        mitreEntry{"TA0008", "Lateral Movement", "Access Cloud Resources"},
-			ContainerServiceAccount:                     mitreEntry{"TA0008", "Lateral Movement", "Container Service Account"},
-			ClusterInternalNetworking:                   mitreEntry{"TA0008", "Lateral Movement", "Cluster Internal Networking"},
-			ApplicationsCredentialsInConfigurationFiles: mitreEntry{"TA0008", "Lateral Movement", "Applications Credentials In Configuration Files"},
-			WritableVolumeMountsOnTheHost:               mitreEntry{"TA0008", "Lateral Movement", "Writable Volume Mounts On The Host"},
-			CoreDNSPoisoning:                            mitreEntry{"TA0008", "Lateral Movement", "CoreDNS Poisoning"},
-			ARPPoisoningOrIPSpoofing:                    mitreEntry{"TA0008", "Lateral Movement", "ARP Poisoning Or IP Spoofing"},
+			AccessCloudResources:                        mitreEntry{"TA0008", "Lateral Movement", "Access Cloud Resources", "T1078.004"},
+			ContainerServiceAccount:                     mitreEntry{"TA0008", "Lateral Movement", "Container Service Account", "T1528"},
+			ClusterInternalNetworking:                   mitreEntry{"TA0008", "Lateral Movement", "Cluster Internal Networking", ""},
+			ApplicationsCredentialsInConfigurationFiles: mitreEntry{"TA0008", "Lateral Movement", "Applications Credentials In Configuration Files", "T1552.001"},
+			WritableVolumeMountsOnTheHost:               mitreEntry{"TA0008", "Lateral Movement", "Writable Volume Mounts On The Host", "T1611"},
+			CoreDNSPoisoning:                            mitreEntry{"TA0008", "Lateral Movement", "CoreDNS Poisoning", "T1557"},
+			ARPPoisoningOrIPSpoofing:                    mitreEntry{"TA0008", "Lateral Movement", "ARP Poisoning Or IP Spoofing", "T1557.002"},
 		},
 	}
 
 	MITREATLAS = mitreAtlasTactics{
 		AMLResourceDevelopment{
-			PoisonTrainingData: mitreEntry{"AML.T0020", "Resource Development", "Poison Training Data"},
+			PoisonTrainingData: mitreEntry{"AML.T0020", "Resource Development", "Poison Training Data", "AML.T0020"},
 		},
-		AMLPersistence{PoisonTrainingData: mitreEntry{"AML.T0020", "Persistence", "Poison Training Data"}},
-		AMLExfiltration{LLMDataLeakage: mitreEntry{"AML.T0057", "Exfiltration", "LLM Data Leakage"}},
+		AMLPersistence{PoisonTrainingData: mitreEntry{"AML.T0020", "Persistence", "Poison Training Data", "AML.T0020"}},
+		AMLExfiltration{LLMDataLeakage: mitreEntry{"AML.T0057", "Exfiltration", "LLM Data Leakage", "AML.T0057"}},
+	}
+}
+
+// CategoryID returns the ID of the category a technique belongs to, e.g. TA0004 for the
+// Privileged Container technique of the Privilege Escalation tactic in the MITRE framework
+func CategoryID(framework, tactic, technique string) (string, bool) {
+	for _, entry := range frameworkEntries(framework) {
+		if entry.Tactic == tactic && entry.Technique == technique {
+			return entry.CategoryID, true
+		}
+	}
+	return "", false
+}
+
+// Tactics returns the tactics of a framework in the order the framework lists them
+func Tactics(framework string) []string {
+	var tactics []string
+	seen := make(map[string]bool)
+	for _, entry := range frameworkEntries(framework) {
+		if !seen[entry.Tactic] {
+			seen[entry.Tactic] = true
+			tactics = append(tactics, entry.Tactic)
+		}
+	}
+	return tactics
+}
+
+// TechniqueID returns the MITRE ATT&CK or ATLAS ID of a technique, e.g. T1611 for the Privileged Container
+// technique of the Privilege Escalation tactic in the MITRE framework
+func TechniqueID(framework, tactic, technique string) (string, bool) {
+	for _, entry := range frameworkEntries(framework) {
+		if entry.Tactic == tactic && entry.Technique == technique && entry.TechniqueID != "" {
+			return entry.TechniqueID, true
+		}
+	}
+	return "", false
+}
+
+// TechniqueIDs returns the distinct MITRE ATT&CK or ATLAS IDs of the techniques of a framework's tactic
+func TechniqueIDs(framework, tactic string) []string {
+	var ids []string
+	seen := make(map[string]bool)
+	for _, entry := range frameworkEntries(framework) {
+		if entry.Tactic == tactic && entry.TechniqueID != "" && !seen[entry.TechniqueID] {
+			seen[entry.TechniqueID] = true
+			ids = append(ids, entry.TechniqueID)
+		}
+	}
+	return ids
+}
+
+// Techniques returns the distinct techniques of a framework's tactic
+func Techniques(framework, tactic string) []string {
+	var techniques []string
+	seen := make(map[string]bool)
+	for _, entry := range frameworkEntries(framework) {
+		if entry.Tactic == tactic && !seen[entry.Technique] {
+			seen[entry.Technique] = true
+			techniques = append(techniques, entry.Technique)
+		}
+	}
+	return techniques
+}
+
+// frameworkEntries returns every technique of the named framework, or nothing if the framework is unknown
+func frameworkEntries(framework string) []mitreEntry {
+	switch Framework(framework) {
+	case Mitre:
+		return entries(MITRE)
+	case MitreAtlas:
+		return entries(MITREATLAS)
+	}
+	return nil
+}
+
+// entries returns every technique of a framework's tactics
+func entries(tactics interface{}) []mitreEntry {
+	var all []mitreEntry
+	v := reflect.ValueOf(tactics)
+	for i := 0; i < v.NumField(); i++ {
+		tactic := v.Field(i)
+		for j := 0; j < tactic.NumField(); j++ {
+			if entry, ok := tactic.Field(j).Interface().(mitreEntry); ok {
+				all = append(all, entry)
+			}
+		}
 	}
+	return all
 }
@@ -0,0 +1,94 @@
+package categories
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestCategoryID(t *testing.T) {
+	tests := []struct {
+		name       string
+		framework  string
+		tactic     string
+		technique  string
+		expectedID string
+		found      bool
+	}{
+		{
+			name:       "MITRE technique",
+			framework:  string(Mitre),
+			tactic:     MITRE.PrivilegeEscalation.PrivilegedContainer.Tactic,
+			technique:  MITRE.PrivilegeEscalation.PrivilegedContainer.Technique,
+			expectedID: "TA0004",
+			found:      true,
+		},
+		{
+			name:       "MITRE ATLAS technique",
+			framework:  string(MitreAtlas),
+			tactic:     MITREATLAS.Exfiltration.LLMDataLeakage.Tactic,
+			technique:  MITREATLAS.Exfiltration.LLMDataLeakage.Technique,
+			expectedID: "AML.T0057",
+			found:      true,
+		},
+		{
+			name:      "Technique of another tactic",
+			framework: string(Mitre),
+			tactic:    "Execution",
+			technique: MITRE.PrivilegeEscalation.PrivilegedContainer.Technique,
+		},
+		{
+			name:      "Unknown framework",
+			framework: "OWASP",
+			tactic:    "Execution",
+			technique: "Exec Into Container",
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			id, found := CategoryID(test.framework, test.tactic, test.technique)
+			assert.Equal(t, test.found, found)
+			assert.Equal(t, test.expectedID, id)
+		})
+	}
+}
+
+func TestTactics(t *testing.T) {
+	tactics := Tactics(string(Mitre))
+	assert.Equal(t, "Initial Access", tactics[0])
+	assert.Equal(t, "Lateral Movement", tactics[len(tactics)-1])
+	assert.Equal(t, []string{"Resource Development", "Persistence", "Exfiltration"}, Tactics(string(MitreAtlas)))
+	assert.Empty(t, Tactics("OWASP"))
+}
+
+func TestTechniques(t *testing.T) {
+	// Techniques listed more than once in a tactic are only returned once
+	execution := Techniques(string(Mitre), "Execution")
+	assert.Len(t, execution, 6)
+	assert.Contains(t, execution, "Application Exploit")
+	assert.Equal(t, []string{"LLM Data Leakage"}, Techniques(string(MitreAtlas), "Exfiltration"))
+	assert.Empty(t, Techniques(string(Mitre), "Unknown"))
+}
+
+func TestTechniqueID(t *testing.T) {
+	id, found := TechniqueID(string(Mitre), MITRE.PrivilegeEscalation.PrivilegedContainer.Tactic, MITRE.PrivilegeEscalation.PrivilegedContainer.Technique)
+	assert.True(t, found)
+	assert.Equal(t, "T1611", id)
+
+	id, found = TechniqueID(string(MitreAtlas), MITREATLAS.Exfiltration.LLMDataLeakage.Tactic, MITREATLAS.Exfiltration.LLMDataLeakage.Technique)
+	assert.True(t, found)
+	assert.Equal(t, "AML.T0057", id)
+
+	// Reading the instance metadata endpoint is the Cloud Instance Metadata API technique
+	id, found = TechniqueID(string(Mitre), MITRE.Discovery.InstanceMetadataAPI.Tactic, MITRE.Discovery.InstanceMetadataAPI.Technique)
+	assert.True(t, found)
+	assert.Equal(t, "T1552.005", id)
+
+	// ATT&CK has no equivalent of every technique
+	_, found = TechniqueID(string(Mitre), MITRE.LateralMovement.ClusterInternalNetworking.Tactic, MITRE.LateralMovement.ClusterInternalNetworking.Technique)
+	assert.False(t, found)
+
+	assert.Equal(t, []string{"T1611", "T1098.006", "T1078.004"}, TechniqueIDs(string(Mitre), "Privilege Escalation"))
+	assert.Equal(t, []string{"T1543.005", "T1611", "T1053.007", "T1546"}, TechniqueIDs(string(Mitre), "Persistence"))
+}
@@ -3,7 +3,9 @@ package executor
 import (
 	"context"
 	"strings"
+	"time"
 
+	"github.com/operantai/woodpecker/internal/k8s"
 	appsv1 "k8s.io/api/apps/v1"
 	corev1 "k8s.io/api/core/v1"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
@@ -26,15 +28,17 @@ type RemoteExecutor struct {
 	ServiceAccountName string
 	TargetPort         int32
 	ImageParameters    []string
+	// TLSSecretName is a kubernetes.io/tls Secret the executor serves webhooks with, on WebhookPort
+	TLSSecretName string
 }
 type RemoteExecuteAPI struct {
-	Image              string   `yaml:"image"`
+	Image              string   `yaml:"image" validate:"required"`
 	ImageParameters    []string `yaml:"imageParameters"`
 	ServiceAccountName string   `yaml:"serviceAccountName"`
 	Target             Target   `yaml:"target"`
 }
 type Target struct {
-	Port int32  `yaml:"targetPort"`
+	Port int32  `yaml:"targetPort" validate:"required"`
 	Path string `yaml:"path"`
 }
 
@@ -94,6 +98,9 @@ func (r *RemoteExecutorConfig) Deploy(ctx context.Context, client *kubernetes.Cl
 	if params.ServiceAccountName != "" {
 		deployment.Spec.Template.Spec.ServiceAccountName = params.ServiceAccountName
 	}
+	if params.TLSSecretName != "" {
+		mountTLSSecret(&deployment.Spec.Template.Spec, params.TLSSecretName)
+	}
 
 	_, err := client.AppsV1().Deployments(r.Namespace).Create(ctx, deployment, metav1.CreateOptions{})
 	if err != nil {
@@ -120,6 +127,11 @@ func (r *RemoteExecutorConfig) Deploy(ctx context.Context, client *kubernetes.Cl
 	return err
 }
 
+// WaitForReady waits until the executor Deployment is ready, or returns a *k8s.NotReadyError explaining why it never became ready
+func (r *RemoteExecutorConfig) WaitForReady(ctx context.Context, client kubernetes.Interface, timeout time.Duration) error {
+	return k8s.WaitForDeployment(ctx, client, r.Namespace, r.Name, timeout)
+}
+
 func (r *RemoteExecutorConfig) Cleanup(ctx context.Context, client *kubernetes.Clientset) error {
 	err := client.AppsV1().Deployments(r.Namespace).Delete(ctx, r.Name, metav1.DeleteOptions{})
 	if err != nil {
@@ -129,6 +141,29 @@ func (r *RemoteExecutorConfig) Cleanup(ctx context.Context, client *kubernetes.C
 	return client.CoreV1().Services(r.Namespace).Delete(ctx, r.Name, metav1.DeleteOptions{})
 }
 
+// mountTLSSecret mounts the TLS Secret into the executor, which serves webhooks once it finds the certificate
+func mountTLSSecret(spec *corev1.PodSpec, secretName string) {
+	spec.Volumes = append(spec.Volumes, corev1.Volume{
+		Name: "tls",
+		VolumeSource: corev1.VolumeSource{
+			Secret: &corev1.SecretVolumeSource{SecretName: secretName},
+		},
+	})
+	container := &spec.Containers[0]
+	container.VolumeMounts = append(container.VolumeMounts, corev1.VolumeMount{
+		Name:      "tls",
+		MountPath: tlsMountPath,
+		ReadOnly:  true,
+	})
+	container.Env = append(container.Env,
+		corev1.EnvVar{Name: "TLS_CERT_FILE", Value: tlsMountPath + "/" + corev1.TLSCertKey},
+		corev1.EnvVar{Name: "TLS_KEY_FILE", Value: tlsMountPath + "/" + corev1.TLSPrivateKeyKey},
+	)
+	if container.Ports[0].ContainerPort != WebhookPort {
+		container.Ports = append(container.Ports, corev1.ContainerPort{ContainerPort: WebhookPort})
+	}
+}
+
 func prepareImageParameters(imageParameters []string) []corev1.EnvVar {
 	var envVar []corev1.EnvVar
 	for _, param := range imageParameters {
@@ -0,0 +1,128 @@
+package executor
+
+import (
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/json"
+	"encoding/pem"
+	"fmt"
+	"math/big"
+	"strings"
+	"time"
+
+	admissionv1 "k8s.io/api/admission/v1"
+	corev1 "k8s.io/api/core/v1"
+)
+
+const (
+	// WebhookPort is the port the executor serves admission webhooks on over TLS
+	WebhookPort int32 = 8443
+	// WebhookPath is the path of the mutating webhook the executor serves
+	WebhookPath = "/webhook/mutate"
+	// MutationAnnotation is the annotation the mutating webhook adds to the pods it admits
+	MutationAnnotation = "woodpecker.operant.ai/mutated"
+
+	// webhookCertificateValidity is how long the certificate of the webhook is valid for, so verify can run well
+	// after run without the API server failing to call the webhook
+	webhookCertificateValidity = 7 * 24 * time.Hour
+
+	// tlsMountPath is where the certificate of the webhook is mounted in the executor
+	tlsMountPath = "/etc/woodpecker/tls"
+)
+
+// patchOperation is a JSON patch operation
+type patchOperation struct {
+	Op    string      `json:"op"`
+	Path  string      `json:"path"`
+	Value interface{} `json:"value"`
+}
+
+// Mutate answers an AdmissionReview of a pod with a patch adding MutationAnnotation to it
+func Mutate(review *admissionv1.AdmissionReview) (*admissionv1.AdmissionReview, error) {
+	if review.Request == nil {
+		return nil, fmt.Errorf("admission review has no request")
+	}
+	var pod corev1.Pod
+	if err := json.Unmarshal(review.Request.Object.Raw, &pod); err != nil {
+		return nil, fmt.Errorf("failed to decode pod: %w", err)
+	}
+
+	var patch []patchOperation
+	if pod.Annotations == nil {
+		patch = append(patch, patchOperation{Op: "add", Path: "/metadata/annotations", Value: map[string]string{MutationAnnotation: "true"}})
+	} else {
+		// "/" is escaped as "~1" in JSON pointers
+		key := strings.ReplaceAll(MutationAnnotation, "/", "~1")
+		patch = append(patch, patchOperation{Op: "add", Path: "/metadata/annotations/" + key, Value: "true"})
+	}
+	contents, err := json.Marshal(patch)
+	if err != nil {
+		return nil, err
+	}
+
+	patchType := admissionv1.PatchTypeJSONPatch
+	return &admissionv1.AdmissionReview{
+		TypeMeta: review.TypeMeta,
+		Response: &admissionv1.AdmissionResponse{
+			UID:       review.Request.UID,
+			Allowed:   true,
+			Patch:     contents,
+			PatchType: &patchType,
+		},
+	}, nil
+}
+
+// NewWebhookCertificate returns a self-signed certificate and its key, PEM encoded, for the webhook served by the
+// named Service. The certificate is its own CA, so it is also the CA bundle of the webhook configuration.
+func NewWebhookCertificate(service, namespace string) ([]byte, []byte, error) {
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		return nil, nil, err
+	}
+	serial, err := rand.Int(rand.Reader, new(big.Int).Lsh(big.NewInt(1), 128))
+	if err != nil {
+		return nil, nil, err
+	}
+	dnsName := fmt.Sprintf("%s.%s.svc", service, namespace)
+	template := &x509.Certificate{
+		SerialNumber:          serial,
+		Subject:               pkix.Name{CommonName: dnsName},
+		DNSNames:              []string{dnsName},
+		NotBefore:             time.Now().Add(-time.Minute),
+		NotAfter:              time.Now().Add(webhookCertificateValidity),
+		KeyUsage:              x509.KeyUsageDigitalSignature | x509.KeyUsageCertSign,
+		ExtKeyUsage:           []x509.ExtKeyUsage{x509.ExtKeyUsageServerAuth},
+		BasicConstraintsValid: true,
+		IsCA:                  true,
+	}
+	der, err := x509.CreateCertificate(rand.Reader, template, template, &key.PublicKey, key)
+	if err != nil {
+		return nil, nil, err
+	}
+	keyDER, err := x509.MarshalECPrivateKey(key)
+	if err != nil {
+		return nil, nil, err
+	}
+	return pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: der}),
+		pem.EncodeToMemory(&pem.Block{Type: "EC PRIVATE KEY", Bytes: keyDER}), nil
+}
+
+// CheckWebhookCertificate returns an error if the PEM encoded certificate of a webhook can't be parsed or has expired,
+// as the API server can't call a webhook whose certificate has expired
+func CheckWebhookCertificate(certPEM []byte) error {
+	block, _ := pem.Decode(certPEM)
+	if block == nil {
+		return fmt.Errorf("No PEM encoded certificate found")
+	}
+	cert, err := x509.ParseCertificate(block.Bytes)
+	if err != nil {
+		return err
+	}
+	if time.Now().After(cert.NotAfter) {
+		return fmt.Errorf("Certificate %s expired at %s", cert.Subject.CommonName, cert.NotAfter.Format(time.RFC3339))
+	}
+	return nil
+}
@@ -0,0 +1,98 @@
+package executor
+
+import (
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/json"
+	"encoding/pem"
+	"math/big"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	admissionv1 "k8s.io/api/admission/v1"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+)
+
+func TestMutate(t *testing.T) {
+	tests := []struct {
+		name          string
+		annotations   map[string]string
+		expectedPatch string
+	}{
+		{
+			name:          "Pod without annotations",
+			expectedPatch: `[{"op":"add","path":"/metadata/annotations","value":{"woodpecker.operant.ai/mutated":"true"}}]`,
+		},
+		{
+			name:          "Pod with annotations",
+			annotations:   map[string]string{"team": "payments"},
+			expectedPatch: `[{"op":"add","path":"/metadata/annotations/woodpecker.operant.ai~1mutated","value":"true"}]`,
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			pod, err := json.Marshal(&corev1.Pod{ObjectMeta: metav1.ObjectMeta{Name: "probe", Annotations: test.annotations}})
+			require.NoError(t, err)
+			review := &admissionv1.AdmissionReview{
+				Request: &admissionv1.AdmissionRequest{UID: "123", Object: runtime.RawExtension{Raw: pod}},
+			}
+
+			response, err := Mutate(review)
+			require.NoError(t, err)
+			assert.True(t, response.Response.Allowed)
+			assert.Equal(t, review.Request.UID, response.Response.UID)
+			assert.JSONEq(t, test.expectedPatch, string(response.Response.Patch))
+		})
+	}
+
+	_, err := Mutate(&admissionv1.AdmissionReview{})
+	assert.Error(t, err)
+}
+
+func TestNewWebhookCertificate(t *testing.T) {
+	certPEM, keyPEM, err := NewWebhookCertificate("webhook", "default")
+	require.NoError(t, err)
+	assert.NotEmpty(t, keyPEM)
+
+	block, _ := pem.Decode(certPEM)
+	require.NotNil(t, block)
+	cert, err := x509.ParseCertificate(block.Bytes)
+	require.NoError(t, err)
+	assert.NoError(t, cert.VerifyHostname("webhook.default.svc"))
+
+	// The certificate is its own CA bundle
+	roots := x509.NewCertPool()
+	require.True(t, roots.AppendCertsFromPEM(certPEM))
+	_, err = cert.Verify(x509.VerifyOptions{DNSName: "webhook.default.svc", Roots: roots})
+	assert.NoError(t, err)
+
+	// The certificate outlives a verify run days after the experiment was run
+	assert.False(t, cert.NotAfter.Before(time.Now().Add(7*24*time.Hour-time.Minute)))
+	assert.NoError(t, CheckWebhookCertificate(certPEM))
+}
+
+func TestCheckWebhookCertificate(t *testing.T) {
+	// A certificate whose validity ended yesterday
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	require.NoError(t, err)
+	cert := &x509.Certificate{
+		SerialNumber: big.NewInt(1),
+		Subject:      pkix.Name{CommonName: "webhook.default.svc"},
+		NotBefore:    time.Now().Add(-48 * time.Hour),
+		NotAfter:     time.Now().Add(-24 * time.Hour),
+	}
+	der, err := x509.CreateCertificate(rand.Reader, cert, cert, &key.PublicKey, key)
+	require.NoError(t, err)
+	err = CheckWebhookCertificate(pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: der}))
+	assert.ErrorContains(t, err, "webhook.default.svc expired")
+
+	assert.Error(t, CheckWebhookCertificate(nil))
+}
@@ -5,10 +5,18 @@ package experiments
 
 import (
 	"context"
+	"encoding/json"
+	"errors"
 	"fmt"
-	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
 
+	"github.com/operantai/woodpecker/internal/k8s"
+	"github.com/operantai/woodpecker/internal/ledger"
 	"github.com/operantai/woodpecker/internal/output"
+	"github.com/operantai/woodpecker/internal/report"
+	"github.com/operantai/woodpecker/internal/suppressions"
 	"github.com/operantai/woodpecker/internal/verifier"
 )
 
@@ -24,6 +32,12 @@ type Experiment interface {
 	Tactic() string
 	// Technique returns the attack method
 	Technique() string
+	// NewParameters returns a pointer to a new value of the type the parameters of the experiment are decoded into
+	NewParameters() interface{}
+	// Severity returns the default severity of the attack, experiments can override it in their metadata
+	Severity() verifier.Severity
+	// Remediation returns what to change so the attack no longer goes through
+	Remediation() *verifier.Remediation
 	// Run runs the experiment, returning an error if it fails
 	Run(ctx context.Context, experimentConfig *ExperimentConfig) error
 	// Verify verifies the experiment, returning an error if it fails
@@ -32,17 +46,47 @@ type Experiment interface {
 	Cleanup(ctx context.Context, experimentConfig *ExperimentConfig) error
 }
 
+// errBlocked wraps the error of an experiment whose attack was refused by the cluster. Dependent experiments
+// are skipped as it didn't leave anything behind for them to build on, but it doesn't trigger --fail-fast
+var errBlocked = errors.New("blocked by the cluster")
+
 // Runner runs a set of experiments
 type Runner struct {
 	ctx               context.Context
 	experiments       map[string]Experiment
 	experimentsConfig map[string]*ExperimentConfig
+	parallelism       int
+	failFast          bool
+	ledger            *ledger.Store
+	runID             string
+	run               *ledger.Run
+	suppressions      *suppressions.File
+}
+
+// RunnerOptions configures how a Runner acts on its experiments
+type RunnerOptions struct {
+	// Parallelism is the maximum number of experiments acted on at the same time, at least 1
+	Parallelism int
+	// FailFast stops scheduling further experiments, and cancels running ones, after the first error
+	FailFast bool
+	// RunID selects the run that verify and clean act on, defaulting to the latest run
+	RunID string
+	// Ledger stores runs, defaulting to a Store in ledger.DefaultDir
+	Ledger *ledger.Store
+	// Suppressions are applied to the outcomes of experiments before they are summarized
+	Suppressions *suppressions.File
+	// Vars override the variables defined in the experiment files
+	Vars map[string]string
+	// Selector selects the experiments to act on, defaulting to all of them
+	Selector Selector
 }
 
-// NewRunner returns a new Runner
-func NewRunner(ctx context.Context, experimentFiles []string) *Runner {
+// NewRunner returns a new Runner for the selected experiments in the given files, directories, globs or suites
+func NewRunner(ctx context.Context, experimentFiles []string, opts RunnerOptions) (*Runner, error) {
+	if opts.Parallelism < 1 {
+		return nil, fmt.Errorf("Parallelism must be at least 1, got %d", opts.Parallelism)
+	}
 	experimentMap := make(map[string]Experiment)
-	experimentConfigMap := make(map[string]*ExperimentConfig)
 
 	// Create a map of experiment types to experiments
 	for _, e := range ExperimentsRegistry {
@@ -50,159 +94,318 @@ func NewRunner(ctx context.Context, experimentFiles []string) *Runner {
 	}
 
 	// Parse the experiment configs
-	for _, e := range experimentFiles {
-		experimentConfigs, err := parseExperimentConfigs(e)
-		if err != nil {
-			output.WriteFatal("Failed to parse experiment configs: %s", err)
-		}
+	experimentConfigMap, errs := parseFiles(experimentFiles, opts.Vars)
+	if len(errs) > 0 {
+		return nil, fmt.Errorf("Failed to parse experiment configs: %w", errors.Join(errs...))
+	}
+	selected := selectExperiments(experimentConfigMap, opts.Selector)
+	if len(selected) == 0 && len(experimentConfigMap) > 0 {
+		return nil, fmt.Errorf("None of the %d experiment(s) match the selection", len(experimentConfigMap))
+	}
+	experimentConfigMap = selected
 
-		for i, eConf := range experimentConfigs {
-			if _, exists := experimentMap[eConf.Metadata.Type]; exists {
-				experimentConfigMap[eConf.Metadata.Name] = &experimentConfigs[i]
-			} else {
-				output.WriteError("Experiment %s does not exist", eConf.Metadata.Type)
-			}
+	store := opts.Ledger
+	if store == nil {
+		dir, err := ledger.DefaultDir()
+		if err != nil {
+			return nil, err
 		}
+		store = ledger.NewStore(dir)
 	}
 
 	return &Runner{
 		ctx:               ctx,
 		experiments:       experimentMap,
 		experimentsConfig: experimentConfigMap,
+		parallelism:       opts.Parallelism,
+		failFast:          opts.FailFast,
+		ledger:            store,
+		runID:             opts.RunID,
+		suppressions:      opts.Suppressions,
+	}, nil
+}
+
+// openRun loads the run selected by the RunnerOptions, which verify and clean act on
+func (r *Runner) openRun() error {
+	run, err := r.ledger.Open(r.runID)
+	if err != nil {
+		return fmt.Errorf("Failed to open run: %w", err)
 	}
+	r.run = run
+	output.WriteInfo("Using run %s", run.ID)
+	return nil
 }
 
-// Run runs all experiments in the Runner
-func (r *Runner) Run() {
-	for _, e := range r.experimentsConfig {
-		experiment := r.experiments[e.Metadata.Type]
-		output.WriteInfo("Running experiment %s", e.Metadata.Name)
-		if err := experiment.Run(r.ctx, e); err != nil {
-			output.WriteError("Experiment %s failed with error: %s", e.Metadata.Name, err)
-		}
-		output.WriteInfo("Finished running experiment %s. Check results using woodpecker experiment verify command. \n", e.Metadata.Name)
+// record moves an experiment to the given state in the current run
+func (r *Runner) record(log *output.Logger, name string, state ledger.State, err error) {
+	if recordErr := r.run.SetState(name, state, err); recordErr != nil {
+		log.WriteWarning("Failed to record state of experiment %s in run %s: %s", name, r.run.ID, recordErr)
 	}
 }
 
-// RunVerifiers runs all verifiers in the Runner for the provided experiments
-func (r *Runner) RunVerifiers(outputFormat string) {
-	if outputFormat != "" {
-		// Handle JSON/YAML output
-		outcomes := []*verifier.LegacyOutcome{}
-		for _, e := range r.experimentsConfig {
-			experiment := r.experiments[e.Metadata.Type]
-			outcome, err := experiment.Verify(r.ctx, e)
-			if err != nil {
-				output.WriteFatal("Verifier %s failed: %s", e.Metadata.Name, err)
+// Lifecycle selects what Run does with the experiments besides running them
+type Lifecycle struct {
+	// Verify verifies each experiment as soon as it has run
+	Verify bool
+	// Cleanup cleans up the experiments once they have all run, even if the run was interrupted
+	Cleanup bool
+	// KeepOnFailure leaves experiments that failed or missed their expectation, along with
+	// their prerequisites, in place for debugging instead of cleaning them up
+	KeepOnFailure bool
+	// OutputFormat is the format verification results are written in, json, yaml or a table when empty
+	OutputFormat string
+}
+
+// Run runs all experiments in the Runner, taking them through the given lifecycle. The returned Report holds the
+// verdicts of the experiments when they are verified, and the experiments that couldn't be run, verified or cleaned
+// up either way. An error is returned if the run couldn't start or was interrupted.
+func (r *Runner) Run(lifecycle Lifecycle) (*report.Report, error) {
+	experiments := make(map[string]string)
+	for name, e := range r.experimentsConfig {
+		experiments[name] = e.Metadata.Type
+	}
+	run, err := r.ledger.Create(experiments)
+	if err != nil {
+		return nil, fmt.Errorf("Failed to start run: %w", err)
+	}
+	r.run = run
+	output.WriteInfo("Started run %s", run.ID)
+
+	var mu sync.Mutex
+	var outcomes []*verifier.LegacyOutcome
+	var errs []report.Error
+	// failed holds the experiments that couldn't be run or verified, or that missed their expectation
+	failed := make(map[string]bool)
+	r.forEach(r.ctx, forward, true, func(ctx context.Context, e *ExperimentConfig, log *output.Logger) error {
+		experiment := r.experiments[e.Metadata.Type]
+		log.WriteInfo("Running experiment %s", e.Metadata.Name)
+		r.record(log, e.Metadata.Name, ledger.Running, nil)
+		err := experiment.Run(ctx, e)
+		switch {
+		case err == nil:
+			r.record(log, e.Metadata.Name, ledger.Succeeded, nil)
+			if !lifecycle.Verify {
+				log.WriteInfo("Finished running experiment %s. Check results using woodpecker experiment verify command. \n", e.Metadata.Name)
 			}
-			outcomes = append(outcomes, outcome)
+		case k8s.IsBlocked(err):
+			log.WriteInfo("Experiment %s was blocked by the cluster: %s", e.Metadata.Name, err)
+			r.record(log, e.Metadata.Name, ledger.Blocked, err)
+			err = fmt.Errorf("%w: %w", errBlocked, err)
+		default:
+			log.WriteError("Experiment %s failed with error: %s", e.Metadata.Name, err)
+			r.record(log, e.Metadata.Name, ledger.Failed, err)
+			mu.Lock()
+			errs = append(errs, report.Error{Experiment: e.Metadata.Name, Error: err.Error()})
+			failed[e.Metadata.Name] = true
+			mu.Unlock()
+			return err
 		}
 
-		structuredOutput := verifier.LegacyStructuredOutput{
-			Results: outcomes,
+		if lifecycle.Verify {
+			outcome, verifyErr := r.verify(ctx, experiment, e, log)
+			mu.Lock()
+			defer mu.Unlock()
+			if verifyErr != nil {
+				log.WriteError("Verifier %s failed: %s", e.Metadata.Name, verifyErr)
+				errs = append(errs, report.Error{Experiment: e.Metadata.Name, Error: verifyErr.Error()})
+				failed[e.Metadata.Name] = true
+				return verifyErr
+			}
+			outcomes = append(outcomes, outcome)
+			if outcome.Verdict == verifier.Failed {
+				failed[e.Metadata.Name] = true
+			}
 		}
-		switch strings.ToLower(outputFormat) {
-		case "json":
-			output.WriteJSON(structuredOutput)
-		case "yaml":
-			output.WriteYAML(structuredOutput)
-		default:
-			output.WriteError("Unknown output format: %s", outputFormat)
+		return err
+	})
+
+	rep := report.New(run.ID, outcomes, errs)
+	var outputErr error
+	if lifecycle.Verify {
+		outputErr = report.Write(rep, lifecycle.OutputFormat)
+	}
+
+	if lifecycle.Cleanup {
+		kept := make(map[string]bool)
+		if lifecycle.KeepOnFailure {
+			kept = r.withPrerequisites(failed)
 		}
-		return
+		r.cleanupRun(kept, rep)
 	}
 
-	// Handle table output - show each test result as a separate row
-	table := output.NewTable([]string{"Experiment", "Description", "Framework", "Tactic", "Technique", "Test", "Result"})
+	if err := run.Finish(); err != nil {
+		output.WriteWarning("Failed to record end of run %s: %s", run.ID, err)
+	}
+	if r.ctx.Err() != nil {
+		return rep, fmt.Errorf("Run %s was interrupted: %w", run.ID, context.Cause(r.ctx))
+	}
+	return rep, outputErr
+}
 
-	for _, e := range r.experimentsConfig {
-		experiment := r.experiments[e.Metadata.Type]
-		outcome, err := experiment.Verify(r.ctx, e)
-		if err != nil {
-			output.WriteFatal("Verifier %s failed: %s", e.Metadata.Name, err)
+// withPrerequisites returns the given experiments along with every experiment they depend on
+func (r *Runner) withPrerequisites(names map[string]bool) map[string]bool {
+	closure := make(map[string]bool)
+	var add func(name string)
+	add = func(name string) {
+		if closure[name] {
+			return
 		}
-
-		// If there are no specific test results, show overall experiment result
-		if len(outcome.Result) == 0 {
-			table.AddRow([]string{
-				outcome.Experiment,
-				outcome.Description,
-				outcome.Framework,
-				outcome.Tactic,
-				outcome.Technique,
-				"Overall",
-				"No results",
-			})
-		} else {
-			// Show each individual test result as a separate row
-			for testName, result := range outcome.Result {
-				// Add status emoji for better visual feedback
-				status := result
-				if result == verifier.Success {
-					status = "✓ " + result
-				} else if result == verifier.Fail {
-					status = "✗ " + result
-				}
-
-				table.AddRow([]string{
-					outcome.Experiment,
-					outcome.Description,
-					outcome.Framework,
-					outcome.Tactic,
-					outcome.Technique,
-					testName,
-					status,
-				})
-			}
+		closure[name] = true
+		for _, dep := range r.experimentsConfig[name].Metadata.DependsOn {
+			add(dep)
 		}
 	}
+	for name := range names {
+		add(name)
+	}
+	return closure
+}
 
-	table.Render()
-
-	// Show summary
-	r.printSummary()
+// cleanupRun cleans up the experiments that were started in the current run, except for the kept ones, and adds
+// the experiments that couldn't be cleaned up to the report. It ignores cancellation of the Runner so that an
+// interrupted run still cleans up after itself.
+func (r *Runner) cleanupRun(kept map[string]bool, rep *report.Report) {
+	var mu sync.Mutex
+	ctx := context.WithoutCancel(r.ctx)
+	r.forEach(ctx, reverse, false, func(ctx context.Context, e *ExperimentConfig, log *output.Logger) error {
+		if kept[e.Metadata.Name] {
+			log.WriteWarning("Keeping experiment %s for debugging, clean it up with woodpecker experiment clean --run-id %s", e.Metadata.Name, r.run.ID)
+			return nil
+		}
+		if state, _ := r.run.State(e.Metadata.Name); state == ledger.Pending {
+			return nil
+		}
+		// Errors are logged and don't stop the remaining experiments from being cleaned up
+		if err := r.cleanup(ctx, e, log); err != nil {
+			mu.Lock()
+			rep.AddError(e.Metadata.Name, fmt.Errorf("Cleanup failed: %w", err))
+			mu.Unlock()
+		}
+		return nil
+	})
 }
 
-// printSummary prints a summary of all experiment results
-func (r *Runner) printSummary() {
-	totalTests := 0
-	passedTests := 0
-	failedTests := 0
+// verifyAll runs the verifier of every experiment in the Runner that is part of the run once, collecting the
+// outcomes into a Report. Experiments whose verifier fails are logged and recorded as errors in the Report.
+func (r *Runner) verifyAll() (*report.Report, error) {
+	if r.run == nil {
+		if err := r.openRun(); err != nil {
+			return nil, err
+		}
+	}
 
-	for _, e := range r.experimentsConfig {
+	var mu sync.Mutex
+	var outcomes []*verifier.LegacyOutcome
+	var errs []report.Error
+	r.forEach(r.ctx, forward, false, func(ctx context.Context, e *ExperimentConfig, log *output.Logger) error {
 		experiment := r.experiments[e.Metadata.Type]
-		outcome, err := experiment.Verify(r.ctx, e)
+		if !r.run.Has(e.Metadata.Name) {
+			log.WriteWarning("Skipping experiment %s, which is not part of run %s", e.Metadata.Name, r.run.ID)
+			return nil
+		}
+		outcome, err := r.verify(ctx, experiment, e, log)
+		mu.Lock()
+		defer mu.Unlock()
 		if err != nil {
-			continue
+			log.WriteError("Verifier %s failed: %s", e.Metadata.Name, err)
+			errs = append(errs, report.Error{Experiment: e.Metadata.Name, Error: err.Error()})
+			return err
 		}
+		outcomes = append(outcomes, outcome)
+		return nil
+	})
+	if r.ctx.Err() != nil {
+		return nil, fmt.Errorf("Verification was interrupted: %w", context.Cause(r.ctx))
+	}
+	return report.New(r.run.ID, outcomes, errs), nil
+}
 
-		for _, result := range outcome.Result {
-			totalTests++
-			if result == verifier.Success {
-				passedTests++
-			} else {
-				failedTests++
-			}
+// verify verifies a single experiment, evaluates the outcome against what the experiment expected, applies the
+// suppressions of accepted risks and records it in the current run, from which reports are rendered later on
+func (r *Runner) verify(ctx context.Context, experiment Experiment, e *ExperimentConfig, log *output.Logger) (*verifier.LegacyOutcome, error) {
+	var outcome *verifier.LegacyOutcome
+	if state, _ := r.run.State(e.Metadata.Name); state == ledger.Blocked {
+		// The attack was refused before it left anything behind to verify
+		outcome = verifier.NewLegacy(
+			e.Metadata.Name,
+			experiment.Description(),
+			experiment.Framework(),
+			experiment.Tactic(),
+			experiment.Technique(),
+		).GetOutcome()
+		outcome.Observed = verifier.Blocked
+	} else {
+		var err error
+		outcome, err = experiment.Verify(ctx, e)
+		if err != nil {
+			return nil, err
 		}
 	}
+	outcome.Source = e.Source
+	outcome.Evaluate(e.Metadata.Expect)
+	severity := e.Metadata.Severity
+	if severity == "" {
+		severity = experiment.Severity()
+	}
+	outcome.Score(severity)
+	r.suppressions.Apply(outcome, e.Metadata.Namespace, time.Now())
+	outcome.Remediate(experiment.Remediation())
 
-	fmt.Printf("\nSummary: %d total tests, %d passed, %d failed\n", totalTests, passedTests, failedTests)
+	contents, err := json.Marshal(outcome)
+	if err == nil {
+		err = r.run.SetOutcome(e.Metadata.Name, contents)
+	}
+	if err != nil {
+		log.WriteWarning("Failed to record outcome of experiment %s in run %s: %s", e.Metadata.Name, r.run.ID, err)
+	}
+	return outcome, nil
+}
 
-	if failedTests == 0 {
-		output.WriteSuccess("All tests passed!")
-	} else {
-		output.WriteWarning("%d test(s) failed", failedTests)
+// RunVerifiers runs all verifiers in the Runner for the provided experiments, and writes the resulting Report
+// in the given format
+func (r *Runner) RunVerifiers(outputFormat string) (*report.Report, error) {
+	rep, err := r.verifyAll()
+	if err != nil {
+		return nil, err
 	}
+	return rep, report.Write(rep, outputFormat)
 }
 
-// Cleanup cleans up all experiments in the Runner
-func (r *Runner) Cleanup() {
-	for _, e := range r.experimentsConfig {
-		output.WriteInfo("Cleaning up experiment %s", e.Metadata.Name)
-		experiment := r.experiments[e.Metadata.Type]
-		if err := experiment.Cleanup(r.ctx, e); err != nil {
-			output.WriteError("Experiment %s cleanup failed: %s", e.Metadata.Name, err)
+// Cleanup cleans up all experiments in the Runner that are part of the run, returning an error if any of them
+// couldn't be cleaned up
+func (r *Runner) Cleanup() error {
+	if err := r.openRun(); err != nil {
+		return err
+	}
+	var errs atomic.Int32
+	r.forEach(r.ctx, reverse, false, func(ctx context.Context, e *ExperimentConfig, log *output.Logger) error {
+		if !r.run.Has(e.Metadata.Name) {
+			log.WriteWarning("Skipping experiment %s, which is not part of run %s", e.Metadata.Name, r.run.ID)
+			return nil
 		}
+		err := r.cleanup(ctx, e, log)
+		if err != nil {
+			errs.Add(1)
+		}
+		return err
+	})
+	switch {
+	case r.ctx.Err() != nil:
+		return fmt.Errorf("Cleanup was interrupted: %w", context.Cause(r.ctx))
+	case errs.Load() > 0:
+		return fmt.Errorf("Failed to clean up %d experiment(s)", errs.Load())
+	}
+	return nil
+}
 
+// cleanup cleans up a single experiment and records it as cleaned in the current run
+func (r *Runner) cleanup(ctx context.Context, e *ExperimentConfig, log *output.Logger) error {
+	log.WriteInfo("Cleaning up experiment %s", e.Metadata.Name)
+	experiment := r.experiments[e.Metadata.Type]
+	if err := experiment.Cleanup(ctx, e); err != nil {
+		log.WriteError("Experiment %s cleanup failed: %s", e.Metadata.Name, err)
+		return err
 	}
+	r.record(log, e.Metadata.Name, ledger.Cleaned, nil)
+	return nil
 }
@@ -8,7 +8,6 @@ import (
 	"fmt"
 	"github.com/operantai/woodpecker/internal/categories"
 	"github.com/operantai/woodpecker/internal/verifier"
-	"gopkg.in/yaml.v3"
 	"net/http"
 	"net/url"
 	"time"
@@ -20,7 +19,7 @@ type LLMDataLeakageExperiment struct {
 }
 
 type LLMDataLeakage struct {
-	Apis []ExecuteAIAPI `yaml:"apis"`
+	Apis []ExecuteAIAPI `yaml:"apis" validate:"required"`
 }
 
 func (p *LLMDataLeakageExperiment) Type() string {
@@ -40,10 +39,33 @@ func (p *LLMDataLeakageExperiment) Framework() string {
 	return string(categories.MitreAtlas)
 }
 
+func (p *LLMDataLeakageExperiment) NewParameters() interface{} {
+	return &LLMDataLeakage{}
+}
+
+func (p *LLMDataLeakageExperiment) Severity() verifier.Severity {
+	return verifier.High
+}
+
+func (p *LLMDataLeakageExperiment) Remediation() *verifier.Remediation {
+	return &verifier.Remediation{
+		Summary: "Redact sensitive data from the model's responses before they reach users",
+		Controls: []verifier.Control{
+			{
+				Kind:        verifier.Configuration,
+				Description: "Route requests to the model through an AI gateway or guardrail that detects and redacts PII, secrets and keys in prompts and responses",
+			},
+			{
+				Kind:        verifier.Configuration,
+				Description: "Keep sensitive data out of system prompts and the context the model can retrieve from",
+			},
+		},
+	}
+}
+
 func (p *LLMDataLeakageExperiment) Run(ctx context.Context, experimentConfig *ExperimentConfig) error {
-	var config LLMDataLeakageExperiment
-	yamlObj, _ := yaml.Marshal(experimentConfig)
-	err := yaml.Unmarshal(yamlObj, &config)
+	config := LLMDataLeakageExperiment{Metadata: experimentConfig.Metadata}
+	err := loadParameters(experimentConfig, &config.Parameters)
 	if err != nil {
 		return err
 	}
@@ -149,13 +171,7 @@ func (p *LLMDataLeakageExperiment) Run(ctx context.Context, experimentConfig *Ex
 		return fmt.Errorf("Failed to marshal experiment results: %w", err)
 	}
 
-	file, err := createTempFile(p.Type(), config.Metadata.Name)
-	if err != nil {
-		return fmt.Errorf("Unable to create file cache for experiment results %w", err)
-	}
-
-	_, err = file.Write(resultJSON)
-	if err != nil {
+	if err := storeResult(ctx, config.Metadata.Name, resultJSON); err != nil {
 		return fmt.Errorf("Failed to write experiment results: %w", err)
 	}
 	return nil
@@ -163,9 +179,8 @@ func (p *LLMDataLeakageExperiment) Run(ctx context.Context, experimentConfig *Ex
 
 // Updated Verify method using generics - THIS IS THE KEY CHANGE
 func (p *LLMDataLeakageExperiment) Verify(ctx context.Context, experimentConfig *ExperimentConfig) (*verifier.LegacyOutcome, error) {
-	var config LLMDataLeakageExperiment
-	yamlObj, _ := yaml.Marshal(experimentConfig)
-	err := yaml.Unmarshal(yamlObj, &config)
+	config := LLMDataLeakageExperiment{Metadata: experimentConfig.Metadata}
+	err := loadParameters(experimentConfig, &config.Parameters)
 	if err != nil {
 		return nil, err
 	}
@@ -179,7 +194,7 @@ func (p *LLMDataLeakageExperiment) Verify(ctx context.Context, experimentConfig
 		config.Technique(),
 	)
 
-	rawResults, err := getTempFileContentsForExperiment(p.Type(), config.Metadata.Name)
+	rawResults, err := getResults(ctx, config.Metadata.Name)
 	if err != nil {
 		return nil, fmt.Errorf("Could not fetch experiment results: %w", err)
 	}
@@ -274,16 +289,6 @@ func convertToVerifierResults(apiResults []AIVerifierResult) []verifier.AIVerifi
 }
 
 func (p *LLMDataLeakageExperiment) Cleanup(ctx context.Context, experimentConfig *ExperimentConfig) error {
-	var config LLMDataLeakageExperiment
-	yamlObj, _ := yaml.Marshal(experimentConfig)
-	err := yaml.Unmarshal(yamlObj, &config)
-	if err != nil {
-		return err
-	}
-
-	if err := removeTempFilesForExperiment(p.Type(), config.Metadata.Name); err != nil {
-		return err
-	}
-
+	// Results are kept in the run ledger, so there is nothing to clean up
 	return nil
 }
@@ -8,7 +8,6 @@ import (
 	"fmt"
 	"github.com/operantai/woodpecker/internal/categories"
 	"github.com/operantai/woodpecker/internal/verifier"
-	"gopkg.in/yaml.v3"
 	"net/http"
 	"net/url"
 	"time"
@@ -20,7 +19,7 @@ type LLMDataPoisoningExperiment struct {
 }
 
 type LLMDataPoison struct {
-	Apis []ExecuteAIAPI `yaml:"apis"`
+	Apis []ExecuteAIAPI `yaml:"apis" validate:"required"`
 }
 
 func (p *LLMDataPoisoningExperiment) Type() string {
@@ -40,10 +39,33 @@ func (p *LLMDataPoisoningExperiment) Framework() string {
 	return string(categories.MitreAtlas)
 }
 
+func (p *LLMDataPoisoningExperiment) NewParameters() interface{} {
+	return &LLMDataPoison{}
+}
+
+func (p *LLMDataPoisoningExperiment) Severity() verifier.Severity {
+	return verifier.High
+}
+
+func (p *LLMDataPoisoningExperiment) Remediation() *verifier.Remediation {
+	return &verifier.Remediation{
+		Summary: "Scrub sensitive data from data sent to AI APIs for training or fine-tuning",
+		Controls: []verifier.Control{
+			{
+				Kind:        verifier.Configuration,
+				Description: "Route training and fine-tuning requests through an AI gateway or guardrail that detects and redacts PII, secrets and keys",
+			},
+			{
+				Kind:        verifier.Configuration,
+				Description: "Review and anonymize datasets before they are used for training",
+			},
+		},
+	}
+}
+
 func (p *LLMDataPoisoningExperiment) Run(ctx context.Context, experimentConfig *ExperimentConfig) error {
-	var config LLMDataPoisoningExperiment
-	yamlObj, _ := yaml.Marshal(experimentConfig)
-	err := yaml.Unmarshal(yamlObj, &config)
+	config := LLMDataPoisoningExperiment{Metadata: experimentConfig.Metadata}
+	err := loadParameters(experimentConfig, &config.Parameters)
 	if err != nil {
 		return err
 	}
@@ -150,13 +172,7 @@ func (p *LLMDataPoisoningExperiment) Run(ctx context.Context, experimentConfig *
 		return fmt.Errorf("Failed to marshal experiment results: %w", err)
 	}
 
-	file, err := createTempFile(p.Type(), config.Metadata.Name)
-	if err != nil {
-		return fmt.Errorf("Unable to create file cache for experiment results %w", err)
-	}
-
-	_, err = file.Write(resultJSON)
-	if err != nil {
+	if err := storeResult(ctx, config.Metadata.Name, resultJSON); err != nil {
 		return fmt.Errorf("Failed to write experiment results: %w", err)
 	}
 
@@ -164,9 +180,8 @@ func (p *LLMDataPoisoningExperiment) Run(ctx context.Context, experimentConfig *
 }
 
 func (p *LLMDataPoisoningExperiment) Verify(ctx context.Context, experimentConfig *ExperimentConfig) (*verifier.LegacyOutcome, error) {
-	var config LLMDataPoisoningExperiment
-	yamlObj, _ := yaml.Marshal(experimentConfig)
-	err := yaml.Unmarshal(yamlObj, &config)
+	config := LLMDataPoisoningExperiment{Metadata: experimentConfig.Metadata}
+	err := loadParameters(experimentConfig, &config.Parameters)
 	if err != nil {
 		return nil, err
 	}
@@ -180,7 +195,7 @@ func (p *LLMDataPoisoningExperiment) Verify(ctx context.Context, experimentConfi
 		config.Technique(),
 	)
 
-	rawResults, err := getTempFileContentsForExperiment(p.Type(), config.Metadata.Name)
+	rawResults, err := getResults(ctx, config.Metadata.Name)
 	if err != nil {
 		return nil, fmt.Errorf("Could not fetch experiment results: %w", err)
 	}
@@ -244,16 +259,6 @@ func (p *LLMDataPoisoningExperiment) Verify(ctx context.Context, experimentConfi
 }
 
 func (p *LLMDataPoisoningExperiment) Cleanup(ctx context.Context, experimentConfig *ExperimentConfig) error {
-	var config LLMDataPoisoningExperiment
-	yamlObj, _ := yaml.Marshal(experimentConfig)
-	err := yaml.Unmarshal(yamlObj, &config)
-	if err != nil {
-		return err
-	}
-
-	if err := removeTempFilesForExperiment(p.Type(), config.Metadata.Name); err != nil {
-		return err
-	}
-
+	// Results are kept in the run ledger, so there is nothing to clean up
 	return nil
 }
@@ -0,0 +1,363 @@
+/*
+Copyright 2023 Operant AI
+*/
+package experiments
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"strings"
+
+	"github.com/operantai/woodpecker/internal/categories"
+	"github.com/operantai/woodpecker/internal/k8s"
+	"github.com/operantai/woodpecker/internal/verifier"
+
+	corev1 "k8s.io/api/core/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/labels"
+	"k8s.io/client-go/kubernetes"
+)
+
+type ClearContainerLogsExperimentConfig struct {
+	Metadata   ExperimentMetadata `yaml:"metadata"`
+	Parameters ClearContainerLogs `yaml:"parameters"`
+}
+
+// ClearContainerLogs is an experiment that erases evidence by truncating the log files of victim pods from their node
+type ClearContainerLogs struct {
+	// Selector is a label selector for the victim pods in the experiment's namespace
+	Selector string `yaml:"selector" validate:"required"`
+	// Allowlist are the names of the pods whose logs may be cleared, pods matching the selector outside of it are left alone
+	Allowlist []string `yaml:"allowlist" validate:"required"`
+	// Image runs the shell that truncates the log files, defaults to alpine:latest
+	Image string `yaml:"image"`
+}
+
+// ClearContainerLogsResult is the state of the logs of a victim pod before they were cleared
+type ClearContainerLogsResult struct {
+	Pod string `json:"pod"`
+	// Before are the logs of each container of the pod before they were cleared
+	Before map[string]logSnapshot `json:"before"`
+}
+
+// logSnapshot is enough of the logs of a container to tell whether they were cleared since, as it keeps logging
+type logSnapshot struct {
+	Bytes int    `json:"bytes"`
+	Head  string `json:"head"`
+}
+
+// logSnapshotHeadSize is how many bytes of the logs of a container are kept
+const logSnapshotHeadSize = 256
+
+// podLogsDir is where the kubelet writes the logs of pods on a node
+const podLogsDir = "/var/log/pods"
+
+// logClearerRole labels the pods that clear logs, so that cleaning up never touches the victim pods
+const logClearerRole = "log-clearer"
+
+// Validate checks that the selector is a valid label selector
+func (p *ClearContainerLogs) Validate() error {
+	if _, err := labels.Parse(p.Selector); err != nil {
+		return fmt.Errorf("invalid selector: %w", err)
+	}
+	return nil
+}
+
+func (p *ClearContainerLogsExperimentConfig) Type() string {
+	return "clear-container-logs"
+}
+
+func (p *ClearContainerLogsExperimentConfig) Description() string {
+	return "Truncate the log files of containers from their node to erase evidence of an attack"
+}
+
+func (p *ClearContainerLogsExperimentConfig) Technique() string {
+	return categories.MITRE.DefenseEvasion.ClearContainerLogs.Technique
+}
+
+func (p *ClearContainerLogsExperimentConfig) Tactic() string {
+	return categories.MITRE.DefenseEvasion.ClearContainerLogs.Tactic
+}
+
+func (p *ClearContainerLogsExperimentConfig) Framework() string {
+	return string(categories.Mitre)
+}
+
+func (p *ClearContainerLogsExperimentConfig) NewParameters() interface{} {
+	return &ClearContainerLogs{}
+}
+
+func (p *ClearContainerLogsExperimentConfig) Severity() verifier.Severity {
+	return verifier.Medium
+}
+
+func (p *ClearContainerLogsExperimentConfig) Remediation() *verifier.Remediation {
+	return &verifier.Remediation{
+		Summary: "Refuse hostPath volumes so pods can't reach the log files on their node, and ship logs out of the cluster",
+		Controls: []verifier.Control{
+			{
+				Kind:        verifier.PodSecurityAdmission,
+				Description: "Enforce the baseline Pod Security Standard on the namespace, which refuses hostPath volumes",
+				Snippet: `apiVersion: v1
+kind: Namespace
+metadata:
+  name: my-namespace
+  labels:
+    pod-security.kubernetes.io/enforce: baseline`,
+			},
+			{
+				Kind:        verifier.Kyverno,
+				Description: "Refuse pods mounting the log directories of the node",
+				Snippet: `apiVersion: kyverno.io/v1
+kind: ClusterPolicy
+metadata:
+  name: disallow-host-logs
+spec:
+  validationFailureAction: Enforce
+  rules:
+    - name: host-logs
+      match:
+        any:
+          - resources:
+              kinds: [Pod]
+      validate:
+        message: Mounting the log directories of the node is not allowed
+        deny:
+          conditions:
+            any:
+              - key: "{{ request.object.spec.volumes[?hostPath].hostPath.path[?starts_with(@, '/var/log')] || '' | length(@) }}"
+                operator: GreaterThan
+                value: 0`,
+			},
+		},
+	}
+}
+
+func (p *ClearContainerLogs) image() string {
+	if p.Image == "" {
+		return "alpine:latest"
+	}
+	return p.Image
+}
+
+// Run truncates the logs of each allowlisted victim pod from a pod on its node mounting the victim's directory of
+// podLogsDir
+func (p *ClearContainerLogsExperimentConfig) Run(ctx context.Context, experimentConfig *ExperimentConfig) error {
+	client, err := k8s.NewClient()
+	if err != nil {
+		return err
+	}
+	config := ClearContainerLogsExperimentConfig{Metadata: experimentConfig.Metadata}
+	err = loadParameters(experimentConfig, &config.Parameters)
+	if err != nil {
+		return err
+	}
+	params := config.Parameters
+	namespace := config.Metadata.Namespace
+
+	clientset := client.Clientset
+	pods, err := clientset.CoreV1().Pods(namespace).List(ctx, metav1.ListOptions{LabelSelector: params.Selector})
+	if err != nil {
+		return err
+	}
+	victims := selectVictims(pods.Items, params.Allowlist)
+	if len(victims) == 0 {
+		return fmt.Errorf("None of the %d scheduled pod(s) matching %q in namespace %s are in the allowlist", len(pods.Items), params.Selector, namespace)
+	}
+
+	for i, victim := range victims {
+		before, err := snapshotLogs(ctx, clientset, &victim)
+		if err != nil {
+			return fmt.Errorf("Failed to read the logs of pod %s: %w", victim.Name, err)
+		}
+		resultJSON, err := json.Marshal(&ClearContainerLogsResult{Pod: victim.Name, Before: before})
+		if err != nil {
+			return fmt.Errorf("Failed to marshal experiment results: %w", err)
+		}
+		if err := storeResult(ctx, config.Metadata.Name, resultJSON); err != nil {
+			return fmt.Errorf("Failed to write experiment results: %w", err)
+		}
+
+		clearer := logClearerPod(config.Metadata.Name, fmt.Sprintf("%s-%d", config.Metadata.Name, i), params.image(), &victim)
+		_, err = clientset.CoreV1().Pods(namespace).Create(ctx, clearer, metav1.CreateOptions{})
+		if err != nil {
+			return err
+		}
+		err = k8s.WaitForPodCompletion(ctx, clientset, namespace, clearer.Name, config.Metadata.ReadinessTimeout)
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// selectVictims returns the pods in the allowlist which have been scheduled to a node
+func selectVictims(pods []corev1.Pod, allowlist []string) []corev1.Pod {
+	allowed := make(map[string]bool)
+	for _, name := range allowlist {
+		allowed[name] = true
+	}
+	var victims []corev1.Pod
+	for _, pod := range pods {
+		if allowed[pod.Name] && pod.Spec.NodeName != "" {
+			victims = append(victims, pod)
+		}
+	}
+	return victims
+}
+
+// logClearerPod returns a pod on the node of the victim that truncates its log files. The kubelet keeps the logs of
+// a pod in <namespace>_<name>_<uid>/<container>/<restarts>.log under podLogsDir, which is mounted with that directory
+// as its sub path, so that the logs of any other pod on the node are out of reach.
+func logClearerPod(experiment, name, image string, victim *corev1.Pod) *corev1.Pod {
+	victimDir := fmt.Sprintf("%s_%s_%s", victim.Namespace, victim.Name, victim.UID)
+	logsDir := podLogsDir + "/" + victimDir
+	return &corev1.Pod{
+		ObjectMeta: metav1.ObjectMeta{
+			Name: name,
+			Labels: map[string]string{
+				"experiment": experiment,
+				"role":       logClearerRole,
+			},
+		},
+		Spec: corev1.PodSpec{
+			NodeName:      victim.Spec.NodeName,
+			RestartPolicy: corev1.RestartPolicyNever,
+			Containers: []corev1.Container{
+				{
+					Name:            "clear-logs",
+					Image:           image,
+					ImagePullPolicy: corev1.PullAlways,
+					Command: []string{
+						"sh",
+						"-c",
+						`for f in "$LOGS_DIR"/*/*.log*; do [ -f "$f" ] && : > "$f" && echo "cleared $f"; done`,
+					},
+					Env: []corev1.EnvVar{
+						{Name: "LOGS_DIR", Value: logsDir},
+					},
+					VolumeMounts: []corev1.VolumeMount{
+						{
+							Name:      "pod-logs",
+							MountPath: logsDir,
+							SubPath:   victimDir,
+						},
+					},
+				},
+			},
+			Volumes: []corev1.Volume{
+				{
+					Name: "pod-logs",
+					VolumeSource: corev1.VolumeSource{
+						HostPath: &corev1.HostPathVolumeSource{
+							Path: podLogsDir,
+						},
+					},
+				},
+			},
+		},
+	}
+}
+
+// snapshotLogs returns a snapshot of the logs of each container of a pod
+func snapshotLogs(ctx context.Context, clientset kubernetes.Interface, pod *corev1.Pod) (map[string]logSnapshot, error) {
+	snapshots := make(map[string]logSnapshot)
+	for _, container := range pod.Spec.Containers {
+		logs, err := containerLogs(ctx, clientset, pod.Namespace, pod.Name, container.Name)
+		if err != nil {
+			return nil, err
+		}
+		head := logs
+		if len(head) > logSnapshotHeadSize {
+			head = head[:logSnapshotHeadSize]
+		}
+		snapshots[container.Name] = logSnapshot{Bytes: len(logs), Head: head}
+	}
+	return snapshots, nil
+}
+
+func containerLogs(ctx context.Context, clientset kubernetes.Interface, namespace, pod, container string) (string, error) {
+	logs, err := clientset.CoreV1().Pods(namespace).GetLogs(pod, &corev1.PodLogOptions{Container: container}).DoRaw(ctx)
+	if err != nil {
+		return "", err
+	}
+	return string(logs), nil
+}
+
+// logsCleared reports whether the logs of a container were cleared since the snapshot was taken. Logs written
+// since are appended, so the logs were cleared if they shrank or no longer start as they did.
+func logsCleared(before logSnapshot, after string) bool {
+	if before.Bytes == 0 {
+		return false
+	}
+	return len(after) < before.Bytes || !strings.HasPrefix(after, before.Head)
+}
+
+func (p *ClearContainerLogsExperimentConfig) Verify(ctx context.Context, experimentConfig *ExperimentConfig) (*verifier.LegacyOutcome, error) {
+	client, err := k8s.NewClient()
+	if err != nil {
+		return nil, err
+	}
+	config := ClearContainerLogsExperimentConfig{Metadata: experimentConfig.Metadata}
+	err = loadParameters(experimentConfig, &config.Parameters)
+	if err != nil {
+		return nil, err
+	}
+
+	v := verifier.NewLegacy(
+		config.Metadata.Name,
+		config.Description(),
+		config.Framework(),
+		config.Tactic(),
+		config.Technique(),
+	)
+
+	rawResults, err := getResults(ctx, config.Metadata.Name)
+	if err != nil {
+		return nil, err
+	}
+	// Each victim pod is a test, which succeeds if the logs of any of its containers were cleared
+	for _, rawResult := range rawResults {
+		var result ClearContainerLogsResult
+		if err := json.Unmarshal(rawResult, &result); err != nil {
+			return nil, fmt.Errorf("Could not parse experiment result: %w", err)
+		}
+
+		v.Fail(result.Pod)
+		outputs := make(map[string]string)
+		for container, before := range result.Before {
+			after, err := containerLogs(ctx, client.Clientset, config.Metadata.Namespace, result.Pod, container)
+			if apierrors.IsNotFound(err) {
+				outputs[container] = "pod no longer exists"
+				continue
+			}
+			if err != nil {
+				return nil, err
+			}
+			outputs[container] = fmt.Sprintf("%d bytes of logs before, %d after", before.Bytes, len(after))
+			if logsCleared(before, after) {
+				v.Success(result.Pod)
+			}
+		}
+		v.StoreResultOutputs(result.Pod, outputs)
+	}
+	return v.GetOutcome(), nil
+}
+
+// Cleanup removes the pods that cleared the logs, the victim pods are left alone
+func (p *ClearContainerLogsExperimentConfig) Cleanup(ctx context.Context, experimentConfig *ExperimentConfig) error {
+	client, err := k8s.NewClient()
+	if err != nil {
+		return err
+	}
+	config := ClearContainerLogsExperimentConfig{Metadata: experimentConfig.Metadata}
+	err = loadParameters(experimentConfig, &config.Parameters)
+	if err != nil {
+		return err
+	}
+	return client.Clientset.CoreV1().Pods(config.Metadata.Namespace).DeleteCollection(ctx, metav1.DeleteOptions{}, metav1.ListOptions{
+		LabelSelector: fmt.Sprintf("experiment=%s,role=%s", config.Metadata.Name, logClearerRole),
+	})
+}
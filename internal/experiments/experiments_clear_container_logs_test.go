@@ -0,0 +1,102 @@
+package experiments
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+func TestSelectVictims(t *testing.T) {
+	pod := func(name, node string) corev1.Pod {
+		return corev1.Pod{
+			ObjectMeta: metav1.ObjectMeta{Name: name},
+			Spec:       corev1.PodSpec{NodeName: node},
+		}
+	}
+	pods := []corev1.Pod{pod("victim", "node-1"), pod("bystander", "node-1"), pod("pending", "")}
+
+	tests := []struct {
+		name          string
+		allowlist     []string
+		expectVictims []string
+	}{
+		{
+			name:          "Allowlisted pod",
+			allowlist:     []string{"victim"},
+			expectVictims: []string{"victim"},
+		},
+		{
+			name:      "Pod outside of the allowlist",
+			allowlist: []string{"other"},
+		},
+		{
+			name:          "Pod not scheduled yet",
+			allowlist:     []string{"victim", "pending"},
+			expectVictims: []string{"victim"},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var names []string
+			for _, victim := range selectVictims(pods, tt.allowlist) {
+				names = append(names, victim.Name)
+			}
+			assert.Equal(t, tt.expectVictims, names)
+		})
+	}
+}
+
+func TestLogsCleared(t *testing.T) {
+	tests := []struct {
+		name          string
+		before        logSnapshot
+		after         string
+		expectCleared bool
+	}{
+		{
+			name:   "Logs kept growing",
+			before: logSnapshot{Bytes: 6, Head: "line1\n"},
+			after:  "line1\nline2\n",
+		},
+		{
+			name:          "Logs truncated",
+			before:        logSnapshot{Bytes: 12, Head: "line1\nline2\n"},
+			after:         "",
+			expectCleared: true,
+		},
+		{
+			name:          "Logs truncated and written again",
+			before:        logSnapshot{Bytes: 6, Head: "line1\n"},
+			after:         "line2\nline3\n",
+			expectCleared: true,
+		},
+		{
+			name:   "No logs to clear",
+			before: logSnapshot{},
+			after:  "",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			assert.Equal(t, tt.expectCleared, logsCleared(tt.before, tt.after))
+		})
+	}
+}
+
+func TestClearContainerLogsLogClearerPod(t *testing.T) {
+	victim := &corev1.Pod{
+		ObjectMeta: metav1.ObjectMeta{Name: "victim", Namespace: "default", UID: "1234"},
+		Spec:       corev1.PodSpec{NodeName: "node-1"},
+	}
+	pod := logClearerPod("clear-container-logs", "clear-container-logs-0", "alpine:latest", victim)
+	assert.Equal(t, "node-1", pod.Spec.NodeName)
+	assert.Equal(t, []corev1.EnvVar{{Name: "LOGS_DIR", Value: "/var/log/pods/default_victim_1234"}}, pod.Spec.Containers[0].Env)
+	// Only the logs of the victim are mounted
+	assert.Equal(t, "/var/log/pods", pod.Spec.Volumes[0].HostPath.Path)
+	assert.Equal(t, []corev1.VolumeMount{{Name: "pod-logs", MountPath: "/var/log/pods/default_victim_1234", SubPath: "default_victim_1234"}}, pod.Spec.Containers[0].VolumeMounts)
+	assert.Equal(t, logClearerRole, pod.Labels["role"])
+}
@@ -10,7 +10,6 @@ import (
 	"github.com/operantai/woodpecker/internal/k8s"
 	"github.com/operantai/woodpecker/internal/verifier"
 
-	"gopkg.in/yaml.v3"
 	appsv1 "k8s.io/api/apps/v1"
 	corev1 "k8s.io/api/core/v1"
 	rbacv1 "k8s.io/api/rbac/v1"
@@ -45,17 +44,77 @@ func (p *ClusterAdminBindingExperimentConfig) Framework() string {
 	return string(categories.Mitre)
 }
 
+func (p *ClusterAdminBindingExperimentConfig) NewParameters() interface{} {
+	return &ClusterAdminBinding{}
+}
+
+func (p *ClusterAdminBindingExperimentConfig) Severity() verifier.Severity {
+	return verifier.Critical
+}
+
+func (p *ClusterAdminBindingExperimentConfig) Remediation() *verifier.Remediation {
+	return &verifier.Remediation{
+		Summary: "Don't bind cluster-admin to service accounts, grant workloads a namespaced role with only the permissions they need",
+		Controls: []verifier.Control{
+			{
+				Kind:        verifier.RBAC,
+				Description: "Replace bindings to cluster-admin with a namespaced Role and RoleBinding, and limit who can create ClusterRoleBindings",
+				Snippet: `apiVersion: rbac.authorization.k8s.io/v1
+kind: Role
+metadata:
+  name: my-app
+  namespace: my-namespace
+rules:
+  - apiGroups: [""]
+    resources: [configmaps]
+    verbs: [get, list]
+---
+apiVersion: rbac.authorization.k8s.io/v1
+kind: RoleBinding
+metadata:
+  name: my-app
+  namespace: my-namespace
+roleRef:
+  apiGroup: rbac.authorization.k8s.io
+  kind: Role
+  name: my-app
+subjects:
+  - kind: ServiceAccount
+    name: my-app
+    namespace: my-namespace`,
+			},
+			{
+				Kind:        verifier.Kyverno,
+				Description: "Refuse bindings to the cluster-admin role",
+				Snippet: `apiVersion: kyverno.io/v1
+kind: ClusterPolicy
+metadata:
+  name: restrict-binding-clusteradmin
+spec:
+  validationFailureAction: Enforce
+  rules:
+    - name: clusteradmin-bindings
+      match:
+        any:
+          - resources:
+              kinds: [RoleBinding, ClusterRoleBinding]
+      validate:
+        message: Binding to cluster-admin is not allowed
+        pattern:
+          roleRef:
+            name: "!cluster-admin"`,
+			},
+		},
+	}
+}
+
 func (p *ClusterAdminBindingExperimentConfig) Run(ctx context.Context, experimentConfig *ExperimentConfig) error {
 	client, err := k8s.NewClient()
 	if err != nil {
 		return err
 	}
-	var config ClusterAdminBindingExperimentConfig
-	yamlObj, err := yaml.Marshal(experimentConfig)
-	if err != nil {
-		return err
-	}
-	err = yaml.Unmarshal(yamlObj, &config)
+	config := ClusterAdminBindingExperimentConfig{Metadata: experimentConfig.Metadata}
+	err = loadParameters(experimentConfig, &config.Parameters)
 	if err != nil {
 		return err
 	}
@@ -142,7 +201,10 @@ func (p *ClusterAdminBindingExperimentConfig) Run(ctx context.Context, experimen
 		},
 	}
 	_, err = clientset.AppsV1().Deployments(config.Metadata.Namespace).Create(ctx, deployment, metav1.CreateOptions{})
-	return err
+	if err != nil {
+		return err
+	}
+	return k8s.WaitForDeployment(ctx, clientset, config.Metadata.Namespace, config.Metadata.Name, config.Metadata.ReadinessTimeout)
 }
 
 func (p *ClusterAdminBindingExperimentConfig) Verify(ctx context.Context, experimentConfig *ExperimentConfig) (*verifier.LegacyOutcome, error) {
@@ -150,9 +212,8 @@ func (p *ClusterAdminBindingExperimentConfig) Verify(ctx context.Context, experi
 	if err != nil {
 		return nil, err
 	}
-	var config ClusterAdminBindingExperimentConfig
-	yamlObj, _ := yaml.Marshal(experimentConfig)
-	err = yaml.Unmarshal(yamlObj, &config)
+	config := ClusterAdminBindingExperimentConfig{Metadata: experimentConfig.Metadata}
+	err = loadParameters(experimentConfig, &config.Parameters)
 	if err != nil {
 		return nil, err
 	}
@@ -196,9 +257,8 @@ func (p *ClusterAdminBindingExperimentConfig) Cleanup(ctx context.Context, exper
 	if err != nil {
 		return err
 	}
-	var config ClusterAdminBindingExperimentConfig
-	yamlObj, _ := yaml.Marshal(experimentConfig)
-	err = yaml.Unmarshal(yamlObj, &config)
+	config := ClusterAdminBindingExperimentConfig{Metadata: experimentConfig.Metadata}
+	err = loadParameters(experimentConfig, &config.Parameters)
 	if err != nil {
 		return err
 	}
@@ -10,7 +10,6 @@ import (
 	"github.com/operantai/woodpecker/internal/categories"
 	"github.com/operantai/woodpecker/internal/k8s"
 	"github.com/operantai/woodpecker/internal/verifier"
-	"gopkg.in/yaml.v3"
 	appsv1 "k8s.io/api/apps/v1"
 	corev1 "k8s.io/api/core/v1"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
@@ -53,14 +52,78 @@ func (p *ContainerSecretsExperimentConfig) Framework() string {
 	return string(categories.Mitre)
 }
 
+func (p *ContainerSecretsExperimentConfig) NewParameters() interface{} {
+	return &ContainerSecrets{}
+}
+
+func (p *ContainerSecretsExperimentConfig) Severity() verifier.Severity {
+	return verifier.Low
+}
+
+func (p *ContainerSecretsExperimentConfig) Remediation() *verifier.Remediation {
+	return &verifier.Remediation{
+		Summary: "Keep credentials out of config maps and environment variables, store them in Secrets mounted as files",
+		Controls: []verifier.Control{
+			{
+				Kind:        verifier.Configuration,
+				Description: "Move credentials into a Secret and mount it as a read-only volume instead of exposing it as environment variables",
+				Snippet: `apiVersion: v1
+kind: Pod
+metadata:
+  name: my-app
+spec:
+  containers:
+    - name: my-app
+      image: my-app:latest
+      volumeMounts:
+        - name: credentials
+          mountPath: /var/run/secrets/my-app
+          readOnly: true
+  volumes:
+    - name: credentials
+      secret:
+        secretName: my-app-credentials`,
+			},
+			{
+				Kind:        verifier.Kyverno,
+				Description: "Refuse environment variables that look like credentials but aren't read from a Secret",
+				Snippet: `apiVersion: kyverno.io/v1
+kind: ClusterPolicy
+metadata:
+  name: disallow-credentials-in-env
+spec:
+  validationFailureAction: Enforce
+  rules:
+    - name: credentials-in-env
+      match:
+        any:
+          - resources:
+              kinds: [Pod]
+      validate:
+        message: Credentials must be read from a Secret
+        foreach:
+          - list: request.object.spec.containers[].env[]
+            deny:
+              conditions:
+                all:
+                  - key: "{{ element.name }}"
+                    operator: AnyIn
+                    value: ["*PASSWORD*", "*SECRET*", "*TOKEN*", "*API_KEY*"]
+                  - key: "{{ element.value || '' }}"
+                    operator: NotEquals
+                    value: ""`,
+			},
+		},
+	}
+}
+
 func (p *ContainerSecretsExperimentConfig) Run(ctx context.Context, experimentConfig *ExperimentConfig) error {
 	client, err := k8s.NewClient()
 	if err != nil {
 		return err
 	}
-	var containerSecretsExperimentConfig ContainerSecretsExperimentConfig
-	yamlObj, _ := yaml.Marshal(experimentConfig)
-	err = yaml.Unmarshal(yamlObj, &containerSecretsExperimentConfig)
+	containerSecretsExperimentConfig := ContainerSecretsExperimentConfig{Metadata: experimentConfig.Metadata}
+	err = loadParameters(experimentConfig, &containerSecretsExperimentConfig.Parameters)
 	if err != nil {
 		return err
 	}
@@ -128,6 +191,10 @@ func (p *ContainerSecretsExperimentConfig) Run(ctx context.Context, experimentCo
 		if err != nil {
 			return err
 		}
+		err = k8s.WaitForDeployment(ctx, clientset, containerSecretsExperimentConfig.Metadata.Namespace, containerSecretsExperimentConfig.Metadata.Name, containerSecretsExperimentConfig.Metadata.ReadinessTimeout)
+		if err != nil {
+			return err
+		}
 	}
 	if params.ConfigMapCheck {
 		_, err = clientset.CoreV1().ConfigMaps(containerSecretsExperimentConfig.Metadata.Namespace).Create(ctx, configMap, metav1.CreateOptions{})
@@ -141,9 +208,8 @@ func (p *ContainerSecretsExperimentConfig) Verify(ctx context.Context, experimen
 	if err != nil {
 		return nil, err
 	}
-	var containerSecretsExperimentConfig ContainerSecretsExperimentConfig
-	yamlObj, _ := yaml.Marshal(experimentConfig)
-	err = yaml.Unmarshal(yamlObj, &containerSecretsExperimentConfig)
+	containerSecretsExperimentConfig := ContainerSecretsExperimentConfig{Metadata: experimentConfig.Metadata}
+	err = loadParameters(experimentConfig, &containerSecretsExperimentConfig.Parameters)
 	if err != nil {
 		return nil, err
 	}
@@ -228,9 +294,8 @@ func (p *ContainerSecretsExperimentConfig) Cleanup(ctx context.Context, experime
 	if err != nil {
 		return err
 	}
-	var containerSecretsExperimentConfig ContainerSecretsExperimentConfig
-	yamlObj, _ := yaml.Marshal(experimentConfig)
-	err = yaml.Unmarshal(yamlObj, &containerSecretsExperimentConfig)
+	containerSecretsExperimentConfig := ContainerSecretsExperimentConfig{Metadata: experimentConfig.Metadata}
+	err = loadParameters(experimentConfig, &containerSecretsExperimentConfig.Parameters)
 	if err != nil {
 		return err
 	}
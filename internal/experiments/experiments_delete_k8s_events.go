@@ -0,0 +1,297 @@
+/*
+Copyright 2023 Operant AI
+*/
+package experiments
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+
+	"github.com/operantai/woodpecker/internal/categories"
+	"github.com/operantai/woodpecker/internal/k8s"
+	"github.com/operantai/woodpecker/internal/verifier"
+
+	corev1 "k8s.io/api/core/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/kubernetes"
+)
+
+type DeleteK8sEventsExperimentConfig struct {
+	Metadata   ExperimentMetadata `yaml:"metadata"`
+	Parameters DeleteK8sEvents    `yaml:"parameters"`
+}
+
+// DeleteK8sEvents is an experiment that erases evidence by deleting events, acting as a ServiceAccount
+type DeleteK8sEvents struct {
+	// ServiceAccountName is the ServiceAccount in the experiment's namespace the events are deleted as, defaults to default
+	ServiceAccountName string `yaml:"serviceAccountName"`
+	// Namespaces are the namespaces to delete events in, a marker event is created in each of them
+	Namespaces []string `yaml:"namespaces" validate:"required"`
+}
+
+// DeleteK8sEventsResult is what happened when deleting events in a namespace
+type DeleteK8sEventsResult struct {
+	Namespace string `json:"namespace"`
+	// DeleteCollectionError is why deleting the events as a collection failed
+	DeleteCollectionError string `json:"deleteCollectionError,omitempty"`
+	// DeleteError is why deleting the marker event on its own failed, which is only tried if deleting the collection did
+	DeleteError string `json:"deleteError,omitempty"`
+	// Refused is whether the cluster refused to delete the marker event, rather than it failing for another reason
+	Refused bool `json:"refused,omitempty"`
+}
+
+func (p *DeleteK8sEventsExperimentConfig) Type() string {
+	return "delete-k8s-events"
+}
+
+func (p *DeleteK8sEventsExperimentConfig) Description() string {
+	return "Delete Kubernetes events as a service account to erase evidence of an attack"
+}
+
+func (p *DeleteK8sEventsExperimentConfig) Technique() string {
+	return categories.MITRE.DefenseEvasion.DeleteK8sEvents.Technique
+}
+
+func (p *DeleteK8sEventsExperimentConfig) Tactic() string {
+	return categories.MITRE.DefenseEvasion.DeleteK8sEvents.Tactic
+}
+
+func (p *DeleteK8sEventsExperimentConfig) Framework() string {
+	return string(categories.Mitre)
+}
+
+func (p *DeleteK8sEventsExperimentConfig) NewParameters() interface{} {
+	return &DeleteK8sEvents{}
+}
+
+func (p *DeleteK8sEventsExperimentConfig) Severity() verifier.Severity {
+	return verifier.Medium
+}
+
+func (p *DeleteK8sEventsExperimentConfig) Remediation() *verifier.Remediation {
+	return &verifier.Remediation{
+		Summary: "Don't grant workloads delete or deletecollection on events, and ship events out of the cluster so they outlive deletion",
+		Controls: []verifier.Control{
+			{
+				Kind:        verifier.RBAC,
+				Description: "Grant workloads that need events read-only access to them, without delete or deletecollection",
+				Snippet: `apiVersion: rbac.authorization.k8s.io/v1
+kind: Role
+metadata:
+  name: event-reader
+  namespace: my-namespace
+rules:
+  - apiGroups: ["", events.k8s.io]
+    resources: [events]
+    verbs: [get, list, watch]`,
+			},
+			{
+				Kind:        verifier.Configuration,
+				Description: "Record event deletions in the audit log, which is kept outside of the cluster",
+				Snippet: `apiVersion: audit.k8s.io/v1
+kind: Policy
+rules:
+  - level: Metadata
+    verbs: [delete, deletecollection]
+    resources:
+      - group: ""
+        resources: [events]
+      - group: events.k8s.io
+        resources: [events]`,
+			},
+		},
+	}
+}
+
+// serviceAccountName returns the ServiceAccount the events are deleted as
+func (p *DeleteK8sEvents) serviceAccountName() string {
+	if p.ServiceAccountName == "" {
+		return "default"
+	}
+	return p.ServiceAccountName
+}
+
+func (p *DeleteK8sEventsExperimentConfig) Run(ctx context.Context, experimentConfig *ExperimentConfig) error {
+	client, err := k8s.NewClient()
+	if err != nil {
+		return err
+	}
+	config := DeleteK8sEventsExperimentConfig{Metadata: experimentConfig.Metadata}
+	err = loadParameters(experimentConfig, &config.Parameters)
+	if err != nil {
+		return err
+	}
+	params := config.Parameters
+
+	// The marker events are created as the user running woodpecker, only deleting them is part of the attack
+	for _, namespace := range params.Namespaces {
+		if err := createMarkerEvent(ctx, client.Clientset, config.Metadata.Name, namespace); err != nil {
+			return fmt.Errorf("Failed to create marker event in namespace %s: %w", namespace, err)
+		}
+	}
+
+	serviceAccount, err := client.ImpersonateServiceAccount(config.Metadata.Namespace, params.serviceAccountName())
+	if err != nil {
+		return err
+	}
+	for _, namespace := range params.Namespaces {
+		result, err := deleteEvents(ctx, serviceAccount.Clientset, config.Metadata.Name, namespace)
+		if err != nil {
+			return fmt.Errorf("Failed to act as service account %s/%s: %w", config.Metadata.Namespace, params.serviceAccountName(), err)
+		}
+
+		resultJSON, err := json.Marshal(&result)
+		if err != nil {
+			return fmt.Errorf("Failed to marshal experiment results: %w", err)
+		}
+		if err := storeResult(ctx, config.Metadata.Name, resultJSON); err != nil {
+			return fmt.Errorf("Failed to write experiment results: %w", err)
+		}
+	}
+	return nil
+}
+
+// markerEventName is the name of the marker event of an experiment
+func markerEventName(experiment string) string {
+	return experiment + "-marker"
+}
+
+// createMarkerEvent creates the event the experiment tries to delete in a namespace, unless it's already there
+func createMarkerEvent(ctx context.Context, clientset kubernetes.Interface, experiment, namespace string) error {
+	now := metav1.Now()
+	event := &corev1.Event{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      markerEventName(experiment),
+			Namespace: namespace,
+			Labels: map[string]string{
+				"experiment": experiment,
+			},
+		},
+		InvolvedObject: corev1.ObjectReference{
+			APIVersion: "v1",
+			Kind:       "Namespace",
+			Name:       namespace,
+		},
+		Reason:         "WoodpeckerMarker",
+		Message:        fmt.Sprintf("Marker event the %s experiment tries to delete", experiment),
+		Type:           corev1.EventTypeNormal,
+		Source:         corev1.EventSource{Component: "woodpecker"},
+		FirstTimestamp: now,
+		LastTimestamp:  now,
+		Count:          1,
+	}
+	_, err := clientset.CoreV1().Events(namespace).Create(ctx, event, metav1.CreateOptions{})
+	if apierrors.IsAlreadyExists(err) {
+		return nil
+	}
+	return err
+}
+
+// deleteEvents deletes the marker event of the experiment in a namespace as a collection, as an attacker wiping
+// events would, and on its own if that fails. Only the marker event is selected, so that no real evidence is lost.
+// Refusals are recorded in the result, an error is only returned if the user running woodpecker can't impersonate.
+func deleteEvents(ctx context.Context, clientset kubernetes.Interface, experiment, namespace string) (DeleteK8sEventsResult, error) {
+	result := DeleteK8sEventsResult{Namespace: namespace}
+	events := clientset.CoreV1().Events(namespace)
+	err := events.DeleteCollection(ctx, metav1.DeleteOptions{}, metav1.ListOptions{
+		LabelSelector: "experiment=" + experiment,
+	})
+	if err == nil {
+		return result, nil
+	}
+	if k8s.IsImpersonationDenied(err) {
+		return result, err
+	}
+	result.DeleteCollectionError = err.Error()
+
+	err = events.Delete(ctx, markerEventName(experiment), metav1.DeleteOptions{})
+	if k8s.IsImpersonationDenied(err) {
+		return result, err
+	}
+	if err != nil && !apierrors.IsNotFound(err) {
+		result.DeleteError = err.Error()
+		result.Refused = k8s.IsBlocked(err)
+	}
+	return result, nil
+}
+
+func (p *DeleteK8sEventsExperimentConfig) Verify(ctx context.Context, experimentConfig *ExperimentConfig) (*verifier.LegacyOutcome, error) {
+	client, err := k8s.NewClient()
+	if err != nil {
+		return nil, err
+	}
+	config := DeleteK8sEventsExperimentConfig{Metadata: experimentConfig.Metadata}
+	err = loadParameters(experimentConfig, &config.Parameters)
+	if err != nil {
+		return nil, err
+	}
+
+	v := verifier.NewLegacy(
+		config.Metadata.Name,
+		config.Description(),
+		config.Framework(),
+		config.Tactic(),
+		config.Technique(),
+	)
+
+	rawResults, err := getResults(ctx, config.Metadata.Name)
+	if err != nil {
+		return nil, err
+	}
+	results := make(map[string]DeleteK8sEventsResult)
+	for _, rawResult := range rawResults {
+		var result DeleteK8sEventsResult
+		if err := json.Unmarshal(rawResult, &result); err != nil {
+			return nil, fmt.Errorf("Could not parse experiment result: %w", err)
+		}
+		results[result.Namespace] = result
+	}
+
+	// Each namespace is a test, which succeeds if the marker event is gone after trying to delete it. Without an
+	// attempt on record the marker may have expired, or never been created, so it being gone proves nothing.
+	for _, namespace := range config.Parameters.Namespaces {
+		result, ok := results[namespace]
+		if !ok {
+			v.Fail(namespace)
+			v.StoreResultOutputs(namespace, "no attempt to delete the events of this namespace was recorded")
+			continue
+		}
+		_, err := client.Clientset.CoreV1().Events(namespace).Get(ctx, markerEventName(config.Metadata.Name), metav1.GetOptions{})
+		switch {
+		case apierrors.IsNotFound(err):
+			v.Success(namespace)
+		case err != nil:
+			return nil, err
+		case result.Refused:
+			v.Blocked(namespace)
+		default:
+			v.Fail(namespace)
+		}
+		v.StoreResultOutputs(namespace, result)
+	}
+	return v.GetOutcome(), nil
+}
+
+func (p *DeleteK8sEventsExperimentConfig) Cleanup(ctx context.Context, experimentConfig *ExperimentConfig) error {
+	client, err := k8s.NewClient()
+	if err != nil {
+		return err
+	}
+	config := DeleteK8sEventsExperimentConfig{Metadata: experimentConfig.Metadata}
+	err = loadParameters(experimentConfig, &config.Parameters)
+	if err != nil {
+		return err
+	}
+
+	var errs []error
+	for _, namespace := range config.Parameters.Namespaces {
+		err := client.Clientset.CoreV1().Events(namespace).Delete(ctx, markerEventName(config.Metadata.Name), metav1.DeleteOptions{})
+		if err != nil && !apierrors.IsNotFound(err) {
+			errs = append(errs, err)
+		}
+	}
+	return errors.Join(errs...)
+}
@@ -0,0 +1,92 @@
+package experiments
+
+import (
+	"context"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	"k8s.io/client-go/kubernetes/fake"
+	k8stesting "k8s.io/client-go/testing"
+)
+
+func TestDeleteEvents(t *testing.T) {
+	eventsResource := schema.GroupResource{Resource: "events"}
+	forbidden := apierrors.NewForbidden(eventsResource, "", assert.AnError)
+	forbidden.ErrStatus.Message = `events is forbidden: User "dev" cannot delete resource "events" in API group "" in the namespace "default"`
+	unavailable := apierrors.NewServiceUnavailable("etcdserver: request timed out")
+	impersonationDenied := apierrors.NewForbidden(schema.GroupResource{Resource: "serviceaccounts"}, "default",
+		assert.AnError)
+	impersonationDenied.ErrStatus.Message = `serviceaccounts "default" is forbidden: User "dev" cannot impersonate resource "serviceaccounts"`
+
+	tests := []struct {
+		name         string
+		denied       map[string]error
+		expectResult DeleteK8sEventsResult
+		expectErr    bool
+	}{
+		{
+			name:         "Events deleted as a collection",
+			expectResult: DeleteK8sEventsResult{Namespace: "default"},
+		},
+		{
+			name:   "Events deleted one by one",
+			denied: map[string]error{"delete-collection": forbidden},
+			expectResult: DeleteK8sEventsResult{
+				Namespace:             "default",
+				DeleteCollectionError: forbidden.Error(),
+			},
+		},
+		{
+			name:   "Deleting events refused",
+			denied: map[string]error{"delete-collection": forbidden, "delete": forbidden},
+			expectResult: DeleteK8sEventsResult{
+				Namespace:             "default",
+				DeleteCollectionError: forbidden.Error(),
+				DeleteError:           forbidden.Error(),
+				Refused:               true,
+			},
+		},
+		{
+			name:   "Deleting events failed",
+			denied: map[string]error{"delete-collection": forbidden, "delete": unavailable},
+			expectResult: DeleteK8sEventsResult{
+				Namespace:             "default",
+				DeleteCollectionError: forbidden.Error(),
+				DeleteError:           unavailable.Error(),
+			},
+		},
+		{
+			name:         "Impersonation denied",
+			denied:       map[string]error{"delete-collection": impersonationDenied},
+			expectResult: DeleteK8sEventsResult{Namespace: "default"},
+			expectErr:    true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ctx := context.Background()
+			clientset := fake.NewSimpleClientset()
+			assert.NoError(t, createMarkerEvent(ctx, clientset, "delete-k8s-events", "default"))
+			// Creating the marker again is a no-op
+			assert.NoError(t, createMarkerEvent(ctx, clientset, "delete-k8s-events", "default"))
+			for verb, err := range tt.denied {
+				err := err
+				clientset.PrependReactor(verb, "events", func(k8stesting.Action) (bool, runtime.Object, error) {
+					return true, nil, err
+				})
+			}
+
+			result, err := deleteEvents(ctx, clientset, "delete-k8s-events", "default")
+			if tt.expectErr {
+				assert.Error(t, err)
+			} else {
+				assert.NoError(t, err)
+			}
+			assert.Equal(t, tt.expectResult, result)
+		})
+	}
+}
@@ -13,7 +13,6 @@ import (
 	"github.com/operantai/woodpecker/internal/categories"
 	"github.com/operantai/woodpecker/internal/k8s"
 	"github.com/operantai/woodpecker/internal/verifier"
-	"gopkg.in/yaml.v3"
 )
 
 type ExecuteAPIExperimentConfig struct {
@@ -22,19 +21,19 @@ type ExecuteAPIExperimentConfig struct {
 }
 
 type ExecuteAPI struct {
-	Targets []ExecuteAPITargets `yaml:"targets"`
+	Targets []ExecuteAPITargets `yaml:"targets" validate:"required"`
 }
 
 type ExecuteAPITargets struct {
-	Target   string              `yaml:"target"`
-	Port     int                 `yaml:"port"`
-	Payloads []ExecuteAPIPayload `yaml:"payloads"`
+	Target   string              `yaml:"target" validate:"required"`
+	Port     int                 `yaml:"port" validate:"required"`
+	Payloads []ExecuteAPIPayload `yaml:"payloads" validate:"required"`
 }
 
 type ExecuteAPIPayload struct {
 	Description      string            `yaml:"description"`
-	Path             string            `yaml:"path"`
-	Method           string            `yaml:"method"`
+	Path             string            `yaml:"path" validate:"required"`
+	Method           string            `yaml:"method" validate:"required"`
 	Headers          map[string]string `yaml:"headers"`
 	Payload          string            `yaml:"payload"`
 	ExpectedResponse string            `yaml:"expectedResponse"`
@@ -65,14 +64,52 @@ func (p *ExecuteAPIExperimentConfig) Framework() string {
 	return string(categories.Mitre)
 }
 
+func (p *ExecuteAPIExperimentConfig) NewParameters() interface{} {
+	return &ExecuteAPI{}
+}
+
+func (p *ExecuteAPIExperimentConfig) Severity() verifier.Severity {
+	return verifier.Medium
+}
+
+func (p *ExecuteAPIExperimentConfig) Remediation() *verifier.Remediation {
+	return &verifier.Remediation{
+		Summary: "Validate the input of the targeted API and only let the clients that need it reach the service",
+		Controls: []verifier.Control{
+			{
+				Kind:        verifier.Configuration,
+				Description: "Validate and sanitize request payloads in the application, or put it behind an API gateway or web application firewall that does",
+			},
+			{
+				Kind:        verifier.NetworkPolicy,
+				Description: "Only allow ingress to the service from the workloads that call it",
+				Snippet: `apiVersion: networking.k8s.io/v1
+kind: NetworkPolicy
+metadata:
+  name: my-app-ingress
+  namespace: my-namespace
+spec:
+  podSelector:
+    matchLabels:
+      app: my-app
+  policyTypes: [Ingress]
+  ingress:
+    - from:
+        - podSelector:
+            matchLabels:
+              app: my-client`,
+			},
+		},
+	}
+}
+
 func (p *ExecuteAPIExperimentConfig) Run(ctx context.Context, experimentConfig *ExperimentConfig) error {
 	client, err := k8s.NewClient()
 	if err != nil {
 		return err
 	}
-	var config ExecuteAPIExperimentConfig
-	yamlObj, _ := yaml.Marshal(experimentConfig)
-	err = yaml.Unmarshal(yamlObj, &config)
+	config := ExecuteAPIExperimentConfig{Metadata: experimentConfig.Metadata}
+	err = loadParameters(experimentConfig, &config.Parameters)
 	if err != nil {
 		return err
 	}
@@ -134,13 +171,7 @@ func (p *ExecuteAPIExperimentConfig) Run(ctx context.Context, experimentConfig *
 			return fmt.Errorf("Failed to marshal experiment results: %w", err)
 		}
 
-		file, err := createTempFile(p.Type(), config.Metadata.Name)
-		if err != nil {
-			return fmt.Errorf("Unable to create file cache for experiment results %w", err)
-		}
-
-		_, err = file.Write(resultJSON)
-		if err != nil {
+		if err := storeResult(ctx, config.Metadata.Name, resultJSON); err != nil {
 			return fmt.Errorf("Failed to write experiment results: %w", err)
 		}
 	}
@@ -149,9 +180,8 @@ func (p *ExecuteAPIExperimentConfig) Run(ctx context.Context, experimentConfig *
 }
 
 func (p *ExecuteAPIExperimentConfig) Verify(ctx context.Context, experimentConfig *ExperimentConfig) (*verifier.LegacyOutcome, error) {
-	var config ExecuteAPIExperimentConfig
-	yamlObj, _ := yaml.Marshal(experimentConfig)
-	err := yaml.Unmarshal(yamlObj, &config)
+	config := ExecuteAPIExperimentConfig{Metadata: experimentConfig.Metadata}
+	err := loadParameters(experimentConfig, &config.Parameters)
 	if err != nil {
 		return nil, err
 	}
@@ -164,7 +194,7 @@ func (p *ExecuteAPIExperimentConfig) Verify(ctx context.Context, experimentConfi
 		config.Technique(),
 	)
 
-	rawResults, err := getTempFileContentsForExperiment(p.Type(), config.Metadata.Name)
+	rawResults, err := getResults(ctx, config.Metadata.Name)
 	if err != nil {
 		return nil, fmt.Errorf("Could not fetch experiment results: %w", err)
 	}
@@ -195,16 +225,6 @@ func (p *ExecuteAPIExperimentConfig) Verify(ctx context.Context, experimentConfi
 }
 
 func (p *ExecuteAPIExperimentConfig) Cleanup(ctx context.Context, experimentConfig *ExperimentConfig) error {
-	var config ExecuteAPIExperimentConfig
-	yamlObj, _ := yaml.Marshal(experimentConfig)
-	err := yaml.Unmarshal(yamlObj, &config)
-	if err != nil {
-		return err
-	}
-
-	if err := removeTempFilesForExperiment(p.Type(), config.Metadata.Name); err != nil {
-		return err
-	}
-
+	// Results are kept in the run ledger, so there is nothing to clean up
 	return nil
 }
@@ -10,7 +10,6 @@ import (
 	"github.com/operantai/woodpecker/internal/categories"
 	"github.com/operantai/woodpecker/internal/k8s"
 	"github.com/operantai/woodpecker/internal/verifier"
-	"gopkg.in/yaml.v3"
 	appsv1 "k8s.io/api/apps/v1"
 	corev1 "k8s.io/api/core/v1"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
@@ -27,7 +26,7 @@ type HostPathMount struct {
 }
 
 type HostPath struct {
-	Path string `yaml:"path"`
+	Path string `yaml:"path" validate:"required"`
 }
 
 func (p *HostPathMountExperimentConfig) Type() string {
@@ -50,14 +49,61 @@ func (p *HostPathMountExperimentConfig) Framework() string {
 	return string(categories.Mitre)
 }
 
+func (p *HostPathMountExperimentConfig) NewParameters() interface{} {
+	return &HostPathMount{}
+}
+
+func (p *HostPathMountExperimentConfig) Severity() verifier.Severity {
+	return verifier.High
+}
+
+func (p *HostPathMountExperimentConfig) Remediation() *verifier.Remediation {
+	return &verifier.Remediation{
+		Summary: "Refuse hostPath volumes with the baseline Pod Security Standard or an admission policy",
+		Controls: []verifier.Control{
+			{
+				Kind:        verifier.PodSecurityAdmission,
+				Description: "Enforce the baseline Pod Security Standard on the namespace, which refuses hostPath volumes",
+				Snippet: `apiVersion: v1
+kind: Namespace
+metadata:
+  name: my-namespace
+  labels:
+    pod-security.kubernetes.io/enforce: baseline`,
+			},
+			{
+				Kind:        verifier.Kyverno,
+				Description: "Refuse pods mounting hostPath volumes",
+				Snippet: `apiVersion: kyverno.io/v1
+kind: ClusterPolicy
+metadata:
+  name: disallow-host-path
+spec:
+  validationFailureAction: Enforce
+  rules:
+    - name: host-path
+      match:
+        any:
+          - resources:
+              kinds: [Pod]
+      validate:
+        message: hostPath volumes are not allowed
+        pattern:
+          spec:
+            =(volumes):
+              - X(hostPath): "null"`,
+			},
+		},
+	}
+}
+
 func (p *HostPathMountExperimentConfig) Run(ctx context.Context, experimentConfig *ExperimentConfig) error {
 	client, err := k8s.NewClient()
 	if err != nil {
 		return err
 	}
-	var hostPathMountExperimentConfig HostPathMountExperimentConfig
-	yamlObj, _ := yaml.Marshal(experimentConfig)
-	err = yaml.Unmarshal(yamlObj, &hostPathMountExperimentConfig)
+	hostPathMountExperimentConfig := HostPathMountExperimentConfig{Metadata: experimentConfig.Metadata}
+	err = loadParameters(experimentConfig, &hostPathMountExperimentConfig.Parameters)
 	if err != nil {
 		return err
 	}
@@ -120,7 +166,10 @@ func (p *HostPathMountExperimentConfig) Run(ctx context.Context, experimentConfi
 		},
 	}
 	_, err = clientset.AppsV1().Deployments(hostPathMountExperimentConfig.Metadata.Namespace).Create(ctx, deployment, metav1.CreateOptions{})
-	return err
+	if err != nil {
+		return err
+	}
+	return k8s.WaitForDeployment(ctx, clientset, hostPathMountExperimentConfig.Metadata.Namespace, hostPathMountExperimentConfig.Metadata.Name, hostPathMountExperimentConfig.Metadata.ReadinessTimeout)
 }
 
 func (p *HostPathMountExperimentConfig) Verify(ctx context.Context, experimentConfig *ExperimentConfig) (*verifier.LegacyOutcome, error) {
@@ -128,9 +177,8 @@ func (p *HostPathMountExperimentConfig) Verify(ctx context.Context, experimentCo
 	if err != nil {
 		return nil, err
 	}
-	var hostPathMountExperimentConfig HostPathMountExperimentConfig
-	yamlObj, _ := yaml.Marshal(experimentConfig)
-	err = yaml.Unmarshal(yamlObj, &hostPathMountExperimentConfig)
+	hostPathMountExperimentConfig := HostPathMountExperimentConfig{Metadata: experimentConfig.Metadata}
+	err = loadParameters(experimentConfig, &hostPathMountExperimentConfig.Parameters)
 	if err != nil {
 		return nil, err
 	}
@@ -177,9 +225,8 @@ func (p *HostPathMountExperimentConfig) Cleanup(ctx context.Context, experimentC
 	if err != nil {
 		return err
 	}
-	var hostPathMountExperimentConfig HostPathMountExperimentConfig
-	yamlObj, _ := yaml.Marshal(experimentConfig)
-	err = yaml.Unmarshal(yamlObj, &hostPathMountExperimentConfig)
+	hostPathMountExperimentConfig := HostPathMountExperimentConfig{Metadata: experimentConfig.Metadata}
+	err = loadParameters(experimentConfig, &hostPathMountExperimentConfig.Parameters)
 	if err != nil {
 		return err
 	}
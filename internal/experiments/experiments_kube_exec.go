@@ -9,8 +9,6 @@ import (
 	"fmt"
 	"regexp"
 
-	"gopkg.in/yaml.v3"
-
 	"github.com/operantai/woodpecker/internal/categories"
 	"github.com/operantai/woodpecker/internal/k8s"
 	"github.com/operantai/woodpecker/internal/verifier"
@@ -24,13 +22,21 @@ type KubeExec struct {
 // KubeExec is an experiment that attempts to run a command in a target container
 type KubeExecParameters struct {
 	Target struct {
-		Pod       string `yaml:"pod"`
+		Pod       string `yaml:"pod" validate:"required"`
 		Container string `yaml:"container"`
 	} `yaml:"target"`
-	Command             []string `yaml:"command"`
+	Command             []string `yaml:"command" validate:"required"`
 	ExpectedOutputRegex string   `yaml:"expectedOutputRegex"`
 }
 
+// Validate checks that the expected output of the command is a valid regular expression
+func (p *KubeExecParameters) Validate() error {
+	if _, err := regexp.Compile(p.ExpectedOutputRegex); err != nil {
+		return fmt.Errorf("invalid expectedOutputRegex: %w", err)
+	}
+	return nil
+}
+
 type KubeExecResult struct {
 	Stdout string `json:"stdout"`
 	Stderr string `json:"stderr"`
@@ -56,14 +62,67 @@ func (k *KubeExec) Framework() string {
 	return string(categories.Mitre)
 }
 
+func (k *KubeExec) NewParameters() interface{} {
+	return &KubeExecParameters{}
+}
+
+func (k *KubeExec) Severity() verifier.Severity {
+	return verifier.Medium
+}
+
+func (k *KubeExec) Remediation() *verifier.Remediation {
+	return &verifier.Remediation{
+		Summary: "Limit who can exec into containers, and alert on exec sessions in production namespaces",
+		Controls: []verifier.Control{
+			{
+				Kind:        verifier.RBAC,
+				Description: "Remove the create verb on pods/exec from roles that don't need interactive access",
+				Snippet: `apiVersion: rbac.authorization.k8s.io/v1
+kind: Role
+metadata:
+  name: developer
+  namespace: my-namespace
+rules:
+  - apiGroups: [""]
+    resources: [pods, pods/log]
+    verbs: [get, list, watch]`,
+			},
+			{
+				Kind:        verifier.Kyverno,
+				Description: "Refuse exec sessions into pods of the namespace",
+				Snippet: `apiVersion: kyverno.io/v1
+kind: ClusterPolicy
+metadata:
+  name: deny-exec
+spec:
+  validationFailureAction: Enforce
+  rules:
+    - name: deny-exec
+      match:
+        any:
+          - resources:
+              kinds: [Pod/exec]
+              namespaces: [my-namespace]
+      validate:
+        message: Exec into pods is not allowed
+        deny:
+          conditions:
+            any:
+              - key: "{{ request.operation || 'BADOPERATION' }}"
+                operator: Equals
+                value: CONNECT`,
+			},
+		},
+	}
+}
+
 func (k *KubeExec) Run(ctx context.Context, experimentConfig *ExperimentConfig) error {
 	client, err := k8s.NewClient()
 	if err != nil {
 		return err
 	}
-	var config KubeExec
-	yamlObj, _ := yaml.Marshal(experimentConfig)
-	err = yaml.Unmarshal(yamlObj, &config)
+	config := KubeExec{Metadata: experimentConfig.Metadata}
+	err = loadParameters(experimentConfig, &config.Parameters)
 	if err != nil {
 		return err
 	}
@@ -86,22 +145,15 @@ func (k *KubeExec) Run(ctx context.Context, experimentConfig *ExperimentConfig)
 		return fmt.Errorf("Failed to marshal experiment results: %w", err)
 	}
 
-	file, err := createTempFile(k.Type(), config.Metadata.Name)
-	if err != nil {
-		return fmt.Errorf("Unable to create file cache for experiment results %w", err)
-	}
-
-	_, err = file.Write(resultJSON)
-	if err != nil {
+	if err := storeResult(ctx, config.Metadata.Name, resultJSON); err != nil {
 		return fmt.Errorf("Failed to write experiment results: %w", err)
 	}
 	return nil
 }
 
 func (k *KubeExec) Verify(ctx context.Context, experimentConfig *ExperimentConfig) (*verifier.LegacyOutcome, error) {
-	var config KubeExec
-	yamlObj, _ := yaml.Marshal(experimentConfig)
-	err := yaml.Unmarshal(yamlObj, &config)
+	config := KubeExec{Metadata: experimentConfig.Metadata}
+	err := loadParameters(experimentConfig, &config.Parameters)
 	if err != nil {
 		return nil, err
 	}
@@ -115,7 +167,12 @@ func (k *KubeExec) Verify(ctx context.Context, experimentConfig *ExperimentConfi
 		config.Technique(),
 	)
 
-	rawResults, err := getTempFileContentsForExperiment(k.Type(), config.Metadata.Name)
+	regex, err := regexp.Compile(config.Parameters.ExpectedOutputRegex)
+	if err != nil {
+		return nil, fmt.Errorf("Invalid expectedOutputRegex: %w", err)
+	}
+
+	rawResults, err := getResults(ctx, config.Metadata.Name)
 	if err != nil {
 		return nil, fmt.Errorf("Could not fetch experiment results: %w", err)
 	}
@@ -129,7 +186,6 @@ func (k *KubeExec) Verify(ctx context.Context, experimentConfig *ExperimentConfi
 		if len(result.Stderr) > 0 {
 			v.Fail(config.Metadata.Name)
 		} else {
-			regex := regexp.MustCompile(config.Parameters.ExpectedOutputRegex)
 			if regex.MatchString(result.Stdout) {
 				v.Success(config.Metadata.Name)
 			} else {
@@ -170,16 +226,6 @@ func convertKubeExecToInterfaceMap(typedResults map[string][]KubeExecResult) map
 }
 
 func (k *KubeExec) Cleanup(ctx context.Context, experimentConfig *ExperimentConfig) error {
-	var config KubeExec
-	yamlObj, _ := yaml.Marshal(experimentConfig)
-	err := yaml.Unmarshal(yamlObj, &config)
-	if err != nil {
-		return err
-	}
-
-	if err := removeTempFilesForExperiment(k.Type(), config.Metadata.Name); err != nil {
-		return err
-	}
-
+	// Results are kept in the run ledger, so there is nothing to clean up
 	return nil
 }
@@ -0,0 +1,66 @@
+package experiments
+
+import (
+	"context"
+	"encoding/json"
+	"testing"
+
+	"github.com/operantai/woodpecker/internal/ledger"
+	"github.com/operantai/woodpecker/internal/verifier"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestKubeExecVerify(t *testing.T) {
+	tests := []struct {
+		name             string
+		result           KubeExecResult
+		expect           verifier.Behavior
+		expectedObserved verifier.Behavior
+		expectedVerdict  verifier.Verdict
+	}{
+		{
+			name:             "Output matches",
+			result:           KubeExecResult{Stdout: "root"},
+			expect:           verifier.Allowed,
+			expectedObserved: verifier.Allowed,
+			expectedVerdict:  verifier.Pass,
+		},
+		{
+			name:            "Output mismatch isn't a block",
+			result:          KubeExecResult{Stdout: "nobody"},
+			expect:          verifier.Blocked,
+			expectedVerdict: verifier.Failed,
+		},
+		{
+			name:            "Stderr isn't a block",
+			result:          KubeExecResult{Stderr: "sh: whoami: not found"},
+			expect:          verifier.Blocked,
+			expectedVerdict: verifier.Failed,
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			run, err := ledger.NewStore(t.TempDir()).Create(map[string]string{"exec": "kube-exec"})
+			require.NoError(t, err)
+			result, err := json.Marshal(test.result)
+			require.NoError(t, err)
+			require.NoError(t, run.AddResult("exec", result))
+
+			e := &ExperimentConfig{
+				Metadata: ExperimentMetadata{Name: "exec", Type: "kube-exec", Namespace: "default"},
+				Parameters: &KubeExecParameters{
+					Command:             []string{"whoami"},
+					ExpectedOutputRegex: "^root$",
+				},
+			}
+			outcome, err := (&KubeExec{}).Verify(ledger.NewContext(context.Background(), run), e)
+			require.NoError(t, err)
+
+			outcome.Evaluate(test.expect)
+			assert.Equal(t, test.expectedObserved, outcome.Observed)
+			assert.Equal(t, test.expectedVerdict, outcome.Verdict)
+		})
+	}
+}
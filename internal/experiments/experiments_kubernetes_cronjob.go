@@ -0,0 +1,266 @@
+/*
+Copyright 2023 Operant AI
+*/
+package experiments
+
+import (
+	"context"
+	"fmt"
+	"strings"
+
+	"github.com/operantai/woodpecker/internal/categories"
+	"github.com/operantai/woodpecker/internal/k8s"
+	"github.com/operantai/woodpecker/internal/verifier"
+
+	batchv1 "k8s.io/api/batch/v1"
+	corev1 "k8s.io/api/core/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/utils/pointer"
+)
+
+type KubernetesCronJobExperimentConfig struct {
+	Metadata   ExperimentMetadata `yaml:"metadata"`
+	Parameters KubernetesCronJob  `yaml:"parameters"`
+}
+
+// KubernetesCronJob is an experiment that persists in the cluster with a CronJob running a command on a schedule
+type KubernetesCronJob struct {
+	// Schedule is the cron schedule of the CronJob, e.g. "*/1 * * * *"
+	Schedule string   `yaml:"schedule" validate:"required"`
+	Image    string   `yaml:"image" validate:"required"`
+	Command  []string `yaml:"command"`
+}
+
+// Test names of the kubernetes-cronjob experiment
+const (
+	cronJobAdmittedTest = "cronjob-admitted"
+	jobCompletedTest    = "job-completed"
+)
+
+// Validate checks that the schedule is a cron expression or one of its macros, which the API server parses later on
+func (p *KubernetesCronJob) Validate() error {
+	if !strings.HasPrefix(p.Schedule, "@") && len(strings.Fields(p.Schedule)) != 5 {
+		return fmt.Errorf("invalid schedule %q, must be a cron expression such as \"*/5 * * * *\" or a macro such as @hourly", p.Schedule)
+	}
+	return nil
+}
+
+func (p *KubernetesCronJobExperimentConfig) Type() string {
+	return "kubernetes-cronjob"
+}
+
+func (p *KubernetesCronJobExperimentConfig) Description() string {
+	return "Persist in the cluster with a CronJob that runs a command on a schedule"
+}
+
+func (p *KubernetesCronJobExperimentConfig) Technique() string {
+	return categories.MITRE.Persistence.KubernetesCronJob.Technique
+}
+
+func (p *KubernetesCronJobExperimentConfig) Tactic() string {
+	return categories.MITRE.Persistence.KubernetesCronJob.Tactic
+}
+
+func (p *KubernetesCronJobExperimentConfig) Framework() string {
+	return string(categories.Mitre)
+}
+
+func (p *KubernetesCronJobExperimentConfig) NewParameters() interface{} {
+	return &KubernetesCronJob{}
+}
+
+func (p *KubernetesCronJobExperimentConfig) Severity() verifier.Severity {
+	return verifier.High
+}
+
+func (p *KubernetesCronJobExperimentConfig) Remediation() *verifier.Remediation {
+	return &verifier.Remediation{
+		Summary: "Only let the accounts that deploy workloads create CronJobs, and restrict the images CronJobs may run",
+		Controls: []verifier.Control{
+			{
+				Kind:        verifier.RBAC,
+				Description: "Grant write access to CronJobs only to the accounts that deploy them, and bind this role to them alone",
+				Snippet: `apiVersion: rbac.authorization.k8s.io/v1
+kind: Role
+metadata:
+  name: cronjob-deployer
+  namespace: my-namespace
+rules:
+  - apiGroups: [batch]
+    resources: [cronjobs]
+    verbs: [create, update, patch, delete]`,
+			},
+			{
+				Kind:        verifier.Kyverno,
+				Description: "Refuse CronJobs running images from outside a trusted registry",
+				Snippet: `apiVersion: kyverno.io/v1
+kind: ClusterPolicy
+metadata:
+  name: restrict-cronjob-images
+spec:
+  validationFailureAction: Enforce
+  rules:
+    - name: trusted-registry
+      match:
+        any:
+          - resources:
+              kinds: [CronJob]
+      validate:
+        message: CronJobs must run images from the trusted registry
+        pattern:
+          spec:
+            jobTemplate:
+              spec:
+                template:
+                  spec:
+                    containers:
+                      - image: "registry.example.com/*"`,
+			},
+		},
+	}
+}
+
+// Run creates the CronJob and waits for a Job it spawned to run to completion, which needs a readinessTimeout
+// longer than the schedule of the CronJob
+func (p *KubernetesCronJobExperimentConfig) Run(ctx context.Context, experimentConfig *ExperimentConfig) error {
+	client, err := k8s.NewClient()
+	if err != nil {
+		return err
+	}
+	config := KubernetesCronJobExperimentConfig{Metadata: experimentConfig.Metadata}
+	err = loadParameters(experimentConfig, &config.Parameters)
+	if err != nil {
+		return err
+	}
+	params := config.Parameters
+
+	labels := map[string]string{
+		"experiment": config.Metadata.Name,
+	}
+	cronJob := &batchv1.CronJob{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:   config.Metadata.Name,
+			Labels: labels,
+		},
+		Spec: batchv1.CronJobSpec{
+			Schedule:          params.Schedule,
+			ConcurrencyPolicy: batchv1.ForbidConcurrent,
+			JobTemplate: batchv1.JobTemplateSpec{
+				ObjectMeta: metav1.ObjectMeta{
+					Labels: labels,
+				},
+				Spec: batchv1.JobSpec{
+					BackoffLimit: pointer.Int32(0),
+					Template: corev1.PodTemplateSpec{
+						ObjectMeta: metav1.ObjectMeta{
+							Labels: labels,
+						},
+						Spec: corev1.PodSpec{
+							RestartPolicy: corev1.RestartPolicyNever,
+							Containers: []corev1.Container{
+								{
+									Name:            config.Metadata.Name,
+									Image:           params.Image,
+									ImagePullPolicy: corev1.PullAlways,
+									Command:         params.Command,
+								},
+							},
+						},
+					},
+				},
+			},
+		},
+	}
+
+	clientset := client.Clientset
+	_, err = clientset.BatchV1().CronJobs(config.Metadata.Namespace).Create(ctx, cronJob, metav1.CreateOptions{})
+	if err != nil {
+		return err
+	}
+	return k8s.WaitForCronJob(ctx, clientset, config.Metadata.Namespace, config.Metadata.Name, config.Metadata.ReadinessTimeout)
+}
+
+func (p *KubernetesCronJobExperimentConfig) Verify(ctx context.Context, experimentConfig *ExperimentConfig) (*verifier.LegacyOutcome, error) {
+	client, err := k8s.NewClient()
+	if err != nil {
+		return nil, err
+	}
+	config := KubernetesCronJobExperimentConfig{Metadata: experimentConfig.Metadata}
+	err = loadParameters(experimentConfig, &config.Parameters)
+	if err != nil {
+		return nil, err
+	}
+
+	v := verifier.NewLegacy(
+		config.Metadata.Name,
+		config.Description(),
+		config.Framework(),
+		config.Tactic(),
+		config.Technique(),
+	)
+
+	clientset := client.Clientset
+	_, err = clientset.BatchV1().CronJobs(config.Metadata.Namespace).Get(ctx, config.Metadata.Name, metav1.GetOptions{})
+	switch {
+	case apierrors.IsNotFound(err):
+		v.Fail(cronJobAdmittedTest)
+	case err != nil:
+		return nil, err
+	default:
+		v.Success(cronJobAdmittedTest)
+	}
+
+	// Check if at least one of the Jobs the CronJob spawned ran to completion
+	jobs, err := k8s.CronJobJobs(ctx, clientset, config.Metadata.Namespace, config.Metadata.Name)
+	if err != nil {
+		return nil, err
+	}
+	v.Fail(jobCompletedTest)
+	for _, job := range jobs {
+		if job.Status.Succeeded > 0 {
+			v.Success(jobCompletedTest)
+			v.StoreResultOutputs(jobCompletedTest, job.Name)
+		}
+	}
+
+	return v.GetOutcome(), nil
+}
+
+// Cleanup removes the CronJob along with the Jobs and Pods it spawned
+func (p *KubernetesCronJobExperimentConfig) Cleanup(ctx context.Context, experimentConfig *ExperimentConfig) error {
+	client, err := k8s.NewClient()
+	if err != nil {
+		return err
+	}
+	config := KubernetesCronJobExperimentConfig{Metadata: experimentConfig.Metadata}
+	err = loadParameters(experimentConfig, &config.Parameters)
+	if err != nil {
+		return err
+	}
+
+	clientset := client.Clientset
+	namespace := config.Metadata.Namespace
+	// List the Jobs first, as they're garbage collected along with the CronJob
+	jobs, err := k8s.CronJobJobs(ctx, clientset, namespace, config.Metadata.Name)
+	if err != nil {
+		return err
+	}
+	background := metav1.DeletePropagationBackground
+	deleteOptions := metav1.DeleteOptions{PropagationPolicy: &background}
+
+	// The CronJob doesn't exist if the cluster refused it
+	err = clientset.BatchV1().CronJobs(namespace).Delete(ctx, config.Metadata.Name, deleteOptions)
+	if err != nil && !apierrors.IsNotFound(err) {
+		return err
+	}
+	for _, job := range jobs {
+		err = clientset.BatchV1().Jobs(namespace).Delete(ctx, job.Name, deleteOptions)
+		if err != nil && !apierrors.IsNotFound(err) {
+			return err
+		}
+	}
+	return clientset.CoreV1().Pods(namespace).DeleteCollection(ctx, metav1.DeleteOptions{}, metav1.ListOptions{
+		LabelSelector: "experiment=" + config.Metadata.Name,
+	})
+}
@@ -17,7 +17,6 @@ import (
 	"github.com/operantai/woodpecker/internal/categories"
 	"github.com/operantai/woodpecker/internal/k8s"
 	"github.com/operantai/woodpecker/internal/verifier"
-	"gopkg.in/yaml.v3"
 )
 
 type ListK8sSecretsConfig struct {
@@ -50,14 +49,53 @@ func (p *ListK8sSecretsConfig) Framework() string {
 	return string(categories.Mitre)
 }
 
+func (p *ListK8sSecretsConfig) NewParameters() interface{} {
+	return &K8sSecretsParameters{}
+}
+
+func (p *ListK8sSecretsConfig) Severity() verifier.Severity {
+	return verifier.High
+}
+
+func (p *ListK8sSecretsConfig) Remediation() *verifier.Remediation {
+	return &verifier.Remediation{
+		Summary: "Don't grant workloads get, list or watch on Secrets, and don't mount service account tokens into pods that don't talk to the API server",
+		Controls: []verifier.Control{
+			{
+				Kind:        verifier.RBAC,
+				Description: "Remove secrets from the roles bound to the workload's service account, or limit them to the named secrets it needs",
+				Snippet: `apiVersion: rbac.authorization.k8s.io/v1
+kind: Role
+metadata:
+  name: my-app
+  namespace: my-namespace
+rules:
+  - apiGroups: [""]
+    resources: [secrets]
+    resourceNames: [my-app-credentials]
+    verbs: [get]`,
+			},
+			{
+				Kind:        verifier.Configuration,
+				Description: "Stop mounting the service account token into pods that don't need it",
+				Snippet: `apiVersion: v1
+kind: ServiceAccount
+metadata:
+  name: my-app
+  namespace: my-namespace
+automountServiceAccountToken: false`,
+			},
+		},
+	}
+}
+
 func (p *ListK8sSecretsConfig) Run(ctx context.Context, experimentConfig *ExperimentConfig) error {
 	client, err := k8s.NewClient()
 	if err != nil {
 		return err
 	}
-	var config ListK8sSecretsConfig
-	yamlObj, _ := yaml.Marshal(experimentConfig)
-	err = yaml.Unmarshal(yamlObj, &config)
+	config := ListK8sSecretsConfig{Metadata: experimentConfig.Metadata}
+	err = loadParameters(experimentConfig, &config.Parameters)
 	if err != nil {
 		return err
 	}
@@ -130,7 +168,7 @@ func (p *ListK8sSecretsConfig) Run(ctx context.Context, experimentConfig *Experi
 		return err
 	}
 
-	return nil
+	return executorConfig.WaitForReady(ctx, client.Clientset, config.Metadata.ReadinessTimeout)
 
 }
 
@@ -139,9 +177,8 @@ func (p *ListK8sSecretsConfig) Verify(ctx context.Context, experimentConfig *Exp
 	if err != nil {
 		return nil, err
 	}
-	var config ListK8sSecretsConfig
-	yamlObj, _ := yaml.Marshal(experimentConfig)
-	err = yaml.Unmarshal(yamlObj, &config)
+	config := ListK8sSecretsConfig{Metadata: experimentConfig.Metadata}
+	err = loadParameters(experimentConfig, &config.Parameters)
 	if err != nil {
 		return nil, err
 	}
@@ -194,9 +231,8 @@ func (p *ListK8sSecretsConfig) Cleanup(ctx context.Context, experimentConfig *Ex
 		return err
 	}
 	clientset := client.Clientset
-	var config ListK8sSecretsConfig
-	yamlObj, _ := yaml.Marshal(experimentConfig)
-	err = yaml.Unmarshal(yamlObj, &config)
+	config := ListK8sSecretsConfig{Metadata: experimentConfig.Metadata}
+	err = loadParameters(experimentConfig, &config.Parameters)
 	if err != nil {
 		return err
 	}
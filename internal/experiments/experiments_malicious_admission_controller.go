@@ -0,0 +1,379 @@
+/*
+Copyright 2023 Operant AI
+*/
+package experiments
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/operantai/woodpecker/internal/categories"
+	"github.com/operantai/woodpecker/internal/executor"
+	"github.com/operantai/woodpecker/internal/k8s"
+	"github.com/operantai/woodpecker/internal/verifier"
+
+	admissionregistrationv1 "k8s.io/api/admissionregistration/v1"
+	corev1 "k8s.io/api/core/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/util/wait"
+	"k8s.io/client-go/kubernetes"
+	"k8s.io/utils/pointer"
+)
+
+// webhookPropagationTimeout is how long the probe pod is retried for while the API server picks up the webhook
+const webhookPropagationTimeout = 15 * time.Second
+
+type MaliciousAdmissionControllerExperimentConfig struct {
+	Metadata   ExperimentMetadata           `yaml:"metadata"`
+	Parameters MaliciousAdmissionController `yaml:"parameters"`
+}
+
+// MaliciousAdmissionController is an experiment that registers a mutating webhook, served by the executor, which
+// tampers with the pods created in a throwaway namespace
+type MaliciousAdmissionController struct {
+	// Image is the executor image serving the webhook
+	Image string `yaml:"image" validate:"required"`
+	// TargetNamespace is the throwaway namespace the webhook is scoped to, the experiment creates and deletes it
+	TargetNamespace string `yaml:"targetNamespace" validate:"required"`
+}
+
+// Test names of the malicious-admission-controller experiment
+const (
+	webhookRegisteredTest = "webhook-registered"
+	podMutatedTest        = "pod-mutated"
+)
+
+func (p *MaliciousAdmissionControllerExperimentConfig) Type() string {
+	return "malicious-admission-controller"
+}
+
+func (p *MaliciousAdmissionControllerExperimentConfig) Description() string {
+	return "Register a mutating admission webhook that tampers with the pods created in a namespace"
+}
+
+// Technique and Tactic report Persistence rather than Credential Access, which the technique is also part of, as the
+// webhook only tampers with the pods it admits and doesn't capture the secrets or tokens they carry
+func (p *MaliciousAdmissionControllerExperimentConfig) Technique() string {
+	return categories.MITRE.Persistence.MaliciousAdmissionController.Technique
+}
+
+func (p *MaliciousAdmissionControllerExperimentConfig) Tactic() string {
+	return categories.MITRE.Persistence.MaliciousAdmissionController.Tactic
+}
+
+func (p *MaliciousAdmissionControllerExperimentConfig) Framework() string {
+	return string(categories.Mitre)
+}
+
+func (p *MaliciousAdmissionControllerExperimentConfig) NewParameters() interface{} {
+	return &MaliciousAdmissionController{}
+}
+
+func (p *MaliciousAdmissionControllerExperimentConfig) Severity() verifier.Severity {
+	return verifier.Critical
+}
+
+func (p *MaliciousAdmissionControllerExperimentConfig) Remediation() *verifier.Remediation {
+	return &verifier.Remediation{
+		Summary: "Only let cluster admins register admission webhooks, as a webhook sees and can change every object it's called for",
+		Controls: []verifier.Control{
+			{
+				Kind:        verifier.RBAC,
+				Description: "Grant write access to webhook configurations to a dedicated role bound to cluster admins only, and remove it from any wildcard roles",
+				Snippet: `apiVersion: rbac.authorization.k8s.io/v1
+kind: ClusterRole
+metadata:
+  name: webhook-admin
+rules:
+  - apiGroups: [admissionregistration.k8s.io]
+    resources: [mutatingwebhookconfigurations, validatingwebhookconfigurations]
+    verbs: [create, update, patch, delete]`,
+			},
+			{
+				Kind:        verifier.Kyverno,
+				Description: "Refuse webhook configurations registered by anyone but cluster admins",
+				Snippet: `apiVersion: kyverno.io/v1
+kind: ClusterPolicy
+metadata:
+  name: restrict-webhook-configurations
+spec:
+  validationFailureAction: Enforce
+  background: false
+  rules:
+    - name: cluster-admins-only
+      match:
+        any:
+          - resources:
+              kinds: [MutatingWebhookConfiguration, ValidatingWebhookConfiguration]
+      exclude:
+        any:
+          - clusterRoles: [cluster-admin]
+      validate:
+        message: Only cluster admins may register admission webhooks
+        deny: {}`,
+			},
+		},
+	}
+}
+
+// executorConfig returns the executor serving the webhook, with the TLS Secret holding its certificate
+func (p *MaliciousAdmissionControllerExperimentConfig) executorConfig() *executor.RemoteExecutorConfig {
+	executorConfig := executor.NewExecutorConfig(
+		p.Metadata.Name,
+		p.Metadata.Namespace,
+		p.Parameters.Image,
+		nil,
+		"",
+		executor.WebhookPort,
+	)
+	executorConfig.Parameters.TLSSecretName = p.Metadata.Name + "-tls"
+	return executorConfig
+}
+
+func (p *MaliciousAdmissionControllerExperimentConfig) Run(ctx context.Context, experimentConfig *ExperimentConfig) error {
+	client, err := k8s.NewClient()
+	if err != nil {
+		return err
+	}
+	config := MaliciousAdmissionControllerExperimentConfig{Metadata: experimentConfig.Metadata}
+	err = loadParameters(experimentConfig, &config.Parameters)
+	if err != nil {
+		return err
+	}
+	params := config.Parameters
+	clientset := client.Clientset
+	labels := map[string]string{
+		"experiment": config.Metadata.Name,
+	}
+
+	// The namespace is deleted on cleanup, so refuse to take over one that already exists
+	namespace := &corev1.Namespace{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:   params.TargetNamespace,
+			Labels: labels,
+		},
+	}
+	_, err = clientset.CoreV1().Namespaces().Create(ctx, namespace, metav1.CreateOptions{})
+	if apierrors.IsAlreadyExists(err) {
+		return fmt.Errorf("Namespace %s already exists, the experiment needs a throwaway namespace it can delete", params.TargetNamespace)
+	}
+	if err != nil {
+		return err
+	}
+
+	executorConfig := config.executorConfig()
+	cert, key, err := executor.NewWebhookCertificate(executorConfig.Name, executorConfig.Namespace)
+	if err != nil {
+		return fmt.Errorf("Failed to generate webhook certificate: %w", err)
+	}
+	secret := &corev1.Secret{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:   executorConfig.Parameters.TLSSecretName,
+			Labels: labels,
+		},
+		Type: corev1.SecretTypeTLS,
+		Data: map[string][]byte{
+			corev1.TLSCertKey:       cert,
+			corev1.TLSPrivateKeyKey: key,
+		},
+	}
+	_, err = clientset.CoreV1().Secrets(config.Metadata.Namespace).Create(ctx, secret, metav1.CreateOptions{})
+	if err != nil {
+		return err
+	}
+	err = executorConfig.Deploy(ctx, clientset)
+	if err != nil {
+		return err
+	}
+	err = executorConfig.WaitForReady(ctx, clientset, config.Metadata.ReadinessTimeout)
+	if err != nil {
+		return err
+	}
+
+	// Failing open keeps the namespace usable should the executor go away before the webhook does
+	failurePolicy := admissionregistrationv1.Ignore
+	sideEffects := admissionregistrationv1.SideEffectClassNone
+	webhook := &admissionregistrationv1.MutatingWebhookConfiguration{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:   config.Metadata.Name,
+			Labels: labels,
+		},
+		Webhooks: []admissionregistrationv1.MutatingWebhook{
+			{
+				Name: config.Metadata.Name + ".woodpecker.operant.ai",
+				ClientConfig: admissionregistrationv1.WebhookClientConfig{
+					Service: &admissionregistrationv1.ServiceReference{
+						Namespace: executorConfig.Namespace,
+						Name:      executorConfig.Name,
+						Path:      pointer.String(executor.WebhookPath),
+						Port:      pointer.Int32(executor.WebhookPort),
+					},
+					CABundle: cert,
+				},
+				Rules: []admissionregistrationv1.RuleWithOperations{
+					{
+						Operations: []admissionregistrationv1.OperationType{admissionregistrationv1.Create},
+						Rule: admissionregistrationv1.Rule{
+							APIGroups:   []string{""},
+							APIVersions: []string{"v1"},
+							Resources:   []string{"pods"},
+						},
+					},
+				},
+				NamespaceSelector: &metav1.LabelSelector{
+					MatchLabels: map[string]string{
+						corev1.LabelMetadataName: params.TargetNamespace,
+					},
+				},
+				FailurePolicy:           &failurePolicy,
+				SideEffects:             &sideEffects,
+				AdmissionReviewVersions: []string{"v1"},
+				TimeoutSeconds:          pointer.Int32(5),
+			},
+		},
+	}
+	_, err = clientset.AdmissionregistrationV1().MutatingWebhookConfigurations().Create(ctx, webhook, metav1.CreateOptions{})
+	return err
+}
+
+func (p *MaliciousAdmissionControllerExperimentConfig) Verify(ctx context.Context, experimentConfig *ExperimentConfig) (*verifier.LegacyOutcome, error) {
+	client, err := k8s.NewClient()
+	if err != nil {
+		return nil, err
+	}
+	config := MaliciousAdmissionControllerExperimentConfig{Metadata: experimentConfig.Metadata}
+	err = loadParameters(experimentConfig, &config.Parameters)
+	if err != nil {
+		return nil, err
+	}
+
+	v := verifier.NewLegacy(
+		config.Metadata.Name,
+		config.Description(),
+		config.Framework(),
+		config.Tactic(),
+		config.Technique(),
+	)
+
+	clientset := client.Clientset
+	webhook, err := clientset.AdmissionregistrationV1().MutatingWebhookConfigurations().Get(ctx, config.Metadata.Name, metav1.GetOptions{})
+	switch {
+	case apierrors.IsNotFound(err):
+		v.Fail(webhookRegisteredTest)
+		v.Fail(podMutatedTest)
+		return v.GetOutcome(), nil
+	case err != nil:
+		return nil, err
+	default:
+		v.Success(webhookRegisteredTest)
+	}
+
+	// An expired certificate makes the API server fail to call the webhook, which its Ignore failure policy would
+	// otherwise hide as a pod the cluster didn't let the webhook mutate
+	for _, hook := range webhook.Webhooks {
+		if err := executor.CheckWebhookCertificate(hook.ClientConfig.CABundle); err != nil {
+			return nil, fmt.Errorf("Webhook %s can't be called: %w", hook.Name, err)
+		}
+	}
+
+	mutated, err := probeWebhook(ctx, clientset, config.Metadata.Name, config.Parameters.TargetNamespace)
+	if err != nil {
+		return nil, err
+	}
+	if mutated {
+		v.Success(podMutatedTest)
+		v.StoreResultOutputs(podMutatedTest, fmt.Sprintf("annotation %s added to the probe pod", executor.MutationAnnotation))
+	} else {
+		v.Fail(podMutatedTest)
+	}
+	return v.GetOutcome(), nil
+}
+
+// probeWebhook creates a probe pod in the target namespace as a dry run, which goes through admission without
+// being persisted, and reports whether the webhook mutated it. The probe is retried for a while, as the API server
+// picks up new webhook configurations asynchronously.
+func probeWebhook(ctx context.Context, clientset kubernetes.Interface, name, namespace string) (bool, error) {
+	probe := &corev1.Pod{
+		ObjectMeta: metav1.ObjectMeta{
+			Name: name + "-probe",
+			Labels: map[string]string{
+				"experiment": name,
+			},
+		},
+		Spec: corev1.PodSpec{
+			Containers: []corev1.Container{
+				{
+					Name:  "probe",
+					Image: "alpine:latest",
+				},
+			},
+		},
+	}
+
+	mutated := false
+	err := wait.PollUntilContextTimeout(ctx, time.Second, webhookPropagationTimeout, true, func(ctx context.Context) (bool, error) {
+		pod, err := clientset.CoreV1().Pods(namespace).Create(ctx, probe, metav1.CreateOptions{DryRun: []string{metav1.DryRunAll}})
+		if err != nil {
+			if k8s.IsBlocked(err) {
+				return true, nil
+			}
+			return false, err
+		}
+		mutated = pod.Annotations[executor.MutationAnnotation] == "true"
+		return mutated, nil
+	})
+	if err != nil && !wait.Interrupted(err) {
+		return false, err
+	}
+	return mutated, nil
+}
+
+// Cleanup removes the webhook configuration before anything else, so that the cluster is never left with a
+// webhook whose server is gone
+func (p *MaliciousAdmissionControllerExperimentConfig) Cleanup(ctx context.Context, experimentConfig *ExperimentConfig) error {
+	client, err := k8s.NewClient()
+	if err != nil {
+		return err
+	}
+	config := MaliciousAdmissionControllerExperimentConfig{Metadata: experimentConfig.Metadata}
+	err = loadParameters(experimentConfig, &config.Parameters)
+	if err != nil {
+		return err
+	}
+
+	clientset := client.Clientset
+	err = clientset.AdmissionregistrationV1().MutatingWebhookConfigurations().Delete(ctx, config.Metadata.Name, metav1.DeleteOptions{})
+	if err != nil && !apierrors.IsNotFound(err) {
+		return fmt.Errorf("Failed to delete webhook configuration %s, leaving its server in place: %w", config.Metadata.Name, err)
+	}
+
+	executorConfig := config.executorConfig()
+	var errs []error
+	for _, deleteFn := range []func() error{
+		func() error {
+			return clientset.AppsV1().Deployments(executorConfig.Namespace).Delete(ctx, executorConfig.Name, metav1.DeleteOptions{})
+		},
+		func() error {
+			return clientset.CoreV1().Services(executorConfig.Namespace).Delete(ctx, executorConfig.Name, metav1.DeleteOptions{})
+		},
+		func() error {
+			return clientset.CoreV1().Secrets(executorConfig.Namespace).Delete(ctx, executorConfig.Parameters.TLSSecretName, metav1.DeleteOptions{})
+		},
+		func() error {
+			// Only delete the target namespace if the experiment created it
+			namespace, err := clientset.CoreV1().Namespaces().Get(ctx, config.Parameters.TargetNamespace, metav1.GetOptions{})
+			if err != nil || namespace.Labels["experiment"] != config.Metadata.Name {
+				return err
+			}
+			return clientset.CoreV1().Namespaces().Delete(ctx, namespace.Name, metav1.DeleteOptions{})
+		},
+	} {
+		if err := deleteFn(); err != nil && !apierrors.IsNotFound(err) {
+			errs = append(errs, err)
+		}
+	}
+	return errors.Join(errs...)
+}
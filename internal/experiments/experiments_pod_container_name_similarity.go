@@ -0,0 +1,371 @@
+/*
+Copyright 2023 Operant AI
+*/
+package experiments
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"sort"
+	"strings"
+	"text/template"
+
+	"github.com/operantai/woodpecker/internal/categories"
+	"github.com/operantai/woodpecker/internal/k8s"
+	"github.com/operantai/woodpecker/internal/verifier"
+
+	corev1 "k8s.io/api/core/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/util/rand"
+	"k8s.io/apimachinery/pkg/util/validation"
+)
+
+type PodContainerNameSimilarityExperimentConfig struct {
+	Metadata   ExperimentMetadata         `yaml:"metadata"`
+	Parameters PodContainerNameSimilarity `yaml:"parameters"`
+}
+
+// PodContainerNameSimilarity is an experiment that hides pods among system components by imitating their names
+type PodContainerNameSimilarity struct {
+	// Templates are the names of the imitations, where {{ .Suffix }} is replaced with random characters like those
+	// the names of pods spawned by controllers end in, e.g. "kube-proxy-{{ .Suffix }}"
+	Templates []string `yaml:"templates" validate:"required"`
+	// Image runs in the imitations, defaults to alpine:latest
+	Image string `yaml:"image"`
+}
+
+// PodContainerNameSimilarityResult is an imitation the experiment tried to create
+type PodContainerNameSimilarityResult struct {
+	Pod string `json:"pod"`
+	// Component is the system component the pod imitates, which is also the name of its container
+	Component string `json:"component"`
+	// Error is why the imitation was refused
+	Error string `json:"error,omitempty"`
+}
+
+// nameTemplateData is what the templates of the names of imitations are rendered with
+type nameTemplateData struct {
+	Suffix string
+}
+
+// Validate checks that the templates render to valid pod and container names
+func (p *PodContainerNameSimilarity) Validate() error {
+	var errs []error
+	for _, text := range p.Templates {
+		name, component, err := renderImitationName(text, "x7f2k")
+		if err != nil {
+			errs = append(errs, err)
+			continue
+		}
+		for _, msg := range validation.IsDNS1123Subdomain(name) {
+			errs = append(errs, fmt.Errorf("invalid template %q, %s is not a valid pod name: %s", text, name, msg))
+		}
+		for _, msg := range validation.IsDNS1123Label(component) {
+			errs = append(errs, fmt.Errorf("invalid template %q, %s is not a valid container name: %s", text, component, msg))
+		}
+	}
+	return errors.Join(errs...)
+}
+
+func (p *PodContainerNameSimilarityExperimentConfig) Type() string {
+	return "pod-container-name-similarity"
+}
+
+func (p *PodContainerNameSimilarityExperimentConfig) Description() string {
+	return "Hide pods among system components by giving them names similar to theirs"
+}
+
+func (p *PodContainerNameSimilarityExperimentConfig) Technique() string {
+	return categories.MITRE.DefenseEvasion.PodContainerNameSimilarity.Technique
+}
+
+func (p *PodContainerNameSimilarityExperimentConfig) Tactic() string {
+	return categories.MITRE.DefenseEvasion.PodContainerNameSimilarity.Tactic
+}
+
+func (p *PodContainerNameSimilarityExperimentConfig) Framework() string {
+	return string(categories.Mitre)
+}
+
+func (p *PodContainerNameSimilarityExperimentConfig) NewParameters() interface{} {
+	return &PodContainerNameSimilarity{}
+}
+
+func (p *PodContainerNameSimilarityExperimentConfig) Severity() verifier.Severity {
+	return verifier.Medium
+}
+
+func (p *PodContainerNameSimilarityExperimentConfig) Remediation() *verifier.Remediation {
+	return &verifier.Remediation{
+		Summary: "Only let the accounts that manage system components create pods in their namespaces, and refuse pods there that no controller owns",
+		Controls: []verifier.Control{
+			{
+				Kind:        verifier.RBAC,
+				Description: "Don't bind roles that can create pods in kube-system to workloads or developers",
+				Snippet: `apiVersion: rbac.authorization.k8s.io/v1
+kind: Role
+metadata:
+  name: kube-system-viewer
+  namespace: kube-system
+rules:
+  - apiGroups: [""]
+    resources: [pods]
+    verbs: [get, list, watch]`,
+			},
+			{
+				Kind:        verifier.Kyverno,
+				Description: "Refuse pods in kube-system that aren't created by a controller",
+				Snippet: `apiVersion: kyverno.io/v1
+kind: ClusterPolicy
+metadata:
+  name: require-owner-in-kube-system
+spec:
+  validationFailureAction: Enforce
+  rules:
+    - name: require-owner
+      match:
+        any:
+          - resources:
+              kinds: [Pod]
+              namespaces: [kube-system]
+      exclude:
+        any:
+          - subjects:
+              - kind: Group
+                name: system:nodes
+      validate:
+        message: Pods in kube-system must be created by a controller
+        deny:
+          conditions:
+            any:
+              - key: "{{ request.object.metadata.ownerReferences || '' | length(@) }}"
+                operator: Equals
+                value: 0`,
+			},
+		},
+	}
+}
+
+func (p *PodContainerNameSimilarity) image() string {
+	if p.Image == "" {
+		return "alpine:latest"
+	}
+	return p.Image
+}
+
+// renderImitationName renders the template of the name of an imitation with a suffix, and returns it along with
+// the name of the component it imitates, which is the name without any suffix
+func renderImitationName(text, suffix string) (string, string, error) {
+	tmpl, err := template.New("name").Option("missingkey=error").Parse(text)
+	if err != nil {
+		return "", "", fmt.Errorf("invalid template %q: %w", text, err)
+	}
+	render := func(suffix string) (string, error) {
+		var name strings.Builder
+		if err := tmpl.Execute(&name, nameTemplateData{Suffix: suffix}); err != nil {
+			return "", fmt.Errorf("invalid template %q: %w", text, err)
+		}
+		return name.String(), nil
+	}
+	name, err := render(suffix)
+	if err != nil {
+		return "", "", err
+	}
+	component, err := render("")
+	if err != nil {
+		return "", "", err
+	}
+	return name, strings.Trim(component, "-"), nil
+}
+
+// Run creates a bare pod for each template. Refused imitations are recorded, as every template is a test of its own.
+// The imitations only carry the experiment label, as copying the labels of a system component could get them
+// adopted by its controller, which would then delete its genuine pods to scale back down.
+func (p *PodContainerNameSimilarityExperimentConfig) Run(ctx context.Context, experimentConfig *ExperimentConfig) error {
+	client, err := k8s.NewClient()
+	if err != nil {
+		return err
+	}
+	config := PodContainerNameSimilarityExperimentConfig{Metadata: experimentConfig.Metadata}
+	err = loadParameters(experimentConfig, &config.Parameters)
+	if err != nil {
+		return err
+	}
+	params := config.Parameters
+
+	for _, text := range params.Templates {
+		name, component, err := renderImitationName(text, rand.String(5))
+		if err != nil {
+			return err
+		}
+		pod := &corev1.Pod{
+			ObjectMeta: metav1.ObjectMeta{
+				Name: name,
+				Labels: map[string]string{
+					"experiment": config.Metadata.Name,
+				},
+			},
+			Spec: corev1.PodSpec{
+				Containers: []corev1.Container{
+					{
+						Name:            component,
+						Image:           params.image(),
+						ImagePullPolicy: corev1.PullAlways,
+						Command: []string{
+							"sh",
+							"-c",
+							"while true; do sleep 30; done",
+						},
+					},
+				},
+			},
+		}
+
+		result := PodContainerNameSimilarityResult{Pod: name, Component: component}
+		_, err = client.Clientset.CoreV1().Pods(config.Metadata.Namespace).Create(ctx, pod, metav1.CreateOptions{})
+		if err != nil {
+			if !k8s.IsBlocked(err) {
+				return err
+			}
+			result.Error = err.Error()
+		}
+
+		resultJSON, err := json.Marshal(&result)
+		if err != nil {
+			return fmt.Errorf("Failed to marshal experiment results: %w", err)
+		}
+		if err := storeResult(ctx, config.Metadata.Name, resultJSON); err != nil {
+			return fmt.Errorf("Failed to write experiment results: %w", err)
+		}
+	}
+	return nil
+}
+
+// Verify checks for each imitation whether it was admitted. What tells it apart from the genuine pods of the
+// component it imitates, by their labels and owners, is reported as an output rather than a test: the imitations
+// carry neither, so that would fail whenever genuine pods exist, whatever the policies of the cluster.
+func (p *PodContainerNameSimilarityExperimentConfig) Verify(ctx context.Context, experimentConfig *ExperimentConfig) (*verifier.LegacyOutcome, error) {
+	client, err := k8s.NewClient()
+	if err != nil {
+		return nil, err
+	}
+	config := PodContainerNameSimilarityExperimentConfig{Metadata: experimentConfig.Metadata}
+	err = loadParameters(experimentConfig, &config.Parameters)
+	if err != nil {
+		return nil, err
+	}
+
+	v := verifier.NewLegacy(
+		config.Metadata.Name,
+		config.Description(),
+		config.Framework(),
+		config.Tactic(),
+		config.Technique(),
+	)
+
+	rawResults, err := getResults(ctx, config.Metadata.Name)
+	if err != nil {
+		return nil, err
+	}
+	pods, err := client.Clientset.CoreV1().Pods(config.Metadata.Namespace).List(ctx, metav1.ListOptions{})
+	if err != nil {
+		return nil, err
+	}
+
+	// Each imitation is a test, which succeeds if it was admitted
+	for _, rawResult := range rawResults {
+		var result PodContainerNameSimilarityResult
+		if err := json.Unmarshal(rawResult, &result); err != nil {
+			return nil, fmt.Errorf("Could not parse experiment result: %w", err)
+		}
+
+		imitation, err := client.Clientset.CoreV1().Pods(config.Metadata.Namespace).Get(ctx, result.Pod, metav1.GetOptions{})
+		if apierrors.IsNotFound(err) {
+			// Only imitations whose creation was refused are blocked, Run records no error otherwise
+			if result.Error != "" {
+				v.Blocked(result.Pod)
+				v.StoreResultOutputs(result.Pod, result.Error)
+			} else {
+				v.Fail(result.Pod)
+			}
+			continue
+		}
+		if err != nil {
+			return nil, err
+		}
+		v.Success(result.Pod)
+
+		genuine := genuinePods(pods.Items, config.Metadata.Name, result.Component)
+		switch differences := imitationDifferences(imitation, genuine); {
+		case len(genuine) == 0:
+			v.StoreResultOutputs(result.Pod, fmt.Sprintf("no genuine %s pods to compare with", result.Component))
+		case len(differences) == 0:
+			v.StoreResultOutputs(result.Pod, fmt.Sprintf("indistinguishable from genuine %s pods by labels and owners", result.Component))
+		default:
+			v.StoreResultOutputs(result.Pod, map[string][]string{"distinguishableBy": differences})
+		}
+	}
+	return v.GetOutcome(), nil
+}
+
+// genuinePods returns the pods of a component, which are named after it, leaving out the imitations of the experiment
+func genuinePods(pods []corev1.Pod, experiment, component string) []corev1.Pod {
+	var genuine []corev1.Pod
+	for _, pod := range pods {
+		if pod.Labels["experiment"] != experiment && strings.HasPrefix(pod.Name, component+"-") {
+			genuine = append(genuine, pod)
+		}
+	}
+	return genuine
+}
+
+// controllerLabels are set on pods by their controllers and differ between pods of the same component
+var controllerLabels = map[string]bool{
+	"pod-template-hash":        true,
+	"controller-revision-hash": true,
+	"pod-template-generation":  true,
+}
+
+// imitationDifferences returns what tells an imitation apart from the genuine pod it resembles the most, by their
+// controllers and labels
+func imitationDifferences(imitation *corev1.Pod, genuine []corev1.Pod) []string {
+	var closest []string
+	for i, pod := range genuine {
+		var differences []string
+		owner, imitationOwner := metav1.GetControllerOf(&pod), metav1.GetControllerOf(imitation)
+		switch {
+		case owner == nil && imitationOwner != nil:
+			differences = append(differences, "genuine pod has no controller")
+		case owner != nil && (imitationOwner == nil || owner.Kind != imitationOwner.Kind || owner.Name != imitationOwner.Name):
+			differences = append(differences, fmt.Sprintf("genuine pod is controlled by %s %s", owner.Kind, owner.Name))
+		}
+		for key, value := range pod.Labels {
+			if !controllerLabels[key] && imitation.Labels[key] != value {
+				differences = append(differences, fmt.Sprintf("genuine pod is labelled %s=%s", key, value))
+			}
+		}
+		sort.Strings(differences)
+		if i == 0 || len(differences) < len(closest) {
+			closest = differences
+		}
+	}
+	return closest
+}
+
+func (p *PodContainerNameSimilarityExperimentConfig) Cleanup(ctx context.Context, experimentConfig *ExperimentConfig) error {
+	client, err := k8s.NewClient()
+	if err != nil {
+		return err
+	}
+	config := PodContainerNameSimilarityExperimentConfig{Metadata: experimentConfig.Metadata}
+	err = loadParameters(experimentConfig, &config.Parameters)
+	if err != nil {
+		return err
+	}
+	return client.Clientset.CoreV1().Pods(config.Metadata.Namespace).DeleteCollection(ctx, metav1.DeleteOptions{}, metav1.ListOptions{
+		LabelSelector: "experiment=" + config.Metadata.Name,
+	})
+}
@@ -0,0 +1,110 @@
+package experiments
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+func TestRenderImitationName(t *testing.T) {
+	tests := []struct {
+		name            string
+		template        string
+		expectName      string
+		expectComponent string
+		expectErr       bool
+	}{
+		{
+			name:            "Suffix",
+			template:        "kube-proxy-{{ .Suffix }}",
+			expectName:      "kube-proxy-x7f2k",
+			expectComponent: "kube-proxy",
+		},
+		{
+			name:            "No suffix",
+			template:        "etcd-control-plane",
+			expectName:      "etcd-control-plane",
+			expectComponent: "etcd-control-plane",
+		},
+		{
+			name:      "Unknown field",
+			template:  "coredns-{{ .Hash }}",
+			expectErr: true,
+		},
+		{
+			name:      "Invalid template",
+			template:  "coredns-{{ .Suffix",
+			expectErr: true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			name, component, err := renderImitationName(tt.template, "x7f2k")
+			if tt.expectErr {
+				assert.Error(t, err)
+				return
+			}
+			assert.NoError(t, err)
+			assert.Equal(t, tt.expectName, name)
+			assert.Equal(t, tt.expectComponent, component)
+		})
+	}
+}
+
+func TestImitationDifferences(t *testing.T) {
+	controller := true
+	kubeProxy := corev1.Pod{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:   "kube-proxy-abcde",
+			Labels: map[string]string{"k8s-app": "kube-proxy", "controller-revision-hash": "123"},
+			OwnerReferences: []metav1.OwnerReference{
+				{APIVersion: "apps/v1", Kind: "DaemonSet", Name: "kube-proxy", Controller: &controller},
+			},
+		},
+	}
+	staticPod := corev1.Pod{
+		ObjectMeta: metav1.ObjectMeta{Name: "etcd-control-plane"},
+	}
+
+	tests := []struct {
+		name              string
+		imitation         corev1.Pod
+		genuine           []corev1.Pod
+		expectDifferences []string
+	}{
+		{
+			name:      "Bare pod imitating a DaemonSet",
+			imitation: corev1.Pod{ObjectMeta: metav1.ObjectMeta{Name: "kube-proxy-x7f2k", Labels: map[string]string{"experiment": "test"}}},
+			genuine:   []corev1.Pod{kubeProxy},
+			expectDifferences: []string{
+				"genuine pod is controlled by DaemonSet kube-proxy",
+				"genuine pod is labelled k8s-app=kube-proxy",
+			},
+		},
+		{
+			name:      "Bare pod imitating a static pod",
+			imitation: corev1.Pod{ObjectMeta: metav1.ObjectMeta{Name: "etcd-control-plane-x7f2k", Labels: map[string]string{"experiment": "test"}}},
+			genuine:   []corev1.Pod{kubeProxy, staticPod},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			assert.Equal(t, tt.expectDifferences, imitationDifferences(&tt.imitation, tt.genuine))
+		})
+	}
+}
+
+func TestGenuinePods(t *testing.T) {
+	pods := []corev1.Pod{
+		{ObjectMeta: metav1.ObjectMeta{Name: "coredns-5d78c9869d-abcde"}},
+		{ObjectMeta: metav1.ObjectMeta{Name: "coredns-x7f2k", Labels: map[string]string{"experiment": "test"}}},
+		{ObjectMeta: metav1.ObjectMeta{Name: "corednsx"}},
+	}
+	genuine := genuinePods(pods, "test", "coredns")
+	assert.Len(t, genuine, 1)
+	assert.Equal(t, "coredns-5d78c9869d-abcde", genuine[0].Name)
+}
@@ -6,8 +6,6 @@ package experiments
 import (
 	"context"
 
-	"gopkg.in/yaml.v3"
-
 	"github.com/operantai/woodpecker/internal/categories"
 	"github.com/operantai/woodpecker/internal/k8s"
 	"github.com/operantai/woodpecker/internal/verifier"
@@ -60,14 +58,65 @@ func (p *PrivilegedContainerExperimentConfig) Framework() string {
 	return string(categories.Mitre)
 }
 
+func (p *PrivilegedContainerExperimentConfig) NewParameters() interface{} {
+	return &PrivilegedContainer{}
+}
+
+func (p *PrivilegedContainerExperimentConfig) Severity() verifier.Severity {
+	return verifier.Critical
+}
+
+func (p *PrivilegedContainerExperimentConfig) Remediation() *verifier.Remediation {
+	return &verifier.Remediation{
+		Summary: "Refuse privileged containers and access to the host's namespaces with the baseline Pod Security Standard or an admission policy",
+		Controls: []verifier.Control{
+			{
+				Kind:        verifier.PodSecurityAdmission,
+				Description: "Enforce the baseline Pod Security Standard, or restricted to also refuse running as root, on the namespace",
+				Snippet: `apiVersion: v1
+kind: Namespace
+metadata:
+  name: my-namespace
+  labels:
+    pod-security.kubernetes.io/enforce: baseline
+    pod-security.kubernetes.io/warn: restricted`,
+			},
+			{
+				Kind:        verifier.Kyverno,
+				Description: "Refuse privileged containers and pods sharing the host's PID or network namespace",
+				Snippet: `apiVersion: kyverno.io/v1
+kind: ClusterPolicy
+metadata:
+  name: disallow-privileged
+spec:
+  validationFailureAction: Enforce
+  rules:
+    - name: privileged-containers
+      match:
+        any:
+          - resources:
+              kinds: [Pod]
+      validate:
+        message: Privileged containers and host namespaces are not allowed
+        pattern:
+          spec:
+            =(hostPID): false
+            =(hostNetwork): false
+            containers:
+              - =(securityContext):
+                  =(privileged): false`,
+			},
+		},
+	}
+}
+
 func (p *PrivilegedContainerExperimentConfig) Run(ctx context.Context, experimentConfig *ExperimentConfig) error {
 	client, err := k8s.NewClient()
 	if err != nil {
 		return err
 	}
-	var config PrivilegedContainerExperimentConfig
-	yamlObj, _ := yaml.Marshal(experimentConfig)
-	err = yaml.Unmarshal(yamlObj, &config)
+	config := PrivilegedContainerExperimentConfig{Metadata: experimentConfig.Metadata}
+	err = loadParameters(experimentConfig, &config.Parameters)
 	if err != nil {
 		return err
 	}
@@ -135,7 +184,10 @@ func (p *PrivilegedContainerExperimentConfig) Run(ctx context.Context, experimen
 	deployment.Spec.Template.Spec.Containers[0] = container
 
 	_, err = clientset.AppsV1().Deployments(config.Metadata.Namespace).Create(ctx, deployment, metav1.CreateOptions{})
-	return err
+	if err != nil {
+		return err
+	}
+	return k8s.WaitForDeployment(ctx, clientset, config.Metadata.Namespace, config.Metadata.Name, config.Metadata.ReadinessTimeout)
 }
 
 func (p *PrivilegedContainerExperimentConfig) Verify(ctx context.Context, experimentConfig *ExperimentConfig) (*verifier.LegacyOutcome, error) {
@@ -143,9 +195,8 @@ func (p *PrivilegedContainerExperimentConfig) Verify(ctx context.Context, experi
 	if err != nil {
 		return nil, err
 	}
-	var config PrivilegedContainerExperimentConfig
-	yamlObj, _ := yaml.Marshal(experimentConfig)
-	err = yaml.Unmarshal(yamlObj, &config)
+	config := PrivilegedContainerExperimentConfig{Metadata: experimentConfig.Metadata}
+	err = loadParameters(experimentConfig, &config.Parameters)
 	if err != nil {
 		return nil, err
 	}
@@ -206,6 +257,18 @@ func (p *PrivilegedContainerExperimentConfig) Verify(ctx context.Context, experi
 		}
 	}
 
+	// An admitted deployment counts as detected if a policy engine auditing the cluster flagged it
+	if v.GetOutcome().Result["Deployed"] == verifier.Success {
+		detections, err := deploymentDetections(ctx, client, deployment)
+		if err != nil {
+			return nil, err
+		}
+		if len(detections) > 0 {
+			v.Detected("Deployed")
+			v.StoreResultOutputs("Deployed", detections)
+		}
+	}
+
 	if len(params.Verifier.Command) > 0 {
 		v.Success("Command")
 		pods, err := client.GetDeploymentsPods(ctx, config.Metadata.Namespace, deployment)
@@ -214,7 +277,10 @@ func (p *PrivilegedContainerExperimentConfig) Verify(ctx context.Context, experi
 		}
 		for _, pod := range pods {
 			_, _, err := client.ExecuteRemoteCommand(ctx, config.Metadata.Namespace, pod.Name, container.Name, config.Parameters.Verifier.Command)
-			if err != nil {
+			switch {
+			case k8s.IsBlocked(err):
+				v.Blocked("Command")
+			case err != nil:
 				v.Fail("Command")
 			}
 		}
@@ -223,14 +289,34 @@ func (p *PrivilegedContainerExperimentConfig) Verify(ctx context.Context, experi
 	return v.GetOutcome(), nil
 }
 
+// deploymentDetections returns the policy violations recorded for a deployment and its pods
+func deploymentDetections(ctx context.Context, client *k8s.Client, deployment *appsv1.Deployment) ([]string, error) {
+	detections, err := k8s.Detections(ctx, client.Clientset, deployment.Namespace, "Deployment", deployment.Name)
+	if err != nil {
+		return nil, err
+	}
+	pods, err := client.GetDeploymentsPods(ctx, deployment.Namespace, deployment)
+	if err != nil {
+		return nil, err
+	}
+	names := make([]string, 0, len(pods))
+	for _, pod := range pods {
+		names = append(names, pod.Name)
+	}
+	podDetections, err := k8s.Detections(ctx, client.Clientset, deployment.Namespace, "Pod", names...)
+	if err != nil {
+		return nil, err
+	}
+	return append(detections, podDetections...), nil
+}
+
 func (p *PrivilegedContainerExperimentConfig) Cleanup(ctx context.Context, experimentConfig *ExperimentConfig) error {
 	client, err := k8s.NewClient()
 	if err != nil {
 		return err
 	}
-	var config PrivilegedContainerExperimentConfig
-	yamlObj, _ := yaml.Marshal(experimentConfig)
-	err = yaml.Unmarshal(yamlObj, &config)
+	config := PrivilegedContainerExperimentConfig{Metadata: experimentConfig.Metadata}
+	err = loadParameters(experimentConfig, &config.Parameters)
 	if err != nil {
 		return err
 	}
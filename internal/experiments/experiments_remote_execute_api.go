@@ -11,7 +11,6 @@ import (
 	"github.com/operantai/woodpecker/internal/executor"
 	"github.com/operantai/woodpecker/internal/k8s"
 	"github.com/operantai/woodpecker/internal/verifier"
-	"gopkg.in/yaml.v3"
 )
 
 // RemoteExecuteAPI is an experiment that uses the remote executor to check a remote output
@@ -49,14 +48,72 @@ func (p *RemoteExecuteAPIExperimentConfig) Framework() string {
 	return string(categories.Mitre)
 }
 
+func (p *RemoteExecuteAPIExperimentConfig) NewParameters() interface{} {
+	return &executor.RemoteExecuteAPI{}
+}
+
+func (p *RemoteExecuteAPIExperimentConfig) Severity() verifier.Severity {
+	return verifier.Medium
+}
+
+func (p *RemoteExecuteAPIExperimentConfig) Remediation() *verifier.Remediation {
+	return &verifier.Remediation{
+		Summary: "Deny egress by default and only allow workloads to reach the destinations they need",
+		Controls: []verifier.Control{
+			{
+				Kind:        verifier.NetworkPolicy,
+				Description: "Deny all egress from the namespace except DNS, then allow the destinations each workload needs",
+				Snippet: `apiVersion: networking.k8s.io/v1
+kind: NetworkPolicy
+metadata:
+  name: default-deny-egress
+  namespace: my-namespace
+spec:
+  podSelector: {}
+  policyTypes: [Egress]
+  egress:
+    - to:
+        - namespaceSelector: {}
+          podSelector:
+            matchLabels:
+              k8s-app: kube-dns
+      ports:
+        - protocol: UDP
+          port: 53`,
+			},
+			{
+				Kind:        verifier.Kyverno,
+				Description: "Only allow images from trusted registries",
+				Snippet: `apiVersion: kyverno.io/v1
+kind: ClusterPolicy
+metadata:
+  name: restrict-image-registries
+spec:
+  validationFailureAction: Enforce
+  rules:
+    - name: trusted-registries
+      match:
+        any:
+          - resources:
+              kinds: [Pod]
+      validate:
+        message: Images must come from a trusted registry
+        pattern:
+          spec:
+            containers:
+              - image: "registry.example.com/*"`,
+			},
+		},
+	}
+}
+
 func (p *RemoteExecuteAPIExperimentConfig) Run(ctx context.Context, experimentConfig *ExperimentConfig) error {
 	client, err := k8s.NewClient()
 	if err != nil {
 		return err
 	}
-	var config RemoteExecuteAPIExperimentConfig
-	yamlObj, _ := yaml.Marshal(experimentConfig)
-	err = yaml.Unmarshal(yamlObj, &config)
+	config := RemoteExecuteAPIExperimentConfig{Metadata: experimentConfig.Metadata}
+	err = loadParameters(experimentConfig, &config.Parameters)
 	if err != nil {
 		return err
 	}
@@ -75,7 +132,7 @@ func (p *RemoteExecuteAPIExperimentConfig) Run(ctx context.Context, experimentCo
 		return err
 	}
 
-	return nil
+	return executorConfig.WaitForReady(ctx, client.Clientset, config.Metadata.ReadinessTimeout)
 }
 
 func (p *RemoteExecuteAPIExperimentConfig) Verify(ctx context.Context, experimentConfig *ExperimentConfig) (*verifier.LegacyOutcome, error) {
@@ -83,9 +140,8 @@ func (p *RemoteExecuteAPIExperimentConfig) Verify(ctx context.Context, experimen
 	if err != nil {
 		return nil, err
 	}
-	var config RemoteExecuteAPIExperimentConfig
-	yamlObj, _ := yaml.Marshal(experimentConfig)
-	err = yaml.Unmarshal(yamlObj, &config)
+	config := RemoteExecuteAPIExperimentConfig{Metadata: experimentConfig.Metadata}
+	err = loadParameters(experimentConfig, &config.Parameters)
 	if err != nil {
 		return nil, err
 	}
@@ -153,9 +209,8 @@ func (p *RemoteExecuteAPIExperimentConfig) Cleanup(ctx context.Context, experime
 		return err
 	}
 	clientset := client.Clientset
-	var config RemoteExecuteAPIExperimentConfig
-	yamlObj, _ := yaml.Marshal(experimentConfig)
-	err = yaml.Unmarshal(yamlObj, &config)
+	config := RemoteExecuteAPIExperimentConfig{Metadata: experimentConfig.Metadata}
+	err = loadParameters(experimentConfig, &config.Parameters)
 	if err != nil {
 		return err
 	}
@@ -0,0 +1,333 @@
+package experiments
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/operantai/woodpecker/internal/ledger"
+	"github.com/operantai/woodpecker/internal/output"
+	"github.com/operantai/woodpecker/internal/suppressions"
+	"github.com/operantai/woodpecker/internal/verifier"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+)
+
+func newTestRunner(dependencies map[string][]string, opts RunnerOptions) *Runner {
+	configs := make(map[string]*ExperimentConfig)
+	for name, deps := range dependencies {
+		configs[name] = &ExperimentConfig{Metadata: ExperimentMetadata{Name: name, DependsOn: deps}}
+	}
+	if opts.Parallelism < 1 {
+		opts.Parallelism = 1
+	}
+	return &Runner{
+		ctx:               context.Background(),
+		experimentsConfig: configs,
+		parallelism:       opts.Parallelism,
+		failFast:          opts.FailFast,
+	}
+}
+
+func independentExperiments(count int) map[string][]string {
+	dependencies := make(map[string][]string)
+	for i := 0; i < count; i++ {
+		dependencies[fmt.Sprintf("experiment-%d", i)] = nil
+	}
+	return dependencies
+}
+
+func TestRunnerForEach(t *testing.T) {
+	tests := []struct {
+		name          string
+		dependencies  map[string][]string
+		opts          RunnerOptions
+		failOn        string
+		expectedCalls int
+	}{
+		{
+			name:          "Sequential",
+			dependencies:  independentExperiments(4),
+			opts:          RunnerOptions{Parallelism: 1},
+			expectedCalls: 4,
+		},
+		{
+			name:          "Bounded parallelism",
+			dependencies:  independentExperiments(8),
+			opts:          RunnerOptions{Parallelism: 3},
+			expectedCalls: 8,
+		},
+		{
+			name:          "Errors don't cancel siblings",
+			dependencies:  independentExperiments(4),
+			opts:          RunnerOptions{Parallelism: 1},
+			failOn:        "experiment-0",
+			expectedCalls: 4,
+		},
+		{
+			name:          "Fail fast skips the remaining experiments",
+			dependencies:  independentExperiments(4),
+			opts:          RunnerOptions{Parallelism: 1, FailFast: true},
+			failOn:        "experiment-0",
+			expectedCalls: 1,
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			r := newTestRunner(test.dependencies, test.opts)
+
+			var calls, running, maxRunning int32
+			var mu sync.Mutex
+			r.forEach(context.Background(), forward, true, func(ctx context.Context, e *ExperimentConfig, log *output.Logger) error {
+				atomic.AddInt32(&calls, 1)
+				current := atomic.AddInt32(&running, 1)
+				defer atomic.AddInt32(&running, -1)
+
+				mu.Lock()
+				if current > maxRunning {
+					maxRunning = current
+				}
+				mu.Unlock()
+
+				time.Sleep(5 * time.Millisecond)
+				if e.Metadata.Name == test.failOn {
+					return errors.New("failed")
+				}
+				return nil
+			})
+
+			assert.Equal(t, int32(test.expectedCalls), calls)
+			assert.LessOrEqual(t, maxRunning, int32(test.opts.Parallelism))
+		})
+	}
+}
+
+// fakeExperiment records the experiments it cleans up, and fails or blocks the ones it is told to
+type fakeExperiment struct {
+	mu       sync.Mutex
+	runErrs  map[string]error
+	results  map[string]string
+	cleanups []string
+	verifies int
+	// onRun is called with the name of every experiment that runs
+	onRun func(name string)
+}
+
+func (f *fakeExperiment) Type() string                { return "fake" }
+func (f *fakeExperiment) Description() string         { return "Fake experiment" }
+func (f *fakeExperiment) Framework() string           { return "MITRE" }
+func (f *fakeExperiment) Tactic() string              { return "Tactic" }
+func (f *fakeExperiment) Technique() string           { return "Technique" }
+func (f *fakeExperiment) NewParameters() interface{}  { return &struct{}{} }
+func (f *fakeExperiment) Severity() verifier.Severity { return verifier.High }
+func (f *fakeExperiment) Remediation() *verifier.Remediation {
+	return &verifier.Remediation{Summary: "Block the fake attack"}
+}
+
+func (f *fakeExperiment) Run(ctx context.Context, e *ExperimentConfig) error {
+	if f.onRun != nil {
+		f.onRun(e.Metadata.Name)
+	}
+	return f.runErrs[e.Metadata.Name]
+}
+
+func (f *fakeExperiment) Verify(ctx context.Context, e *ExperimentConfig) (*verifier.LegacyOutcome, error) {
+	f.mu.Lock()
+	f.verifies++
+	f.mu.Unlock()
+
+	v := verifier.NewLegacy(e.Metadata.Name, f.Description(), f.Framework(), f.Tactic(), f.Technique())
+	if result, ok := f.results[e.Metadata.Name]; ok && result == verifier.Fail {
+		v.Fail("attack")
+	} else {
+		v.Success("attack")
+	}
+	return v.GetOutcome(), nil
+}
+
+func (f *fakeExperiment) Cleanup(ctx context.Context, e *ExperimentConfig) error {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	f.cleanups = append(f.cleanups, e.Metadata.Name)
+	return nil
+}
+
+// newFakeRunner returns a Runner for experiments with the given dependencies, which are all run by experiment
+func newFakeRunner(t *testing.T, ctx context.Context, experiment *fakeExperiment, dependencies map[string][]string, expect map[string]verifier.Behavior) *Runner {
+	r := newTestRunner(dependencies, RunnerOptions{Parallelism: 1})
+	r.ctx = ctx
+	r.ledger = ledger.NewStore(t.TempDir())
+	r.experiments = map[string]Experiment{"fake": experiment}
+	for name, e := range r.experimentsConfig {
+		e.Metadata.Type = "fake"
+		e.Metadata.Expect = expect[name]
+	}
+	return r
+}
+
+func TestNewRunnerParallelism(t *testing.T) {
+	for _, parallelism := range []int{0, -1} {
+		_, err := NewRunner(context.Background(), nil, RunnerOptions{Parallelism: parallelism})
+		assert.Error(t, err, "parallelism %d", parallelism)
+	}
+}
+
+func TestRunnerRunLifecycle(t *testing.T) {
+	forbidden := apierrors.NewForbidden(schema.GroupResource{Group: "apps", Resource: "deployments"}, "b", errors.New(`User "dev" cannot create resource "deployments" in API group "apps" in the namespace "default"`))
+	dependencies := map[string][]string{
+		"a": nil,
+		"b": {"a"},
+		"c": {"b"},
+		"d": nil,
+	}
+
+	tests := []struct {
+		name             string
+		experiment       *fakeExperiment
+		expect           map[string]verifier.Behavior
+		lifecycle        Lifecycle
+		interruptAfter   string
+		expectError      bool
+		expectedSummary  verifier.Summary
+		expectedCleanups []string
+	}{
+		{
+			name:             "Everything passes and is cleaned up",
+			experiment:       &fakeExperiment{},
+			lifecycle:        Lifecycle{Verify: true, Cleanup: true},
+			expectedSummary:  verifier.Summary{Experiments: 4, Passed: 4, Tests: 4, TestsPassed: 4},
+			expectedCleanups: []string{"c", "b", "a", "d"},
+		},
+		{
+			name:             "Run without cleanup",
+			experiment:       &fakeExperiment{},
+			lifecycle:        Lifecycle{Verify: true},
+			expectedSummary:  verifier.Summary{Experiments: 4, Passed: 4, Tests: 4, TestsPassed: 4},
+			expectedCleanups: nil,
+		},
+		{
+			name:             "Failed experiment is cleaned up but its dependents never started",
+			experiment:       &fakeExperiment{runErrs: map[string]error{"b": errors.New("boom")}},
+			lifecycle:        Lifecycle{Verify: true, Cleanup: true},
+			expectedSummary:  verifier.Summary{Experiments: 2, Passed: 2, Errors: 1, Tests: 2, TestsPassed: 2},
+			expectedCleanups: []string{"b", "a", "d"},
+		},
+		{
+			name:             "Keep on failure keeps a missed expectation and its prerequisites",
+			experiment:       &fakeExperiment{results: map[string]string{"b": verifier.Fail}},
+			lifecycle:        Lifecycle{Verify: true, Cleanup: true, KeepOnFailure: true},
+			expectedSummary:  verifier.Summary{Experiments: 4, Passed: 3, Failed: 1, Tests: 4, TestsPassed: 3, TestsFailed: 1, FailedBySeverity: map[verifier.Severity]int{verifier.High: 1}},
+			expectedCleanups: []string{"c", "d"},
+		},
+		{
+			name:             "Blocked as expected",
+			experiment:       &fakeExperiment{runErrs: map[string]error{"b": forbidden}},
+			expect:           map[string]verifier.Behavior{"b": verifier.Blocked},
+			lifecycle:        Lifecycle{Verify: true, Cleanup: true, KeepOnFailure: true},
+			expectedSummary:  verifier.Summary{Experiments: 3, Passed: 3, Tests: 3, TestsPassed: 3},
+			expectedCleanups: []string{"b", "a", "d"},
+		},
+		{
+			name:             "Interrupted run still cleans up",
+			experiment:       &fakeExperiment{},
+			lifecycle:        Lifecycle{Verify: true, Cleanup: true},
+			interruptAfter:   "a",
+			expectError:      true,
+			expectedCleanups: []string{"a"},
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			ctx, cancel := context.WithCancel(context.Background())
+			defer cancel()
+			test.experiment.onRun = func(name string) {
+				if name == test.interruptAfter {
+					cancel()
+				}
+			}
+
+			r := newFakeRunner(t, ctx, test.experiment, dependencies, test.expect)
+
+			rep, err := r.Run(test.lifecycle)
+			if test.expectError {
+				assert.Error(t, err)
+			} else {
+				assert.NoError(t, err)
+				assert.Equal(t, test.expectedSummary, rep.Summary)
+			}
+			assert.Equal(t, test.expectedCleanups, test.experiment.cleanups)
+		})
+	}
+}
+
+func TestRunnerRunVerifiers(t *testing.T) {
+	experiment := &fakeExperiment{}
+	r := newFakeRunner(t, context.Background(), experiment, independentExperiments(3), nil)
+	_, err := r.Run(Lifecycle{})
+	assert.NoError(t, err)
+
+	// Every experiment is verified exactly once, and the summary is derived from the same outcomes
+	rep, err := r.RunVerifiers("json")
+	assert.NoError(t, err)
+	assert.Equal(t, 3, experiment.verifies)
+	assert.Equal(t, r.run.ID, rep.RunID)
+	assert.Len(t, rep.Results, 3)
+	assert.Equal(t, verifier.Summary{Experiments: 3, Passed: 3, Tests: 3, TestsPassed: 3}, rep.Summary)
+
+	// Outcomes are recorded in the run for reports
+	for name := range r.experimentsConfig {
+		assert.NotNil(t, r.run.Outcome(name), "outcome of %s", name)
+	}
+}
+
+func TestRunnerOtherRun(t *testing.T) {
+	experiment := &fakeExperiment{}
+	r := newFakeRunner(t, context.Background(), experiment, independentExperiments(2), nil)
+	_, err := r.Run(Lifecycle{})
+	require.NoError(t, err)
+
+	// Experiments added to the files since the run are neither verified nor cleaned up
+	r.experimentsConfig["added"] = &ExperimentConfig{Metadata: ExperimentMetadata{Name: "added", Type: "fake"}}
+	rep, err := r.RunVerifiers("json")
+	require.NoError(t, err)
+	assert.Equal(t, 2, experiment.verifies)
+	assert.Len(t, rep.Results, 2)
+	assert.Empty(t, rep.Errors)
+
+	assert.NoError(t, r.Cleanup())
+	assert.ElementsMatch(t, []string{"experiment-0", "experiment-1"}, experiment.cleanups)
+}
+
+func TestRunnerRunSuppressed(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "suppressions.yaml")
+	require.NoError(t, os.WriteFile(path, []byte(`
+suppressions:
+  - experiment: b
+    expires: 2999-12-31
+    justification: Accepted risk
+`), 0600))
+	f, err := suppressions.Load(path)
+	require.NoError(t, err)
+
+	experiment := &fakeExperiment{results: map[string]string{"b": verifier.Fail}}
+	r := newFakeRunner(t, context.Background(), experiment, map[string][]string{"a": nil, "b": nil}, map[string]verifier.Behavior{"b": verifier.Allowed})
+	r.suppressions = f
+
+	// The suppressed experiment is an accepted risk, it isn't kept for debugging and doesn't fail the run
+	rep, err := r.Run(Lifecycle{Verify: true, Cleanup: true, KeepOnFailure: true})
+	assert.NoError(t, err)
+	assert.Equal(t, 1, rep.Summary.Suppressed)
+	assert.Equal(t, 0, rep.Summary.Failed)
+	assert.Len(t, experiment.cleanups, 2)
+}
@@ -4,80 +4,34 @@ import (
 	"context"
 	"errors"
 	"fmt"
-	"os"
-	"path/filepath"
 	"strings"
 
 	"github.com/docker/docker/api/types/container"
 	dockerClient "github.com/docker/docker/client"
 	"github.com/operantai/woodpecker/internal/k8s"
+	"github.com/operantai/woodpecker/internal/ledger"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 )
 
-const tmpFileDir = "/tmp/woodpecker"
+// errNoRun is returned when an experiment needs the run ledger but wasn't given a run
+var errNoRun = errors.New("no run found in context")
 
-func createTempFile(experimentType, experiment string) (*os.File, error) {
-	if _, err := os.Stat(tmpFileDir); err != nil {
-		if os.IsNotExist(err) {
-			if err := os.Mkdir(tmpFileDir, 0700); err != nil {
-				return nil, err
-			}
-		}
-	}
-	file, err := os.CreateTemp(tmpFileDir, fmt.Sprintf("%s-%s", experimentType, experiment))
-	if err != nil {
-		return nil, err
-	}
-	return file, nil
-}
-
-func getTempFileContentsForExperiment(experimentType, experiment string) ([][]byte, error) {
-	var contents [][]byte
-	files, err := getTempFilesForExperiment(experimentType, experiment)
-	if err != nil {
-		return nil, err
-	}
-	for _, file := range files {
-		content, err := os.ReadFile(file)
-		if err != nil {
-			return nil, err
-		}
-		contents = append(contents, content)
+// storeResult records a raw JSON result payload for an experiment in the current run
+func storeResult(ctx context.Context, experiment string, result []byte) error {
+	run, ok := ledger.FromContext(ctx)
+	if !ok {
+		return errNoRun
 	}
-	return contents, nil
+	return run.AddResult(experiment, result)
 }
 
-func getTempFilesForExperiment(experimentType, experiment string) ([]string, error) {
-	d, err := os.Open(tmpFileDir)
-	if err != nil {
-		return nil, err
-	}
-	files, err := d.ReadDir(-1)
-	if err != nil {
-		return nil, err
-	}
-	var fullPaths []string
-	for _, file := range files {
-		if !file.IsDir() && strings.HasPrefix(file.Name(), fmt.Sprintf("%s-%s", experimentType, experiment)) {
-			fullPaths = append(fullPaths, filepath.Join(tmpFileDir, file.Name()))
-		}
-	}
-	return fullPaths, nil
-}
-
-func removeTempFilesForExperiment(experimentType, experiment string) error {
-	files, err := getTempFilesForExperiment(experimentType, experiment)
-	if err != nil {
-		return err
-	}
-
-	for _, file := range files {
-		if err := os.Remove(file); err != nil {
-			return err
-		}
-
+// getResults returns the raw JSON result payloads recorded for an experiment in the current run
+func getResults(ctx context.Context, experiment string) ([][]byte, error) {
+	run, ok := ledger.FromContext(ctx)
+	if !ok {
+		return nil, errNoRun
 	}
-	return nil
+	return run.Results(experiment), nil
 }
 
 const WoodpeckerAI = "woodpecker-ai-verifier"
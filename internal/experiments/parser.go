@@ -4,15 +4,23 @@ Copyright 2023 Operant AI
 package experiments
 
 import (
+	"errors"
 	"fmt"
 	"os"
+	"reflect"
+	"strings"
 	"time"
 
+	"github.com/operantai/woodpecker/internal/verifier"
 	"gopkg.in/yaml.v3"
 )
 
 // ExperimentsConfig is a structure which represents the configuration for a set of experiments
 type ExperimentsConfig struct {
+	// Vars are the variables the experiments reference as ${name}, they're replaced before the experiments are parsed
+	Vars map[string]string `yaml:"vars"`
+	// Include lists files, directories or globs of other experiment files, relative to this file
+	Include           []string           `yaml:"include"`
 	ExperimentConfigs []ExperimentConfig `yaml:"experiments"`
 }
 
@@ -20,8 +28,34 @@ type ExperimentsConfig struct {
 type ExperimentConfig struct {
 	// Metadata for the experiment
 	Metadata ExperimentMetadata `yaml:"metadata"`
-	// Parameters for the experiment
-	Parameters interface{} `yaml:"parameters"`
+	// Parameters for the experiment, a pointer to the parameter type of the experiment once its file is parsed
+	Parameters interface{} `yaml:"-"`
+	// Source is the file the experiment was defined in
+	Source string `yaml:"-"`
+
+	// line is the line of the file the experiment starts at
+	line int
+	// parameters holds the parameters as written in the file until they are decoded into their type
+	parameters *yaml.Node
+}
+
+// UnmarshalYAML decodes the metadata of an experiment, and keeps its parameters to be decoded once the type of
+// the experiment, and so the type of its parameters, is known
+func (e *ExperimentConfig) UnmarshalYAML(value *yaml.Node) error {
+	var config struct {
+		Metadata   ExperimentMetadata `yaml:"metadata"`
+		Parameters yaml.Node          `yaml:"parameters"`
+	}
+	if err := value.Decode(&config); err != nil {
+		return err
+	}
+	e.Metadata = config.Metadata
+	e.line = value.Line
+	e.parameters = nil
+	if config.Parameters.Kind != 0 && config.Parameters.Tag != "!!null" {
+		e.parameters = &config.Parameters
+	}
+	return nil
 }
 
 // ExperimentMetadata is a structure which represents the metadata required for an experiment
@@ -32,6 +66,16 @@ type ExperimentMetadata struct {
 	Namespace string `yaml:"namespace"`
 	// Type of the experiment
 	Type string `yaml:"type"`
+	// Tags label the experiment so that a subset of experiments can be selected, e.g. rbac or pci
+	Tags []string `yaml:"tags"`
+	// DependsOn lists the names of experiments that must succeed before this one runs
+	DependsOn []string `yaml:"dependsOn"`
+	// ReadinessTimeout is how long to wait for workloads created by the experiment to become ready, defaults to 2m
+	ReadinessTimeout time.Duration `yaml:"readinessTimeout"`
+	// Expect is how the cluster is expected to respond to the attack, one of allowed, blocked or detected, defaults to allowed
+	Expect verifier.Behavior `yaml:"expect"`
+	// Severity overrides the default severity of the experiment's type, one of low, medium, high or critical
+	Severity verifier.Severity `yaml:"severity"`
 }
 
 type AIAppRequest struct {
@@ -50,10 +94,10 @@ type AIAppPayload struct {
 }
 
 type AIAPIPayload struct {
-	Model                string   `json:"model" yaml:"model"`
+	Model                string   `json:"model" yaml:"model" validate:"required"`
 	AIApi                string   `json:"ai_api" yaml:"ai_api"`
 	SystemPrompt         string   `json:"system_prompt" yaml:"system_prompt"`
-	Prompt               string   `json:"prompt" yaml:"prompt"`
+	Prompt               string   `json:"prompt" yaml:"prompt" validate:"required"`
 	Response             string   `json:"response" yaml:"response"`
 	VerifyPromptChecks   []string `json:"verify_prompt_checks" yaml:"verify_prompt_checks"`
 	VerifyResponseChecks []string `json:"verify_response_checks" yaml:"verify_response_checks"`
@@ -89,31 +133,292 @@ type ExecuteAIAPIResult struct {
 	Response       AIVerifierAPIResponse `json:"response"`
 }
 
-// parseExperimentConfig parses a YAML file and returns a slice of ExperimentConfig
-func parseExperimentConfigs(file string) ([]ExperimentConfig, error) {
+// parseExperimentConfig parses a YAML file and returns a slice of ExperimentConfig, with the variables it
+// references replaced by their values and vars overriding the ones it defines
+func parseExperimentConfigs(file string, vars map[string]string) ([]ExperimentConfig, error) {
 	// Read the file and then unmarshal it into a slice of ExperimentConfig
 	contents, err := os.ReadFile(file)
 	if err != nil {
 		return nil, err
 	}
-	configs, err := unmarshalYAML(contents)
+	configs, err := unmarshalYAML(contents, vars)
 	if err != nil {
-		return nil, err
+		return nil, withFile(file, err)
+	}
+	for i := range configs {
+		configs[i].Source = file
 	}
 	return configs, nil
 }
 
-func unmarshalYAML(contents []byte) ([]ExperimentConfig, error) {
+// unmarshalYAML parses the experiments in a file, returning the problems of every invalid experiment joined
+// into a single error
+func unmarshalYAML(contents []byte, vars map[string]string) ([]ExperimentConfig, error) {
+	var doc yaml.Node
+	if err := yaml.Unmarshal(contents, &doc); err != nil {
+		return nil, err
+	}
+	if doc.Kind == 0 {
+		return nil, nil
+	}
+	if err := interpolate(&doc, vars); err != nil {
+		return nil, err
+	}
 	var config ExperimentsConfig
-	err := yaml.Unmarshal(contents, &config)
-	if err != nil {
+	if err := doc.Decode(&config); err != nil {
 		return nil, err
 	}
 
-	for _, experiment := range config.ExperimentConfigs {
-		if experiment.Parameters == nil {
-			return nil, fmt.Errorf("Experiment %s is missing parameters", experiment.Metadata.Name)
+	var errs []error
+	for i := range config.ExperimentConfigs {
+		if err := config.ExperimentConfigs[i].validate(); err != nil {
+			errs = append(errs, err)
 		}
 	}
+	if len(errs) > 0 {
+		return nil, errors.Join(errs...)
+	}
 	return config.ExperimentConfigs, nil
 }
+
+// validate checks the metadata of an experiment and decodes its parameters
+func (e *ExperimentConfig) validate() error {
+	if e.parameters == nil {
+		return fmt.Errorf("Experiment %s is missing parameters", e.Metadata.Name)
+	}
+	expect, err := verifier.ParseBehavior(string(e.Metadata.Expect))
+	if err != nil {
+		return fmt.Errorf("Experiment %s has an invalid expect: %w", e.Metadata.Name, err)
+	}
+	e.Metadata.Expect = expect
+	if _, err := verifier.ParseSeverity(string(e.Metadata.Severity)); err != nil {
+		return fmt.Errorf("Experiment %s has an invalid severity: %w", e.Metadata.Name, err)
+	}
+	experiment, ok := lookupExperiment(e.Metadata.Type)
+	if !ok {
+		return fmt.Errorf("Experiment %s does not exist", e.Metadata.Type)
+	}
+	if err := e.decodeParameters(experiment.NewParameters()); err != nil {
+		return fmt.Errorf("Experiment %s has invalid parameters: %w", e.Metadata.Name, err)
+	}
+	return nil
+}
+
+// withFile prefixes the errors joined in err with the file they were found in
+func withFile(file string, err error) error {
+	joined, ok := err.(interface{ Unwrap() []error })
+	if !ok {
+		return fmt.Errorf("%s: %w", file, err)
+	}
+	var errs []error
+	for _, e := range joined.Unwrap() {
+		errs = append(errs, fmt.Errorf("%s: %w", file, e))
+	}
+	return errors.Join(errs...)
+}
+
+// parseFiles parses the experiments in the files the given paths refer to, including those suites include, and
+// checks their dependencies, keyed by name. It returns every problem found, including experiments defined more
+// than once.
+func parseFiles(paths []string, vars map[string]string) (map[string]*ExperimentConfig, []error) {
+	configs := make(map[string]*ExperimentConfig)
+	files, errs := resolveFiles(paths)
+	for _, file := range files {
+		experimentConfigs, err := parseExperimentConfigs(file, vars)
+		if err != nil {
+			if joined, ok := err.(interface{ Unwrap() []error }); ok {
+				errs = append(errs, joined.Unwrap()...)
+			} else {
+				errs = append(errs, err)
+			}
+			continue
+		}
+		for i, e := range experimentConfigs {
+			if existing, ok := configs[e.Metadata.Name]; ok {
+				errs = append(errs, fmt.Errorf("Experiment %s is defined more than once, in %s:%d and %s:%d", e.Metadata.Name, existing.Source, existing.line, e.Source, e.line))
+				continue
+			}
+			configs[e.Metadata.Name] = &experimentConfigs[i]
+		}
+	}
+	if len(errs) > 0 {
+		return nil, errs
+	}
+	if err := validateDependencies(configs); err != nil {
+		return nil, []error{fmt.Errorf("Invalid experiment dependencies: %w", err)}
+	}
+	return configs, nil
+}
+
+// Validate checks the experiments in the files the given paths refer to without acting on a cluster, returning the number of
+// experiments and every problem found with them. vars override the variables the files define.
+func Validate(files []string, vars map[string]string) (int, []error) {
+	configs, errs := parseFiles(files, vars)
+	return len(configs), errs
+}
+
+// decodeParameters strictly decodes the parameters of the experiment into params, a pointer to the parameter
+// type of the experiment, rejecting fields that the type doesn't have
+func (e *ExperimentConfig) decodeParameters(params interface{}) error {
+	if err := checkKnownFields(e.parameters, reflect.TypeOf(params)); err != nil {
+		return err
+	}
+	if err := e.parameters.Decode(params); err != nil {
+		return err
+	}
+	if missing := missingParameter(reflect.ValueOf(params), ""); missing != "" {
+		return fmt.Errorf("line %d, column %d: missing required parameter %s", e.parameters.Line, e.parameters.Column, missing)
+	}
+	if v, ok := params.(parametersValidator); ok {
+		if err := v.Validate(); err != nil {
+			return err
+		}
+	}
+	e.Parameters = params
+	return nil
+}
+
+// parametersValidator is implemented by parameter types with checks beyond their required parameters
+type parametersValidator interface {
+	Validate() error
+}
+
+// missingParameter returns the path of the first parameter tagged with validate:"required" that isn't set
+func missingParameter(v reflect.Value, path string) string {
+	for v.Kind() == reflect.Pointer {
+		if v.IsNil() {
+			return ""
+		}
+		v = v.Elem()
+	}
+	switch v.Kind() {
+	case reflect.Struct:
+		for _, field := range yamlFields(v.Type()) {
+			name := field.Name
+			if path != "" {
+				name = path + "." + field.Name
+			}
+			value := v.FieldByIndex(field.Index)
+			if field.Required && value.IsZero() {
+				return name
+			}
+			if missing := missingParameter(value, name); missing != "" {
+				return missing
+			}
+		}
+	case reflect.Slice, reflect.Array:
+		for i := 0; i < v.Len(); i++ {
+			if missing := missingParameter(v.Index(i), fmt.Sprintf("%s[%d]", path, i)); missing != "" {
+				return missing
+			}
+		}
+	}
+	return ""
+}
+
+// loadParameters sets params to the parameters of an experiment, which were decoded into their type when the
+// file of the experiment was parsed
+func loadParameters[T any](experimentConfig *ExperimentConfig, params *T) error {
+	decoded, ok := experimentConfig.Parameters.(*T)
+	if !ok {
+		return fmt.Errorf("Experiment %s has parameters of type %T, expected %T", experimentConfig.Metadata.Name, experimentConfig.Parameters, params)
+	}
+	*params = *decoded
+	return nil
+}
+
+// checkKnownFields returns an error with the line and column of the first field of node that the type t
+// doesn't have. yaml.v3 only rejects unknown fields when decoding whole documents, and so can't check the
+// parameters of an experiment on their own.
+func checkKnownFields(node *yaml.Node, t reflect.Type) error {
+	if node.Kind == yaml.AliasNode {
+		return checkKnownFields(node.Alias, t)
+	}
+	for t.Kind() == reflect.Pointer {
+		t = t.Elem()
+	}
+	if reflect.PointerTo(t).Implements(reflect.TypeOf((*yaml.Unmarshaler)(nil)).Elem()) {
+		return nil
+	}
+
+	// Nodes that don't match the kind of t are left to Decode, which reports them as type errors
+	switch {
+	case t.Kind() == reflect.Struct && node.Kind == yaml.MappingNode:
+		fields := make(map[string]reflect.Type)
+		for _, field := range yamlFields(t) {
+			fields[field.Name] = field.Type
+		}
+		for i := 0; i+1 < len(node.Content); i += 2 {
+			key, value := node.Content[i], node.Content[i+1]
+			if key.Value == "<<" {
+				if err := checkKnownFields(value, t); err != nil {
+					return err
+				}
+				continue
+			}
+			field, ok := fields[key.Value]
+			if !ok {
+				return fmt.Errorf("line %d, column %d: unknown field %q", key.Line, key.Column, key.Value)
+			}
+			if err := checkKnownFields(value, field); err != nil {
+				return err
+			}
+		}
+	case t.Kind() == reflect.Map && node.Kind == yaml.MappingNode:
+		for i := 1; i < len(node.Content); i += 2 {
+			if err := checkKnownFields(node.Content[i], t.Elem()); err != nil {
+				return err
+			}
+		}
+	case (t.Kind() == reflect.Slice || t.Kind() == reflect.Array) && node.Kind == yaml.SequenceNode:
+		for _, item := range node.Content {
+			if err := checkKnownFields(item, t.Elem()); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+// yamlField is a field of a struct as yaml.v3 decodes it
+type yamlField struct {
+	Name  string
+	Type  reflect.Type
+	Index []int
+	// Required fields are tagged with validate:"required", and must not be left empty
+	Required bool
+}
+
+// yamlFields returns the fields of a struct in order, named by the key yaml.v3 decodes them from, including the
+// fields of inlined structs
+func yamlFields(t reflect.Type) []yamlField {
+	var fields []yamlField
+	for i := 0; i < t.NumField(); i++ {
+		field := t.Field(i)
+		if !field.IsExported() {
+			continue
+		}
+		tag := field.Tag.Get("yaml")
+		if tag == "-" {
+			continue
+		}
+		name, options, _ := strings.Cut(tag, ",")
+		if strings.Contains(","+options+",", ",inline,") && field.Type.Kind() == reflect.Struct {
+			for _, inlined := range yamlFields(field.Type) {
+				inlined.Index = append([]int{i}, inlined.Index...)
+				fields = append(fields, inlined)
+			}
+			continue
+		}
+		if name == "" {
+			name = strings.ToLower(field.Name)
+		}
+		fields = append(fields, yamlField{
+			Name:     name,
+			Type:     field.Type,
+			Index:    []int{i},
+			Required: field.Tag.Get("validate") == "required",
+		})
+	}
+	return fields
+}
@@ -1,16 +1,22 @@
 package experiments
 
 import (
+	"io/fs"
+	"os"
+	"path/filepath"
 	"testing"
 
+	embedExperiments "github.com/operantai/woodpecker/experiments"
 	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
 )
 
 func TestUnmarshalYAML(t *testing.T) {
 	tests := []struct {
-		name        string
-		contents    []byte
-		expectError bool
+		name          string
+		contents      []byte
+		expectError   bool
+		errorContains string
 	}{
 		{
 			name: "Valid Experiment",
@@ -19,11 +25,13 @@ experiments:
 - metadata:
     name: "Experiment 1"
     namespace: "my-namespace"
-    type: "privileged_container"
+    type: "privileged-container"
     labels:
       key1: "value1"
   parameters:
-    hostPid: true
+    experiment:
+      image: alpine:latest
+      hostPid: true
 `),
 			expectError: false,
 		},
@@ -34,22 +42,227 @@ experiments:
 - metadata:
     name: "Experiment 2"
     namespace: "my-namespace"
-    type: "privileged_container"
+    type: "privileged-container"
     labels:
       key1: "value1"
 `),
 			expectError: true,
 		},
+		{
+			name: "Invalid Experiment (unknown expectation)",
+			contents: []byte(`
+experiments:
+- metadata:
+    name: "Experiment 3"
+    namespace: "my-namespace"
+    type: "privileged-container"
+    expect: "prevented"
+  parameters:
+    experiment:
+      hostPid: true
+`),
+			expectError: true,
+		},
+		{
+			name: "Invalid Experiment (unknown severity)",
+			contents: []byte(`
+experiments:
+- metadata:
+    name: "Experiment 4"
+    namespace: "my-namespace"
+    type: "privileged-container"
+    severity: "severe"
+  parameters:
+    experiment:
+      hostPid: true
+`),
+			expectError: true,
+		},
+		{
+			name: "Invalid Experiment (unknown type)",
+			contents: []byte(`
+experiments:
+- metadata:
+    name: "Experiment 5"
+    namespace: "my-namespace"
+    type: "privileged_container"
+  parameters:
+    experiment:
+      hostPid: true
+`),
+			expectError:   true,
+			errorContains: "Experiment privileged_container does not exist",
+		},
+		{
+			name: "Invalid Experiment (misspelled parameter)",
+			contents: []byte(`
+experiments:
+- metadata:
+    name: "Experiment 6"
+    namespace: "my-namespace"
+    type: "privileged-container"
+  parameters:
+    experiment:
+      privileged: true
+      hostpid: true
+`),
+			expectError:   true,
+			errorContains: `Experiment Experiment 6 has invalid parameters: line 10, column 7: unknown field "hostpid"`,
+		},
+		{
+			name: "Invalid Experiment (misspelled parameter in a list)",
+			contents: []byte(`
+experiments:
+- metadata:
+    name: "Experiment 7"
+    namespace: "my-namespace"
+    type: "credential-access-container-secrets"
+  parameters:
+    env:
+      - envKey: PASSWORD
+        envVaule: hunter2
+`),
+			expectError:   true,
+			errorContains: `line 10, column 9: unknown field "envVaule"`,
+		},
+		{
+			name: "Invalid Experiment (wrong parameter type)",
+			contents: []byte(`
+experiments:
+- metadata:
+    name: "Experiment 8"
+    namespace: "my-namespace"
+    type: "privileged-container"
+  parameters:
+    experiment:
+      hostPid: sometimes
+`),
+			expectError:   true,
+			errorContains: "line 9",
+		},
+		{
+			name: "Invalid Experiment (missing required parameter)",
+			contents: []byte(`
+experiments:
+- metadata:
+    name: "Experiment 9"
+    namespace: "my-namespace"
+    type: "host-path-mount"
+  parameters:
+    hostPath: {}
+`),
+			expectError:   true,
+			errorContains: "missing required parameter hostPath.path",
+		},
+		{
+			name: "Valid Experiment (defaulted parameter)",
+			contents: []byte(`
+experiments:
+- metadata:
+    name: "Experiment 9"
+    namespace: "my-namespace"
+    type: "privileged-container"
+  parameters:
+    experiment:
+      hostPid: true
+`),
+			expectError: false,
+		},
+		{
+			name: "Invalid Experiment (invalid regex)",
+			contents: []byte(`
+experiments:
+- metadata:
+    name: "Experiment 10"
+    namespace: "my-namespace"
+    type: "kube-exec"
+  parameters:
+    target:
+      pod: "my-pod"
+    command: ["cat", "/etc/passwd"]
+    expectedOutputRegex: "root:("
+`),
+			expectError:   true,
+			errorContains: "invalid expectedOutputRegex",
+		},
+		{
+			name: "Invalid Experiment (invalid schedule)",
+			contents: []byte(`
+experiments:
+- metadata:
+    name: "Experiment 11"
+    namespace: "my-namespace"
+    type: "kubernetes-cronjob"
+  parameters:
+    schedule: "every minute"
+    image: alpine:latest
+`),
+			expectError:   true,
+			errorContains: "invalid schedule",
+		},
 	}
 
 	for _, test := range tests {
 		t.Run(test.name, func(t *testing.T) {
-			_, err := unmarshalYAML(test.contents)
+			configs, err := unmarshalYAML(test.contents, nil)
 			if test.expectError {
 				assert.Error(t, err)
-			} else {
-				assert.NoError(t, err)
+				if test.errorContains != "" {
+					assert.ErrorContains(t, err, test.errorContains)
+				}
+				return
 			}
+			require.NoError(t, err)
+			require.Len(t, configs, 1)
+			params, ok := configs[0].Parameters.(*PrivilegedContainer)
+			require.True(t, ok)
+			assert.True(t, params.Experiment.HostPid)
+		})
+	}
+}
+
+func TestUnmarshalYAMLSnippets(t *testing.T) {
+	files, err := fs.Glob(embedExperiments.EmbeddedExperiments, "*.yaml")
+	require.NoError(t, err)
+	require.NotEmpty(t, files)
+	for _, file := range files {
+		t.Run(file, func(t *testing.T) {
+			contents, err := embedExperiments.EmbeddedExperiments.ReadFile(file)
+			require.NoError(t, err)
+			_, err = unmarshalYAML(contents, nil)
+			assert.NoError(t, err)
 		})
 	}
 }
+
+func TestValidate(t *testing.T) {
+	experiment := func(name string) string {
+		return `
+experiments:
+- metadata:
+    name: "` + name + `"
+    type: "privileged-container"
+  parameters:
+    experiment:
+      image: alpine:latest
+`
+	}
+	dir := t.TempDir()
+	write := func(name, contents string) string {
+		path := filepath.Join(dir, name)
+		require.NoError(t, os.WriteFile(path, []byte(contents), 0o600))
+		return path
+	}
+	first := write("first.yaml", experiment("Experiment 1"))
+	second := write("second.yaml", experiment("Experiment 2"))
+	duplicate := write("duplicate.yaml", experiment("Experiment 1"))
+
+	count, errs := Validate([]string{first, second}, nil)
+	assert.Empty(t, errs)
+	assert.Equal(t, 2, count)
+
+	_, errs = Validate([]string{first, duplicate}, nil)
+	require.Len(t, errs, 1)
+	assert.ErrorContains(t, errs[0], "Experiment 1")
+	assert.ErrorContains(t, errs[0], duplicate)
+}
@@ -12,6 +12,21 @@ var ExperimentsRegistry = []Experiment{
 	&LLMDataLeakageExperiment{},
 	&LLMDataPoisoningExperiment{},
 	&KubeExec{},
+	&KubernetesCronJobExperimentConfig{},
+	&MaliciousAdmissionControllerExperimentConfig{},
+	&DeleteK8sEventsExperimentConfig{},
+	&ClearContainerLogsExperimentConfig{},
+	&PodContainerNameSimilarityExperimentConfig{},
+}
+
+// lookupExperiment returns the experiment of the given type from the registry
+func lookupExperiment(experimentType string) (Experiment, bool) {
+	for _, experiment := range ExperimentsRegistry {
+		if experiment.Type() == experimentType {
+			return experiment, true
+		}
+	}
+	return nil, false
 }
 
 func ListExperiments() map[string]string {
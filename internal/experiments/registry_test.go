@@ -0,0 +1,55 @@
+package experiments
+
+import (
+	"io"
+	"strings"
+	"testing"
+
+	"github.com/operantai/woodpecker/internal/categories"
+	"github.com/operantai/woodpecker/internal/verifier"
+	"github.com/stretchr/testify/assert"
+	"gopkg.in/yaml.v3"
+)
+
+func TestRegistryRemediation(t *testing.T) {
+	for _, experiment := range ExperimentsRegistry {
+		t.Run(experiment.Type(), func(t *testing.T) {
+			_, err := verifier.ParseSeverity(string(experiment.Severity()))
+			assert.NoError(t, err)
+			assert.NotEmpty(t, experiment.Severity())
+
+			remediation := experiment.Remediation()
+			if !assert.NotNil(t, remediation) {
+				return
+			}
+			assert.NotEmpty(t, remediation.Summary)
+			assert.NotEmpty(t, remediation.Controls)
+			for _, control := range remediation.Controls {
+				assert.NotEmpty(t, control.Description)
+				if control.Snippet == "" {
+					continue
+				}
+				decoder := yaml.NewDecoder(strings.NewReader(control.Snippet))
+				for {
+					var document map[string]interface{}
+					if err := decoder.Decode(&document); err != nil {
+						assert.ErrorIs(t, err, io.EOF, "snippet of %s control", control.Kind)
+						break
+					}
+					assert.NotEmpty(t, document["kind"], "snippet of %s control", control.Kind)
+				}
+			}
+		})
+	}
+}
+
+func TestRegistryTechniqueIDs(t *testing.T) {
+	// Every experiment shows up in the Navigator layer and under a technique's SARIF rule
+	for _, experiment := range ExperimentsRegistry {
+		t.Run(experiment.Type(), func(t *testing.T) {
+			id, found := categories.TechniqueID(experiment.Framework(), experiment.Tactic(), experiment.Technique())
+			assert.True(t, found)
+			assert.NotEmpty(t, id)
+		})
+	}
+}
@@ -0,0 +1,197 @@
+/*
+Copyright 2023 Operant AI
+*/
+package experiments
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"sort"
+	"strings"
+
+	"github.com/operantai/woodpecker/internal/ledger"
+	"github.com/operantai/woodpecker/internal/output"
+)
+
+// traversal is the direction in which the dependency graph of experiments is walked
+type traversal int
+
+const (
+	// forward acts on prerequisites before the experiments that depend on them
+	forward traversal = iota
+	// reverse acts on dependents before their prerequisites, which is the order to tear experiments down in
+	reverse
+)
+
+// validateDependencies checks that every dependency refers to a known experiment and that
+// the dependencies between experiments form a directed acyclic graph
+func validateDependencies(configs map[string]*ExperimentConfig) error {
+	names := make([]string, 0, len(configs))
+	for name, e := range configs {
+		names = append(names, name)
+		for _, dep := range e.Metadata.DependsOn {
+			if _, ok := configs[dep]; !ok {
+				return fmt.Errorf("experiment %s depends on unknown experiment %s", name, dep)
+			}
+		}
+	}
+	sort.Strings(names)
+
+	const (
+		unvisited = iota
+		visiting
+		visited
+	)
+	state := make(map[string]int)
+	var path []string
+	var visit func(name string) error
+	visit = func(name string) error {
+		switch state[name] {
+		case visiting:
+			// Trim the path down to the start of the cycle
+			for i, p := range path {
+				if p == name {
+					return fmt.Errorf("dependency cycle detected: %s", strings.Join(append(path[i:], name), " -> "))
+				}
+			}
+		case visited:
+			return nil
+		}
+		state[name] = visiting
+		path = append(path, name)
+		for _, dep := range configs[name].Metadata.DependsOn {
+			if err := visit(dep); err != nil {
+				return err
+			}
+		}
+		path = path[:len(path)-1]
+		state[name] = visited
+		return nil
+	}
+
+	for _, name := range names {
+		if err := visit(name); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// configs returns the experiment configs of the Runner sorted by name
+func (r *Runner) configs() []*ExperimentConfig {
+	configs := make([]*ExperimentConfig, 0, len(r.experimentsConfig))
+	for _, e := range r.experimentsConfig {
+		configs = append(configs, e)
+	}
+	sort.Slice(configs, func(i, j int) bool {
+		return configs[i].Metadata.Name < configs[j].Metadata.Name
+	})
+	return configs
+}
+
+// forEach calls fn with a context derived from ctx for every experiment config in topological order, walking
+// the dependency graph in the given direction with a pool of at most r.parallelism workers. Experiments whose dependencies are
+// satisfied run at the same time, and ties are broken by name so that the order is deterministic.
+//
+// Each call gets its own Logger, which is flushed to stderr in one piece once fn returns so that output
+// from concurrent experiments doesn't interleave. An error returned by fn is logged, and only cancels the
+// remaining experiments when FailFast is set. When skipDependents is set, experiments waiting on one
+// that returned an error, or that was itself skipped, are skipped.
+func (r *Runner) forEach(ctx context.Context, t traversal, skipDependents bool, fn func(ctx context.Context, e *ExperimentConfig, log *output.Logger) error) {
+	ctx, cancel := context.WithCancelCause(ctx)
+	defer cancel(nil)
+	if r.run != nil {
+		ctx = ledger.NewContext(ctx, r.run)
+	}
+
+	configs := r.configs()
+
+	// waiting counts the unfinished experiments each experiment waits on, and unblocks
+	// lists the experiments to notify once an experiment finishes
+	waiting := make(map[string]int)
+	unblocks := make(map[string][]string)
+	for _, e := range configs {
+		for _, dep := range e.Metadata.DependsOn {
+			if t == forward {
+				waiting[e.Metadata.Name]++
+				unblocks[dep] = append(unblocks[dep], e.Metadata.Name)
+			} else {
+				waiting[dep]++
+				unblocks[e.Metadata.Name] = append(unblocks[e.Metadata.Name], dep)
+			}
+		}
+	}
+
+	var ready []string
+	for _, e := range configs {
+		if waiting[e.Metadata.Name] == 0 {
+			ready = append(ready, e.Metadata.Name)
+		}
+	}
+
+	// blockedBy records the failed experiment that caused an experiment to be skipped
+	blockedBy := make(map[string]string)
+	finish := func(name string, failed bool) {
+		for _, next := range unblocks[name] {
+			if failed && skipDependents {
+				if _, blocked := blockedBy[next]; !blocked {
+					blockedBy[next] = name
+				}
+			}
+			waiting[next]--
+			if waiting[next] == 0 {
+				ready = append(ready, next)
+			}
+		}
+		sort.Strings(ready)
+	}
+
+	type result struct {
+		name string
+		err  error
+	}
+	done := make(chan result)
+	started := make(map[string]bool)
+	running := 0
+	for {
+		for ctx.Err() == nil && running < r.parallelism && len(ready) > 0 {
+			name := ready[0]
+			ready = ready[1:]
+			started[name] = true
+
+			if prerequisite, blocked := blockedBy[name]; blocked {
+				output.WriteWarning("Skipping experiment %s: prerequisite %s did not succeed", name, prerequisite)
+				finish(name, true)
+				continue
+			}
+
+			running++
+			go func(e *ExperimentConfig) {
+				buf := new(bytes.Buffer)
+				err := fn(ctx, e, output.NewLogger(buf))
+				output.Flush(buf)
+				done <- result{name: e.Metadata.Name, err: err}
+			}(r.experimentsConfig[name])
+		}
+
+		if running == 0 {
+			break
+		}
+
+		res := <-done
+		running--
+		if res.err != nil && r.failFast && !errors.Is(res.err, errBlocked) {
+			cancel(fmt.Errorf("experiment %s failed and --fail-fast is set", res.name))
+		}
+		finish(res.name, res.err != nil)
+	}
+
+	// Anything that was never started was cancelled
+	for _, e := range configs {
+		if !started[e.Metadata.Name] {
+			output.WriteWarning("Skipping experiment %s: %s", e.Metadata.Name, context.Cause(ctx))
+		}
+	}
+}
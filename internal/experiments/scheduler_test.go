@@ -0,0 +1,127 @@
+package experiments
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"testing"
+
+	"github.com/operantai/woodpecker/internal/output"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestRunnerForEachDependencies(t *testing.T) {
+	dependencies := map[string][]string{
+		"privileged-container": nil,
+		"kube-exec":            {"privileged-container"},
+		"kube-exec-again":      {"kube-exec"},
+		"host-path-mount":      nil,
+	}
+	r := newTestRunner(dependencies, RunnerOptions{Parallelism: 2})
+
+	var mu sync.Mutex
+	var ran []string
+	r.forEach(context.Background(), forward, true, func(ctx context.Context, e *ExperimentConfig, log *output.Logger) error {
+		mu.Lock()
+		ran = append(ran, e.Metadata.Name)
+		mu.Unlock()
+		if e.Metadata.Name == "privileged-container" {
+			return errors.New("failed")
+		}
+		return nil
+	})
+
+	// Failed prerequisites skip their dependents, and the dependents of those
+	assert.ElementsMatch(t, []string{"privileged-container", "host-path-mount"}, ran)
+}
+
+func TestRunnerForEachOrder(t *testing.T) {
+	dependencies := map[string][]string{
+		"a": {"c"},
+		"b": {"a", "c"},
+		"c": nil,
+		"d": nil,
+	}
+
+	tests := []struct {
+		name          string
+		traversal     traversal
+		expectedOrder []string
+	}{
+		{
+			name:          "Forward",
+			traversal:     forward,
+			expectedOrder: []string{"c", "a", "b", "d"},
+		},
+		{
+			name:          "Reverse",
+			traversal:     reverse,
+			expectedOrder: []string{"b", "a", "c", "d"},
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			r := newTestRunner(dependencies, RunnerOptions{Parallelism: 1})
+
+			var order []string
+			r.forEach(context.Background(), test.traversal, false, func(ctx context.Context, e *ExperimentConfig, log *output.Logger) error {
+				order = append(order, e.Metadata.Name)
+				return nil
+			})
+
+			assert.Equal(t, test.expectedOrder, order)
+		})
+	}
+}
+
+func TestValidateDependencies(t *testing.T) {
+	tests := []struct {
+		name          string
+		dependencies  map[string][]string
+		expectedError string
+	}{
+		{
+			name: "Valid graph",
+			dependencies: map[string][]string{
+				"privileged-container": nil,
+				"kube-exec":            {"privileged-container"},
+			},
+		},
+		{
+			name: "Unknown dependency",
+			dependencies: map[string][]string{
+				"kube-exec": {"privileged-container"},
+			},
+			expectedError: "experiment kube-exec depends on unknown experiment privileged-container",
+		},
+		{
+			name: "Self dependency",
+			dependencies: map[string][]string{
+				"a": {"a"},
+			},
+			expectedError: "dependency cycle detected: a -> a",
+		},
+		{
+			name: "Cycle",
+			dependencies: map[string][]string{
+				"a": {"b"},
+				"b": {"c"},
+				"c": {"a"},
+			},
+			expectedError: "dependency cycle detected: a -> b -> c -> a",
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			r := newTestRunner(test.dependencies, RunnerOptions{})
+			err := validateDependencies(r.experimentsConfig)
+			if test.expectedError == "" {
+				assert.NoError(t, err)
+			} else {
+				assert.EqualError(t, err, test.expectedError)
+			}
+		})
+	}
+}
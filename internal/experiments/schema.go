@@ -0,0 +1,159 @@
+package experiments
+
+import (
+	"fmt"
+	"reflect"
+	"time"
+
+	"github.com/operantai/woodpecker/internal/verifier"
+)
+
+const jsonSchemaDraft = "https://json-schema.org/draft/2020-12/schema"
+
+// durationPattern matches the durations time.ParseDuration accepts, e.g. 2m or 1h30m
+const durationPattern = `^([0-9]+(\.[0-9]+)?(ns|us|µs|ms|s|m|h))+$`
+
+// Schema returns the JSON Schema of experiment files, which editors use to complete and check them. The schema
+// only accepts experiments of the given type, or of any type in the registry if it is empty.
+func Schema(experimentType string) (map[string]interface{}, error) {
+	var types []Experiment
+	for _, experiment := range ExperimentsRegistry {
+		if experimentType == "" || experiment.Type() == experimentType {
+			types = append(types, experiment)
+		}
+	}
+	if len(types) == 0 {
+		return nil, fmt.Errorf("Experiment %s does not exist", experimentType)
+	}
+
+	names := make([]string, 0, len(types))
+	for _, experiment := range types {
+		names = append(names, experiment.Type())
+	}
+	metadata := typeSchema(reflect.TypeOf(ExperimentMetadata{}))
+	metadata["required"] = []string{"name", "type"}
+	properties := metadata["properties"].(map[string]interface{})
+	properties["type"] = map[string]interface{}{"type": "string", "enum": names}
+	properties["expect"] = map[string]interface{}{
+		"type": "string",
+		"enum": []verifier.Behavior{verifier.Allowed, verifier.Blocked, verifier.Detected},
+	}
+	properties["severity"] = map[string]interface{}{
+		"type": "string",
+		"enum": []verifier.Severity{verifier.Low, verifier.Medium, verifier.High, verifier.Critical},
+	}
+	// Metadata is decoded leniently, so unlike parameters it may hold other fields such as labels
+	delete(metadata, "additionalProperties")
+
+	experiment := map[string]interface{}{
+		"type":     "object",
+		"required": []string{"metadata", "parameters"},
+		"properties": map[string]interface{}{
+			"metadata": metadata,
+		},
+	}
+	if len(types) == 1 {
+		experiment["description"] = types[0].Description()
+		experiment["properties"].(map[string]interface{})["parameters"] = typeSchema(reflect.TypeOf(types[0].NewParameters()))
+	} else {
+		var conditions []interface{}
+		for _, t := range types {
+			parameters := typeSchema(reflect.TypeOf(t.NewParameters()))
+			parameters["description"] = t.Description()
+			conditions = append(conditions, map[string]interface{}{
+				"if": map[string]interface{}{
+					"properties": map[string]interface{}{
+						"metadata": map[string]interface{}{
+							"properties": map[string]interface{}{"type": map[string]interface{}{"const": t.Type()}},
+						},
+					},
+				},
+				"then": map[string]interface{}{
+					"properties": map[string]interface{}{"parameters": parameters},
+				},
+			})
+		}
+		experiment["allOf"] = conditions
+	}
+
+	title := "woodpecker experiments"
+	if experimentType != "" {
+		title = fmt.Sprintf("woodpecker %s experiments", experimentType)
+	}
+	return map[string]interface{}{
+		"$schema": jsonSchemaDraft,
+		"title":   title,
+		"type":    "object",
+		"properties": map[string]interface{}{
+			"include": map[string]interface{}{
+				"type":  "array",
+				"items": map[string]interface{}{"type": "string"},
+			},
+			"vars": map[string]interface{}{
+				"type":                 "object",
+				"additionalProperties": map[string]interface{}{"type": []string{"string", "number", "boolean"}},
+			},
+			"experiments": map[string]interface{}{
+				"type":  "array",
+				"items": experiment,
+			},
+		},
+	}, nil
+}
+
+// scalarSchema returns the JSON Schema of a scalar that isn't a string, which may also be given as a variable
+func scalarSchema(scalarType string) map[string]interface{} {
+	return map[string]interface{}{
+		"anyOf": []interface{}{
+			map[string]interface{}{"type": scalarType},
+			map[string]interface{}{"type": "string", "pattern": variablePattern.String()},
+		},
+	}
+}
+
+// typeSchema returns the JSON Schema of the YAML a Go type is decoded from. Structs don't allow fields they
+// don't have, as parameters are decoded strictly, and fields tagged with validate:"required" are required.
+func typeSchema(t reflect.Type) map[string]interface{} {
+	for t.Kind() == reflect.Pointer {
+		t = t.Elem()
+	}
+	if t == reflect.TypeOf(time.Duration(0)) {
+		return map[string]interface{}{"type": "string", "pattern": durationPattern}
+	}
+
+	switch t.Kind() {
+	case reflect.Struct:
+		properties := make(map[string]interface{})
+		var required []string
+		for _, field := range yamlFields(t) {
+			properties[field.Name] = typeSchema(field.Type)
+			if field.Required {
+				required = append(required, field.Name)
+			}
+		}
+		schema := map[string]interface{}{
+			"type":                 "object",
+			"properties":           properties,
+			"additionalProperties": false,
+		}
+		if len(required) > 0 {
+			schema["required"] = required
+		}
+		return schema
+	case reflect.Slice, reflect.Array:
+		return map[string]interface{}{"type": "array", "items": typeSchema(t.Elem())}
+	case reflect.Map:
+		return map[string]interface{}{"type": "object", "additionalProperties": typeSchema(t.Elem())}
+	case reflect.String:
+		return map[string]interface{}{"type": "string"}
+	case reflect.Bool:
+		return scalarSchema("boolean")
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64,
+		reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		return scalarSchema("integer")
+	case reflect.Float32, reflect.Float64:
+		return scalarSchema("number")
+	}
+	// Anything goes for interfaces
+	return map[string]interface{}{}
+}
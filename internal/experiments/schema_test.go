@@ -0,0 +1,40 @@
+package experiments
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestSchema(t *testing.T) {
+	schema, err := Schema("privileged-container")
+	require.NoError(t, err)
+	experiment := schema["properties"].(map[string]interface{})["experiments"].(map[string]interface{})["items"].(map[string]interface{})
+	properties := experiment["properties"].(map[string]interface{})
+
+	metadata := properties["metadata"].(map[string]interface{})
+	assert.Equal(t, []string{"name", "type"}, metadata["required"])
+	assert.Equal(t, []string{"privileged-container"}, metadata["properties"].(map[string]interface{})["type"].(map[string]interface{})["enum"])
+
+	parameters := properties["parameters"].(map[string]interface{})
+	assert.Equal(t, false, parameters["additionalProperties"])
+	container := parameters["properties"].(map[string]interface{})["experiment"].(map[string]interface{})
+	// The image defaults to alpine:latest, so it isn't required
+	assert.NotContains(t, container, "required")
+	assert.Contains(t, container["properties"], "hostPid")
+
+	schema, err = Schema("host-path-mount")
+	require.NoError(t, err)
+	experiment = schema["properties"].(map[string]interface{})["experiments"].(map[string]interface{})["items"].(map[string]interface{})
+	hostPath := experiment["properties"].(map[string]interface{})["parameters"].(map[string]interface{})["properties"].(map[string]interface{})["hostPath"].(map[string]interface{})
+	assert.Equal(t, []string{"path"}, hostPath["required"])
+
+	all, err := Schema("")
+	require.NoError(t, err)
+	experiment = all["properties"].(map[string]interface{})["experiments"].(map[string]interface{})["items"].(map[string]interface{})
+	assert.Len(t, experiment["allOf"], len(ExperimentsRegistry))
+
+	_, err = Schema("does-not-exist")
+	assert.Error(t, err)
+}
@@ -0,0 +1,81 @@
+package experiments
+
+import (
+	"regexp"
+	"slices"
+	"strings"
+
+	"github.com/operantai/woodpecker/internal/categories"
+)
+
+// Selector selects the experiments a Runner acts on. An experiment is selected when it matches every criterion
+// that is set, and for criteria holding several values, any of them. The zero Selector selects every experiment.
+type Selector struct {
+	// Tags selects experiments with any of the tags
+	Tags []string
+	// Types selects experiments of any of the types
+	Types []string
+	// Tactics selects experiments of any of the tactics, by name or ID, e.g. "Privilege Escalation" or TA0004
+	Tactics []string
+	// Name selects experiments whose name matches
+	Name *regexp.Regexp
+	// Exclude drops experiments whose name matches
+	Exclude *regexp.Regexp
+}
+
+// Matches reports whether the selector selects the experiment of the given config
+func (s Selector) Matches(e *ExperimentConfig) bool {
+	if len(s.Tags) > 0 && !slices.ContainsFunc(e.Metadata.Tags, func(tag string) bool { return slices.Contains(s.Tags, tag) }) {
+		return false
+	}
+	if len(s.Types) > 0 && !slices.Contains(s.Types, e.Metadata.Type) {
+		return false
+	}
+	if len(s.Tactics) > 0 && !s.matchesTactic(e) {
+		return false
+	}
+	if s.Name != nil && !s.Name.MatchString(e.Metadata.Name) {
+		return false
+	}
+	if s.Exclude != nil && s.Exclude.MatchString(e.Metadata.Name) {
+		return false
+	}
+	return true
+}
+
+// matchesTactic reports whether the tactic of the experiment's type is one of the selected ones
+func (s Selector) matchesTactic(e *ExperimentConfig) bool {
+	experiment, ok := lookupExperiment(e.Metadata.Type)
+	if !ok {
+		return false
+	}
+	id, _ := categories.CategoryID(experiment.Framework(), experiment.Tactic(), experiment.Technique())
+	for _, tactic := range s.Tactics {
+		if strings.EqualFold(tactic, experiment.Tactic()) || (id != "" && strings.EqualFold(tactic, id)) {
+			return true
+		}
+	}
+	return false
+}
+
+// selectExperiments returns the configs the selector selects, along with the experiments they depend on
+// so that they can still be scheduled
+func selectExperiments(configs map[string]*ExperimentConfig, s Selector) map[string]*ExperimentConfig {
+	selected := make(map[string]*ExperimentConfig)
+	var add func(e *ExperimentConfig)
+	add = func(e *ExperimentConfig) {
+		if _, ok := selected[e.Metadata.Name]; ok {
+			return
+		}
+		selected[e.Metadata.Name] = e
+		for _, dep := range e.Metadata.DependsOn {
+			add(configs[dep])
+		}
+	}
+	for _, e := range configs {
+		if s.Matches(e) {
+			add(e)
+		}
+	}
+	return selected
+}
@@ -0,0 +1,76 @@
+package experiments
+
+import (
+	"regexp"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestSelectExperiments(t *testing.T) {
+	config := func(name, experimentType string, tags []string, dependsOn ...string) *ExperimentConfig {
+		return &ExperimentConfig{Metadata: ExperimentMetadata{Name: name, Type: experimentType, Tags: tags, DependsOn: dependsOn}}
+	}
+	configs := map[string]*ExperimentConfig{
+		"privileged":    config("privileged", "privileged-container", []string{"pods", "pci"}),
+		"host-path":     config("host-path", "host-path-mount", []string{"pods"}),
+		"cluster-admin": config("cluster-admin", "cluster-admin-binding", []string{"rbac", "pci"}),
+		"list-secrets":  config("list-secrets", "list-kubernetes-secrets", []string{"rbac"}, "cluster-admin"),
+	}
+
+	tests := []struct {
+		name     string
+		selector Selector
+		expected []string
+	}{
+		{
+			name:     "Everything",
+			expected: []string{"cluster-admin", "host-path", "list-secrets", "privileged"},
+		},
+		{
+			name:     "Tag",
+			selector: Selector{Tags: []string{"pci"}},
+			expected: []string{"cluster-admin", "privileged"},
+		},
+		{
+			name:     "Type",
+			selector: Selector{Types: []string{"host-path-mount", "privileged-container"}},
+			expected: []string{"host-path", "privileged"},
+		},
+		{
+			name:     "Tactic by ID",
+			selector: Selector{Tactics: []string{"TA0004"}},
+			expected: []string{"cluster-admin", "host-path", "privileged"},
+		},
+		{
+			name:     "Tactic by name",
+			selector: Selector{Tactics: []string{"credential access"}},
+			expected: []string{"cluster-admin", "list-secrets"},
+		},
+		{
+			name:     "Tags and name",
+			selector: Selector{Tags: []string{"pods"}, Name: regexp.MustCompile("^host")},
+			expected: []string{"host-path"},
+		},
+		{
+			name:     "Exclude",
+			selector: Selector{Tags: []string{"pods"}, Exclude: regexp.MustCompile("privileged")},
+			expected: []string{"host-path"},
+		},
+		{
+			name:     "Dependencies are selected with their dependents",
+			selector: Selector{Name: regexp.MustCompile("secrets")},
+			expected: []string{"cluster-admin", "list-secrets"},
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			var names []string
+			for name := range selectExperiments(configs, test.selector) {
+				names = append(names, name)
+			}
+			assert.ElementsMatch(t, test.expected, names)
+		})
+	}
+}
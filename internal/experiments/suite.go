@@ -0,0 +1,119 @@
+package experiments
+
+import (
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"gopkg.in/yaml.v3"
+)
+
+// fileResolver expands the paths of experiment files, directories, globs and the files suites include into
+// the experiment files to parse
+type fileResolver struct {
+	files []string
+	seen  map[string]bool
+	errs  []error
+}
+
+// resolveFiles returns the experiment files the given paths refer to, each of them once. A path may be a file,
+// a directory whose YAML files are all included, or a glob. Files may include other paths with an include block,
+// which are relative to the including file.
+func resolveFiles(paths []string) ([]string, []error) {
+	r := &fileResolver{seen: make(map[string]bool)}
+	for _, path := range paths {
+		r.expand(path, "")
+	}
+	return r.files, r.errs
+}
+
+// expand adds the files a path refers to, suite is the file that includes the path or empty for the paths
+// given to resolveFiles
+func (r *fileResolver) expand(path, suite string) {
+	if suite != "" && !filepath.IsAbs(path) {
+		path = filepath.Join(filepath.Dir(suite), path)
+	}
+	matches := []string{path}
+	if strings.ContainsAny(path, "*?[") {
+		var err error
+		if matches, err = filepath.Glob(path); err != nil {
+			r.errs = append(r.errs, r.includeError(suite, fmt.Errorf("invalid glob %s: %w", path, err)))
+			return
+		}
+		if len(matches) == 0 {
+			r.errs = append(r.errs, r.includeError(suite, fmt.Errorf("%s matches no files", path)))
+			return
+		}
+	}
+
+	for _, match := range matches {
+		info, err := os.Stat(match)
+		if err != nil {
+			r.errs = append(r.errs, r.includeError(suite, err))
+			continue
+		}
+		if !info.IsDir() {
+			r.add(match)
+			continue
+		}
+		err = filepath.WalkDir(match, func(file string, d fs.DirEntry, err error) error {
+			if err != nil {
+				return err
+			}
+			if ext := filepath.Ext(file); !d.IsDir() && (ext == ".yaml" || ext == ".yml") {
+				r.add(file)
+			}
+			return nil
+		})
+		if err != nil {
+			r.errs = append(r.errs, r.includeError(suite, err))
+		}
+	}
+}
+
+// add adds an experiment file and the paths it includes, unless it was already added
+func (r *fileResolver) add(file string) {
+	key, err := filepath.Abs(file)
+	if err != nil {
+		key = filepath.Clean(file)
+	}
+	if r.seen[key] {
+		return
+	}
+	r.seen[key] = true
+
+	includes, err := readIncludes(file)
+	if err != nil {
+		r.errs = append(r.errs, fmt.Errorf("%s: %w", file, err))
+		return
+	}
+	r.files = append(r.files, file)
+	for _, include := range includes {
+		r.expand(include, file)
+	}
+}
+
+// includeError attributes an error resolving a path to the suite that includes it
+func (r *fileResolver) includeError(suite string, err error) error {
+	if suite == "" {
+		return err
+	}
+	return fmt.Errorf("%s: invalid include: %w", suite, err)
+}
+
+// readIncludes returns the paths an experiment file includes
+func readIncludes(file string) ([]string, error) {
+	contents, err := os.ReadFile(file)
+	if err != nil {
+		return nil, err
+	}
+	var suite struct {
+		Include []string `yaml:"include"`
+	}
+	if err := yaml.Unmarshal(contents, &suite); err != nil {
+		return nil, err
+	}
+	return suite.Include, nil
+}
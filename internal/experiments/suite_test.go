@@ -0,0 +1,50 @@
+package experiments
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestResolveFiles(t *testing.T) {
+	dir := t.TempDir()
+	write := func(name, contents string) string {
+		path := filepath.Join(dir, name)
+		require.NoError(t, os.MkdirAll(filepath.Dir(path), 0o700))
+		require.NoError(t, os.WriteFile(path, []byte(contents), 0o600))
+		return path
+	}
+	rbac := write("library/rbac/cluster-admin.yaml", "experiments: []\n")
+	pods := write("library/pods/privileged.yaml", "experiments: []\n")
+	hostPath := write("library/pods/host-path.yml", "experiments: []\n")
+	write("library/pods/README.md", "Not an experiment file\n")
+	llm := write("llm.yaml", "experiments: []\n")
+	suite := write("suites/all.yaml", `
+include:
+  - ../library
+  - ../library/pods/*.yaml
+  - ../*.yaml
+`)
+
+	files, errs := resolveFiles([]string{suite})
+	assert.Empty(t, errs)
+	assert.Equal(t, []string{suite, hostPath, pods, rbac, llm}, files)
+
+	missing := write("suites/missing.yaml", `
+include:
+  - ../library/nodes/*.yaml
+`)
+	_, errs = resolveFiles([]string{missing})
+	require.Len(t, errs, 1)
+	assert.ErrorContains(t, errs[0], missing)
+	assert.ErrorContains(t, errs[0], "matches no files")
+
+	// Suites including each other are only read once
+	cycle := write("suites/cycle.yaml", "include: [all.yaml, cycle.yaml]\n")
+	files, errs = resolveFiles([]string{cycle})
+	assert.Empty(t, errs)
+	assert.Equal(t, []string{cycle, suite, hostPath, pods, rbac, llm}, files)
+}
@@ -0,0 +1,131 @@
+package experiments
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"regexp"
+	"strings"
+
+	"gopkg.in/yaml.v3"
+)
+
+// variablePattern matches references to variables such as ${namespace}, and to environment variables such as
+// ${env:HOME}. A reference is escaped with a second $, e.g. $${namespace} is kept as ${namespace}.
+var variablePattern = regexp.MustCompile(`\$?\$\{([^}]*)\}`)
+
+// envPrefix prefixes references to environment variables
+const envPrefix = "env:"
+
+// ParseVars parses variables given as key=value, such as the values of the --set flag
+func ParseVars(assignments []string) (map[string]string, error) {
+	vars := make(map[string]string, len(assignments))
+	for _, assignment := range assignments {
+		key, value, ok := strings.Cut(assignment, "=")
+		if !ok || strings.TrimSpace(key) == "" {
+			return nil, fmt.Errorf("Invalid variable %q, must be key=value", assignment)
+		}
+		vars[strings.TrimSpace(key)] = value
+	}
+	return vars, nil
+}
+
+// interpolate replaces the references to variables in the values of a document with their values. The variables
+// are defined in the vars block of the document, whose values may reference environment variables and the
+// variables defined before them, and overrides replaces or adds to them. The vars block is removed from the
+// document once it's read.
+func interpolate(doc *yaml.Node, overrides map[string]string) error {
+	if doc.Kind != yaml.DocumentNode || len(doc.Content) == 0 || doc.Content[0].Kind != yaml.MappingNode {
+		return nil
+	}
+	root := doc.Content[0]
+
+	vars := make(map[string]string, len(overrides))
+	var errs []error
+	for i := 0; i+1 < len(root.Content); i += 2 {
+		if root.Content[i].Value != "vars" {
+			continue
+		}
+		block := root.Content[i+1]
+		if block.Kind != yaml.MappingNode {
+			return fmt.Errorf("line %d, column %d: vars must be a map of names to values", block.Line, block.Column)
+		}
+		for j := 0; j+1 < len(block.Content); j += 2 {
+			key, value := block.Content[j], block.Content[j+1]
+			if value.Kind != yaml.ScalarNode {
+				errs = append(errs, fmt.Errorf("line %d, column %d: variable %s must be a scalar", value.Line, value.Column, key.Value))
+				continue
+			}
+			if override, ok := overrides[key.Value]; ok {
+				vars[key.Value] = override
+				continue
+			}
+			resolved, err := interpolateString(value.Value, vars)
+			if err != nil {
+				errs = append(errs, fmt.Errorf("line %d, column %d: %w", value.Line, value.Column, err))
+				continue
+			}
+			vars[key.Value] = resolved
+		}
+		root.Content = append(root.Content[:i], root.Content[i+2:]...)
+		break
+	}
+	for key, value := range overrides {
+		vars[key] = value
+	}
+	interpolateNode(root, vars, &errs)
+	return errors.Join(errs...)
+}
+
+// interpolateNode replaces the references to variables in the scalar values under node, appending the
+// references it couldn't resolve to errs
+func interpolateNode(node *yaml.Node, vars map[string]string, errs *[]error) {
+	if node.Kind == yaml.ScalarNode {
+		if !strings.Contains(node.Value, "${") {
+			return
+		}
+		resolved, err := interpolateString(node.Value, vars)
+		if err != nil {
+			*errs = append(*errs, fmt.Errorf("line %d, column %d: %w", node.Line, node.Column, err))
+			return
+		}
+		node.Value = resolved
+		// Let plain values be resolved again, so that a variable holding a number decodes into an int
+		if node.Style == 0 {
+			node.Tag = ""
+		}
+		return
+	}
+	for i, child := range node.Content {
+		// Keys of maps are left as is, only values are interpolated
+		if node.Kind == yaml.MappingNode && i%2 == 0 {
+			continue
+		}
+		interpolateNode(child, vars, errs)
+	}
+}
+
+// interpolateString replaces the references to variables in s with their values, failing on the first
+// reference it can't resolve
+func interpolateString(s string, vars map[string]string) (string, error) {
+	var err error
+	resolved := variablePattern.ReplaceAllStringFunc(s, func(reference string) string {
+		if strings.HasPrefix(reference, "$$") {
+			return reference[1:]
+		}
+		name := strings.TrimSpace(reference[2 : len(reference)-1])
+		if env, ok := strings.CutPrefix(name, envPrefix); ok {
+			value, ok := os.LookupEnv(env)
+			if !ok && err == nil {
+				err = fmt.Errorf("environment variable %s is not set", env)
+			}
+			return value
+		}
+		value, ok := vars[name]
+		if !ok && err == nil {
+			err = fmt.Errorf("undefined variable %s", name)
+		}
+		return value
+	})
+	return resolved, err
+}
@@ -0,0 +1,101 @@
+package experiments
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestUnmarshalYAMLVars(t *testing.T) {
+	t.Setenv("WOODPECKER_TEST_IMAGE", "alpine:3.20")
+	contents := []byte(`
+vars:
+  namespace: staging
+  image: ${env:WOODPECKER_TEST_IMAGE}
+  hostPid: true
+experiments:
+- metadata:
+    name: "privileged-${namespace}"
+    namespace: ${namespace}
+    type: "privileged-container"
+  parameters:
+    experiment:
+      image: ${image}
+      hostPid: ${hostPid}
+      command: ["echo", "$${namespace}"]
+`)
+	tests := []struct {
+		name          string
+		contents      []byte
+		vars          map[string]string
+		namespace     string
+		expectError   bool
+		errorContains string
+	}{
+		{
+			name:      "Variables defined in the file",
+			contents:  contents,
+			namespace: "staging",
+		},
+		{
+			name:      "Variables overridden with --set",
+			contents:  contents,
+			vars:      map[string]string{"namespace": "prod"},
+			namespace: "prod",
+		},
+		{
+			name: "Undefined variable",
+			contents: []byte(`
+experiments:
+- metadata:
+    name: "Experiment 1"
+    namespace: ${namespace}
+    type: "privileged-container"
+  parameters:
+    experiment:
+      image: alpine:latest
+`),
+			expectError:   true,
+			errorContains: "line 5, column 16: undefined variable namespace",
+		},
+		{
+			name: "Unset environment variable",
+			contents: []byte(`
+vars:
+  image: ${env:WOODPECKER_TEST_UNSET}
+experiments: []
+`),
+			expectError:   true,
+			errorContains: "environment variable WOODPECKER_TEST_UNSET is not set",
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			configs, err := unmarshalYAML(test.contents, test.vars)
+			if test.expectError {
+				assert.ErrorContains(t, err, test.errorContains)
+				return
+			}
+			require.NoError(t, err)
+			require.Len(t, configs, 1)
+			assert.Equal(t, "privileged-"+test.namespace, configs[0].Metadata.Name)
+			assert.Equal(t, test.namespace, configs[0].Metadata.Namespace)
+			params, ok := configs[0].Parameters.(*PrivilegedContainer)
+			require.True(t, ok)
+			assert.Equal(t, "alpine:3.20", params.Experiment.Image)
+			assert.True(t, params.Experiment.HostPid)
+			assert.Equal(t, []string{"echo", "${namespace}"}, params.Experiment.Command)
+		})
+	}
+}
+
+func TestParseVars(t *testing.T) {
+	vars, err := ParseVars([]string{"namespace=prod", "prompt=a=b"})
+	require.NoError(t, err)
+	assert.Equal(t, map[string]string{"namespace": "prod", "prompt": "a=b"}, vars)
+
+	_, err = ParseVars([]string{"namespace"})
+	assert.Error(t, err)
+}
@@ -6,7 +6,9 @@ package k8s
 import (
 	"fmt"
 	"path/filepath"
+	"strings"
 
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
 	"k8s.io/client-go/kubernetes"
 	"k8s.io/client-go/rest"
 	"k8s.io/client-go/tools/clientcmd"
@@ -56,3 +58,27 @@ func NewClientInContainer() (*Client, error) {
 		Clientset: clientset,
 	}, nil
 }
+
+// ImpersonateServiceAccount returns a Client that acts as the named ServiceAccount, with the groups a token of the
+// ServiceAccount would have. The user running woodpecker must be allowed to impersonate it.
+func (c *Client) ImpersonateServiceAccount(namespace, name string) (*Client, error) {
+	config := rest.CopyConfig(c.RestConfig)
+	config.Impersonate = rest.ImpersonationConfig{
+		UserName: fmt.Sprintf("system:serviceaccount:%s:%s", namespace, name),
+		Groups:   []string{"system:serviceaccounts", "system:serviceaccounts:" + namespace, "system:authenticated"},
+	}
+	clientset, err := kubernetes.NewForConfig(config)
+	if err != nil {
+		return nil, fmt.Errorf("Failed to create Kubernetes Client for service account %s/%s: %w", namespace, name, err)
+	}
+	return &Client{
+		Clientset:  clientset,
+		RestConfig: config,
+	}, nil
+}
+
+// IsImpersonationDenied reports whether err means the user running woodpecker isn't allowed to impersonate,
+// which unlike other authorization errors says nothing about what the impersonated account may do
+func IsImpersonationDenied(err error) bool {
+	return apierrors.IsForbidden(err) && strings.Contains(err.Error(), "cannot impersonate")
+}
@@ -0,0 +1,24 @@
+package k8s
+
+import (
+	"context"
+
+	batchv1 "k8s.io/api/batch/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/kubernetes"
+)
+
+// CronJobJobs returns the Jobs the named CronJob spawned, which it controls
+func CronJobJobs(ctx context.Context, clientset kubernetes.Interface, namespace, name string) ([]batchv1.Job, error) {
+	jobs, err := clientset.BatchV1().Jobs(namespace).List(ctx, metav1.ListOptions{})
+	if err != nil {
+		return nil, err
+	}
+	var owned []batchv1.Job
+	for _, job := range jobs.Items {
+		if owner := metav1.GetControllerOf(&job); owner != nil && owner.Kind == "CronJob" && owner.Name == name {
+			owned = append(owned, job)
+		}
+	}
+	return owned, nil
+}
@@ -0,0 +1,40 @@
+package k8s
+
+import (
+	"context"
+	"fmt"
+
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/kubernetes"
+)
+
+// detectionReasons are the reasons of the events a policy engine records on a resource that violates a policy it
+// only audits, such as Kyverno's PolicyViolation
+var detectionReasons = map[string]bool{
+	"PolicyViolation": true,
+}
+
+// Detections returns the policy violations recorded as events on the named objects of a kind, which mean the
+// objects were admitted but noticed by a policy engine auditing the cluster
+func Detections(ctx context.Context, clientset kubernetes.Interface, namespace, kind string, names ...string) ([]string, error) {
+	events, err := clientset.CoreV1().Events(namespace).List(ctx, metav1.ListOptions{})
+	if err != nil {
+		return nil, err
+	}
+	wanted := make(map[string]bool)
+	for _, name := range names {
+		wanted[name] = true
+	}
+	var detections []string
+	for _, event := range events.Items {
+		if event.Type != corev1.EventTypeWarning || !detectionReasons[event.Reason] {
+			continue
+		}
+		if event.InvolvedObject.Kind != kind || !wanted[event.InvolvedObject.Name] {
+			continue
+		}
+		detections = append(detections, fmt.Sprintf("%s %s: %s", kind, event.InvolvedObject.Name, event.Message))
+	}
+	return detections, nil
+}
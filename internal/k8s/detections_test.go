@@ -0,0 +1,40 @@
+package k8s
+
+import (
+	"context"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/kubernetes/fake"
+)
+
+func TestDetections(t *testing.T) {
+	event := func(name, kind, object, eventType, reason string) *corev1.Event {
+		return &corev1.Event{
+			ObjectMeta:     metav1.ObjectMeta{Name: name, Namespace: "default"},
+			InvolvedObject: corev1.ObjectReference{Kind: kind, Name: object},
+			Type:           eventType,
+			Reason:         reason,
+			Message:        "policy disallow-privileged-containers/autogen-privileged-containers fail: validation error",
+		}
+	}
+	clientset := fake.NewSimpleClientset(
+		event("violation", "Deployment", "privileged", corev1.EventTypeWarning, "PolicyViolation"),
+		event("other-deployment", "Deployment", "other", corev1.EventTypeWarning, "PolicyViolation"),
+		event("other-kind", "ReplicaSet", "privileged", corev1.EventTypeWarning, "PolicyViolation"),
+		event("other-reason", "Deployment", "privileged", corev1.EventTypeNormal, "ScalingReplicaSet"),
+	)
+
+	detections, err := Detections(context.Background(), clientset, "default", "Deployment", "privileged")
+	assert.NoError(t, err)
+	assert.Equal(t, []string{
+		"Deployment privileged: policy disallow-privileged-containers/autogen-privileged-containers fail: validation error",
+	}, detections)
+
+	detections, err = Detections(context.Background(), clientset, "default", "Pod", "privileged-abc")
+	assert.NoError(t, err)
+	assert.Empty(t, detections)
+}
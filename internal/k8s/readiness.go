@@ -0,0 +1,392 @@
+/*
+Copyright 2023 Operant AI
+*/
+package k8s
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"regexp"
+	"strings"
+	"time"
+
+	appsv1 "k8s.io/api/apps/v1"
+	batchv1 "k8s.io/api/batch/v1"
+	corev1 "k8s.io/api/core/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/util/wait"
+	"k8s.io/client-go/kubernetes"
+)
+
+// DefaultReadinessTimeout is how long to wait for a workload to become ready when no timeout is configured
+const DefaultReadinessTimeout = 2 * time.Minute
+
+// readinessPollInterval is how often the state of a workload is checked while waiting for it to become ready
+var readinessPollInterval = 2 * time.Second
+
+// Reasons a workload never became ready, in addition to the container waiting reasons reported by the kubelet
+const (
+	ReasonAdmissionDenied  = "AdmissionDenied"
+	ReasonFailedCreate     = "FailedCreate"
+	ReasonProgressDeadline = "ProgressDeadlineExceeded"
+	ReasonPodFailed        = "PodFailed"
+	ReasonTimeout          = "Timeout"
+)
+
+// terminalWaitingReasons are container waiting reasons that a workload won't recover from without intervention
+var terminalWaitingReasons = map[string]bool{
+	"ErrImagePull":               true,
+	"ImagePullBackOff":           true,
+	"InvalidImageName":           true,
+	"CreateContainerConfigError": true,
+	"CreateContainerError":       true,
+	"RunContainerError":          true,
+	"CrashLoopBackOff":           true,
+}
+
+// NotReadyError is returned when a workload never became ready, Reason and Message explain why
+type NotReadyError struct {
+	Kind      string
+	Namespace string
+	Name      string
+	Reason    string
+	Message   string
+}
+
+func (e *NotReadyError) Error() string {
+	return fmt.Sprintf("%s %s/%s never became ready: %s: %s", e.Kind, e.Namespace, e.Name, e.Reason, e.Message)
+}
+
+// WaitForDeployment waits until the named Deployment is Available with all of its replicas updated and ready.
+// It returns early with a *NotReadyError when the Deployment or one of its Pods reaches a terminal state, such as
+// an image that can't be pulled or pods that are denied by admission control, and when the timeout expires.
+// A timeout of zero or less uses DefaultReadinessTimeout.
+func WaitForDeployment(ctx context.Context, clientset kubernetes.Interface, namespace, name string, timeout time.Duration) error {
+	if timeout <= 0 {
+		timeout = DefaultReadinessTimeout
+	}
+
+	// status describes the last observed state of the workload, to explain a timeout
+	status := "Deployment not found"
+	var notReady *NotReadyError
+	err := wait.PollUntilContextTimeout(ctx, readinessPollInterval, timeout, true, func(ctx context.Context) (bool, error) {
+		deployment, err := clientset.AppsV1().Deployments(namespace).Get(ctx, name, metav1.GetOptions{})
+		if err != nil {
+			if apierrors.IsNotFound(err) {
+				return false, nil
+			}
+			return false, err
+		}
+
+		ready, reason, message := deploymentStatus(deployment)
+		if ready {
+			return true, nil
+		}
+		status = message
+		if reason != "" {
+			notReady = &NotReadyError{Kind: "Deployment", Namespace: namespace, Name: name, Reason: reason, Message: message}
+			return false, notReady
+		}
+
+		pods, err := clientset.CoreV1().Pods(namespace).List(ctx, metav1.ListOptions{
+			LabelSelector: metav1.FormatLabelSelector(deployment.Spec.Selector),
+		})
+		if err != nil {
+			return false, err
+		}
+		for _, pod := range pods.Items {
+			reason, message := podStatus(&pod)
+			if reason == "" {
+				if message != "" {
+					status = fmt.Sprintf("pod %s: %s", pod.Name, message)
+				}
+				continue
+			}
+			notReady = &NotReadyError{Kind: "Pod", Namespace: namespace, Name: pod.Name, Reason: reason, Message: message}
+			return false, notReady
+		}
+		return false, nil
+	})
+
+	switch {
+	case err == nil:
+		return nil
+	case notReady != nil && errors.Is(err, notReady):
+		return notReady
+	case wait.Interrupted(err) && ctx.Err() == nil:
+		return &NotReadyError{
+			Kind:      "Deployment",
+			Namespace: namespace,
+			Name:      name,
+			Reason:    ReasonTimeout,
+			Message:   fmt.Sprintf("timed out after %s, last status: %s", timeout, status),
+		}
+	default:
+		return err
+	}
+}
+
+// WaitForCronJob waits until a Job spawned by the named CronJob has run to completion. It returns early with a
+// *NotReadyError when one of its Jobs failed, its Pods are denied by admission control or reach a terminal state,
+// and when the timeout expires, which has to leave room for the schedule of the CronJob. A timeout of zero or less
+// uses DefaultReadinessTimeout.
+func WaitForCronJob(ctx context.Context, clientset kubernetes.Interface, namespace, name string, timeout time.Duration) error {
+	if timeout <= 0 {
+		timeout = DefaultReadinessTimeout
+	}
+
+	// status describes the last observed state of the workload, to explain a timeout
+	status := "no job scheduled yet"
+	var notReady *NotReadyError
+	err := wait.PollUntilContextTimeout(ctx, readinessPollInterval, timeout, true, func(ctx context.Context) (bool, error) {
+		jobs, err := CronJobJobs(ctx, clientset, namespace, name)
+		if err != nil {
+			return false, err
+		}
+		for _, job := range jobs {
+			if job.Status.Succeeded > 0 {
+				return true, nil
+			}
+		}
+
+		for _, job := range jobs {
+			reason, message, err := jobStatus(ctx, clientset, &job)
+			if err != nil {
+				return false, err
+			}
+			if reason != "" {
+				notReady = &NotReadyError{Kind: "Job", Namespace: namespace, Name: job.Name, Reason: reason, Message: message}
+				return false, notReady
+			}
+			if message != "" {
+				status = fmt.Sprintf("job %s: %s", job.Name, message)
+			}
+		}
+		return false, nil
+	})
+
+	switch {
+	case err == nil:
+		return nil
+	case notReady != nil && errors.Is(err, notReady):
+		return notReady
+	case wait.Interrupted(err) && ctx.Err() == nil:
+		return &NotReadyError{
+			Kind:      "CronJob",
+			Namespace: namespace,
+			Name:      name,
+			Reason:    ReasonTimeout,
+			Message:   fmt.Sprintf("no job completed after %s, last status: %s", timeout, status),
+		}
+	default:
+		return err
+	}
+}
+
+// WaitForPodCompletion waits until the named Pod has run to completion. It returns early with a *NotReadyError when
+// the Pod failed or reaches a terminal state, and when the timeout expires. A timeout of zero or less uses
+// DefaultReadinessTimeout.
+func WaitForPodCompletion(ctx context.Context, clientset kubernetes.Interface, namespace, name string, timeout time.Duration) error {
+	if timeout <= 0 {
+		timeout = DefaultReadinessTimeout
+	}
+
+	// status describes the last observed state of the workload, to explain a timeout
+	status := "Pod not found"
+	var notReady *NotReadyError
+	err := wait.PollUntilContextTimeout(ctx, readinessPollInterval, timeout, true, func(ctx context.Context) (bool, error) {
+		pod, err := clientset.CoreV1().Pods(namespace).Get(ctx, name, metav1.GetOptions{})
+		if err != nil {
+			if apierrors.IsNotFound(err) {
+				return false, nil
+			}
+			return false, err
+		}
+		if pod.Status.Phase == corev1.PodSucceeded {
+			return true, nil
+		}
+
+		reason, message := podStatus(pod)
+		if reason != "" {
+			notReady = &NotReadyError{Kind: "Pod", Namespace: namespace, Name: name, Reason: reason, Message: message}
+			return false, notReady
+		}
+		status = fmt.Sprintf("phase %s", pod.Status.Phase)
+		if message != "" {
+			status = message
+		}
+		return false, nil
+	})
+
+	switch {
+	case err == nil:
+		return nil
+	case notReady != nil && errors.Is(err, notReady):
+		return notReady
+	case wait.Interrupted(err) && ctx.Err() == nil:
+		return &NotReadyError{
+			Kind:      "Pod",
+			Namespace: namespace,
+			Name:      name,
+			Reason:    ReasonTimeout,
+			Message:   fmt.Sprintf("not completed after %s, last status: %s", timeout, status),
+		}
+	default:
+		return err
+	}
+}
+
+// jobStatus reports why a Job won't complete, or an empty reason along with a description of what it is
+// waiting on when it may still complete
+func jobStatus(ctx context.Context, clientset kubernetes.Interface, job *batchv1.Job) (string, string, error) {
+	for _, condition := range job.Status.Conditions {
+		if condition.Type == batchv1.JobFailed && condition.Status == corev1.ConditionTrue {
+			return ReasonPodFailed, fmt.Sprintf("%s: %s", condition.Reason, condition.Message), nil
+		}
+	}
+
+	// The Job controller only reports Pods it failed to create as events
+	events, err := clientset.CoreV1().Events(job.Namespace).List(ctx, metav1.ListOptions{})
+	if err != nil {
+		return "", "", err
+	}
+	for _, event := range events.Items {
+		if event.InvolvedObject.Kind == "Job" && event.InvolvedObject.Name == job.Name && event.Reason == "FailedCreate" {
+			return classifyCreateFailure(event.Message), event.Message, nil
+		}
+	}
+
+	pods, err := clientset.CoreV1().Pods(job.Namespace).List(ctx, metav1.ListOptions{
+		LabelSelector: metav1.FormatLabelSelector(job.Spec.Selector),
+	})
+	if err != nil {
+		return "", "", err
+	}
+	waiting := fmt.Sprintf("%d pod(s) active", job.Status.Active)
+	for _, pod := range pods.Items {
+		reason, message := podStatus(&pod)
+		if reason != "" {
+			return reason, fmt.Sprintf("pod %s: %s", pod.Name, message), nil
+		}
+		if message != "" {
+			waiting = fmt.Sprintf("pod %s: %s", pod.Name, message)
+		}
+	}
+	return "", waiting, nil
+}
+
+// deploymentStatus reports whether a Deployment is ready. When it isn't, a non-empty reason means the
+// Deployment is in a terminal state, and message describes its state either way
+func deploymentStatus(deployment *appsv1.Deployment) (bool, string, string) {
+	desired := int32(1)
+	if deployment.Spec.Replicas != nil {
+		desired = *deployment.Spec.Replicas
+	}
+
+	available := false
+	for _, condition := range deployment.Status.Conditions {
+		switch {
+		case condition.Type == appsv1.DeploymentReplicaFailure && condition.Status == corev1.ConditionTrue:
+			return false, classifyCreateFailure(condition.Message), condition.Message
+		case condition.Type == appsv1.DeploymentProgressing && condition.Status == corev1.ConditionFalse &&
+			condition.Reason == "ProgressDeadlineExceeded":
+			return false, ReasonProgressDeadline, condition.Message
+		case condition.Type == appsv1.DeploymentAvailable && condition.Status == corev1.ConditionTrue:
+			available = true
+		}
+	}
+
+	status := deployment.Status
+	if available && deployment.Status.ObservedGeneration >= deployment.Generation &&
+		status.UpdatedReplicas >= desired && status.ReadyReplicas >= desired && status.AvailableReplicas >= desired {
+		return true, "", ""
+	}
+	return false, "", fmt.Sprintf("%d of %d replicas ready", status.ReadyReplicas, desired)
+}
+
+// podStatus reports why a Pod is in a terminal state, or an empty reason along with a description
+// of what it is waiting on when it may still become ready
+func podStatus(pod *corev1.Pod) (string, string) {
+	if pod.Status.Phase == corev1.PodFailed {
+		return ReasonPodFailed, pod.Status.Message
+	}
+
+	statuses := append([]corev1.ContainerStatus{}, pod.Status.InitContainerStatuses...)
+	statuses = append(statuses, pod.Status.ContainerStatuses...)
+	waiting := ""
+	for _, status := range statuses {
+		if status.State.Waiting == nil {
+			continue
+		}
+		message := fmt.Sprintf("container %s is waiting: %s %s", status.Name, status.State.Waiting.Reason, status.State.Waiting.Message)
+		if terminalWaitingReasons[status.State.Waiting.Reason] {
+			return status.State.Waiting.Reason, strings.TrimSpace(message)
+		}
+		waiting = strings.TrimSpace(message)
+	}
+	if waiting != "" {
+		return "", waiting
+	}
+
+	for _, condition := range pod.Status.Conditions {
+		if condition.Type == corev1.PodScheduled && condition.Status == corev1.ConditionFalse {
+			return "", fmt.Sprintf("not scheduled: %s %s", condition.Reason, condition.Message)
+		}
+	}
+	return "", ""
+}
+
+// classifyCreateFailure distinguishes pods that were denied by admission control from other failures to create them
+func classifyCreateFailure(message string) string {
+	if isAdmissionDenial(message) {
+		return ReasonAdmissionDenied
+	}
+	return ReasonFailedCreate
+}
+
+// IsBlocked reports whether err means the cluster refused to carry out an action, because it was forbidden by RBAC,
+// denied by admission control, or because the pods of a workload were denied while it was waiting to become ready.
+// The user running woodpecker being denied impersonation doesn't count, as the attack was never attempted, and
+// neither do other refusals such as an exceeded ResourceQuota or a namespace being terminated.
+func IsBlocked(err error) bool {
+	if IsImpersonationDenied(err) {
+		return false
+	}
+	var notReady *NotReadyError
+	if errors.As(err, &notReady) {
+		return notReady.Reason == ReasonAdmissionDenied
+	}
+	var status apierrors.APIStatus
+	if errors.As(err, &status) {
+		message := status.Status().Message
+		return isRBACDenial(message) || isAdmissionDenial(message)
+	}
+	return false
+}
+
+// rbacDenialPattern matches the message of an API server error caused by RBAC, e.g.
+// User "jane" cannot create resource "pods" in API group "" in the namespace "default"
+var rbacDenialPattern = regexp.MustCompile(`cannot [a-z]+ resource "`)
+
+// isRBACDenial reports whether an API server error message was caused by RBAC
+func isRBACDenial(message string) bool {
+	return rbacDenialPattern.MatchString(message)
+}
+
+// isAdmissionDenial reports whether an API server error message was caused by admission control, an admission
+// webhook, PodSecurity admission or a ValidatingAdmissionPolicy. Quota errors also come from admission, but are
+// a lack of resources rather than a guardrail.
+func isAdmissionDenial(message string) bool {
+	message = strings.ToLower(message)
+	if strings.Contains(message, "exceeded quota") || strings.Contains(message, "failed quota") {
+		return false
+	}
+	for _, marker := range []string{"admission webhook", "denied the request", "violates podsecurity", "validatingadmissionpolicy"} {
+		if strings.Contains(message, marker) {
+			return true
+		}
+	}
+	return false
+}
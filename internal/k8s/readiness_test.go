@@ -0,0 +1,333 @@
+package k8s
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	appsv1 "k8s.io/api/apps/v1"
+	batchv1 "k8s.io/api/batch/v1"
+	corev1 "k8s.io/api/core/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	"k8s.io/client-go/kubernetes/fake"
+)
+
+func testDeployment(conditions ...appsv1.DeploymentCondition) *appsv1.Deployment {
+	replicas := int32(1)
+	return &appsv1.Deployment{
+		ObjectMeta: metav1.ObjectMeta{Name: "experiment", Namespace: "default"},
+		Spec: appsv1.DeploymentSpec{
+			Replicas: &replicas,
+			Selector: &metav1.LabelSelector{MatchLabels: map[string]string{"app": "experiment"}},
+		},
+		Status: appsv1.DeploymentStatus{Conditions: conditions},
+	}
+}
+
+func testPod(waitingReason string) *corev1.Pod {
+	return &corev1.Pod{
+		ObjectMeta: metav1.ObjectMeta{Name: "experiment-abc", Namespace: "default", Labels: map[string]string{"app": "experiment"}},
+		Status: corev1.PodStatus{
+			Phase: corev1.PodPending,
+			ContainerStatuses: []corev1.ContainerStatus{
+				{
+					Name:  "experiment",
+					State: corev1.ContainerState{Waiting: &corev1.ContainerStateWaiting{Reason: waitingReason}},
+				},
+			},
+		},
+	}
+}
+
+func TestWaitForDeployment(t *testing.T) {
+	readinessPollInterval = 10 * time.Millisecond
+
+	available := testDeployment(appsv1.DeploymentCondition{Type: appsv1.DeploymentAvailable, Status: corev1.ConditionTrue})
+	available.Status.UpdatedReplicas = 1
+	available.Status.ReadyReplicas = 1
+	available.Status.AvailableReplicas = 1
+
+	tests := []struct {
+		name           string
+		objects        []runtime.Object
+		expectedReason string
+	}{
+		{
+			name:    "Deployment is available",
+			objects: []runtime.Object{available},
+		},
+		{
+			name:           "Image can't be pulled",
+			objects:        []runtime.Object{testDeployment(), testPod("ImagePullBackOff")},
+			expectedReason: "ImagePullBackOff",
+		},
+		{
+			name:           "Invalid container config",
+			objects:        []runtime.Object{testDeployment(), testPod("CreateContainerConfigError")},
+			expectedReason: "CreateContainerConfigError",
+		},
+		{
+			name: "Pods denied by admission control",
+			objects: []runtime.Object{testDeployment(appsv1.DeploymentCondition{
+				Type:    appsv1.DeploymentReplicaFailure,
+				Status:  corev1.ConditionTrue,
+				Reason:  "FailedCreate",
+				Message: `pods "experiment-abc" is forbidden: violates PodSecurity "restricted:latest": privileged`,
+			})},
+			expectedReason: ReasonAdmissionDenied,
+		},
+		{
+			name: "Progress deadline exceeded",
+			objects: []runtime.Object{testDeployment(appsv1.DeploymentCondition{
+				Type:   appsv1.DeploymentProgressing,
+				Status: corev1.ConditionFalse,
+				Reason: "ProgressDeadlineExceeded",
+			})},
+			expectedReason: ReasonProgressDeadline,
+		},
+		{
+			name:           "Container still being created",
+			objects:        []runtime.Object{testDeployment(), testPod("ContainerCreating")},
+			expectedReason: ReasonTimeout,
+		},
+		{
+			name:           "Deployment doesn't exist",
+			expectedReason: ReasonTimeout,
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			clientset := fake.NewSimpleClientset(test.objects...)
+			err := WaitForDeployment(context.Background(), clientset, "default", "experiment", 50*time.Millisecond)
+			if test.expectedReason == "" {
+				assert.NoError(t, err)
+				return
+			}
+
+			var notReady *NotReadyError
+			require.True(t, errors.As(err, &notReady), "expected a NotReadyError, got %v", err)
+			assert.Equal(t, test.expectedReason, notReady.Reason)
+		})
+	}
+}
+
+func testJob(status batchv1.JobStatus) *batchv1.Job {
+	controller := true
+	return &batchv1.Job{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      "experiment-123",
+			Namespace: "default",
+			OwnerReferences: []metav1.OwnerReference{
+				{APIVersion: "batch/v1", Kind: "CronJob", Name: "experiment", Controller: &controller},
+			},
+		},
+		Spec: batchv1.JobSpec{
+			Selector: &metav1.LabelSelector{MatchLabels: map[string]string{"app": "experiment"}},
+		},
+		Status: status,
+	}
+}
+
+func TestWaitForCronJob(t *testing.T) {
+	readinessPollInterval = 10 * time.Millisecond
+
+	tests := []struct {
+		name           string
+		objects        []runtime.Object
+		expectedReason string
+	}{
+		{
+			name:    "Job completed",
+			objects: []runtime.Object{testJob(batchv1.JobStatus{Succeeded: 1})},
+		},
+		{
+			name: "Job failed",
+			objects: []runtime.Object{testJob(batchv1.JobStatus{Conditions: []batchv1.JobCondition{
+				{Type: batchv1.JobFailed, Status: corev1.ConditionTrue, Reason: "BackoffLimitExceeded"},
+			}})},
+			expectedReason: ReasonPodFailed,
+		},
+		{
+			name: "Pods denied by admission control",
+			objects: []runtime.Object{
+				testJob(batchv1.JobStatus{}),
+				&corev1.Event{
+					ObjectMeta:     metav1.ObjectMeta{Name: "experiment-123.1", Namespace: "default"},
+					InvolvedObject: corev1.ObjectReference{Kind: "Job", Name: "experiment-123"},
+					Reason:         "FailedCreate",
+					Message:        `Error creating: admission webhook "validate.kyverno.svc" denied the request`,
+				},
+			},
+			expectedReason: ReasonAdmissionDenied,
+		},
+		{
+			name:           "Image can't be pulled",
+			objects:        []runtime.Object{testJob(batchv1.JobStatus{Active: 1}), testPod("ErrImagePull")},
+			expectedReason: "ErrImagePull",
+		},
+		{
+			name:           "No job scheduled",
+			expectedReason: ReasonTimeout,
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			clientset := fake.NewSimpleClientset(test.objects...)
+			err := WaitForCronJob(context.Background(), clientset, "default", "experiment", 50*time.Millisecond)
+			if test.expectedReason == "" {
+				assert.NoError(t, err)
+				return
+			}
+
+			var notReady *NotReadyError
+			require.True(t, errors.As(err, &notReady), "expected a NotReadyError, got %v", err)
+			assert.Equal(t, test.expectedReason, notReady.Reason)
+		})
+	}
+}
+
+func TestWaitForPodCompletion(t *testing.T) {
+	readinessPollInterval = 10 * time.Millisecond
+
+	succeeded := testPod("")
+	succeeded.Status = corev1.PodStatus{Phase: corev1.PodSucceeded}
+	failed := testPod("")
+	failed.Status = corev1.PodStatus{Phase: corev1.PodFailed, Message: "exit code 1"}
+
+	tests := []struct {
+		name           string
+		objects        []runtime.Object
+		expectedReason string
+	}{
+		{
+			name:    "Pod completed",
+			objects: []runtime.Object{succeeded},
+		},
+		{
+			name:           "Pod failed",
+			objects:        []runtime.Object{failed},
+			expectedReason: ReasonPodFailed,
+		},
+		{
+			name:           "Image can't be pulled",
+			objects:        []runtime.Object{testPod("ErrImagePull")},
+			expectedReason: "ErrImagePull",
+		},
+		{
+			name:           "Container still being created",
+			objects:        []runtime.Object{testPod("ContainerCreating")},
+			expectedReason: ReasonTimeout,
+		},
+		{
+			name:           "Pod doesn't exist",
+			expectedReason: ReasonTimeout,
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			clientset := fake.NewSimpleClientset(test.objects...)
+			err := WaitForPodCompletion(context.Background(), clientset, "default", "experiment-abc", 50*time.Millisecond)
+			if test.expectedReason == "" {
+				assert.NoError(t, err)
+				return
+			}
+
+			var notReady *NotReadyError
+			require.True(t, errors.As(err, &notReady), "expected a NotReadyError, got %v", err)
+			assert.Equal(t, test.expectedReason, notReady.Reason)
+		})
+	}
+}
+
+func TestWaitForDeploymentCancelled(t *testing.T) {
+	readinessPollInterval = 10 * time.Millisecond
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	err := WaitForDeployment(ctx, fake.NewSimpleClientset(), "default", "experiment", time.Second)
+
+	var notReady *NotReadyError
+	assert.Error(t, err)
+	assert.False(t, errors.As(err, &notReady))
+}
+
+func TestIsBlocked(t *testing.T) {
+	deployments := schema.GroupResource{Group: "apps", Resource: "deployments"}
+	tests := []struct {
+		name     string
+		err      error
+		expected bool
+	}{
+		{
+			name:     "Forbidden by RBAC",
+			err:      apierrors.NewForbidden(deployments, "experiment", errors.New(`User "dev" cannot create resource "deployments" in API group "apps" in the namespace "default"`)),
+			expected: true,
+		},
+		{
+			name:     "Denied by PodSecurity admission",
+			err:      apierrors.NewForbidden(schema.GroupResource{Resource: "pods"}, "experiment", errors.New(`violates PodSecurity "baseline:latest": privileged`)),
+			expected: true,
+		},
+		{
+			name:     "Denied by a ValidatingAdmissionPolicy",
+			err:      apierrors.NewForbidden(deployments, "experiment", errors.New(`ValidatingAdmissionPolicy 'no-privileged' with binding 'no-privileged' denied request`)),
+			expected: true,
+		},
+		{
+			name:     "ResourceQuota exceeded",
+			err:      apierrors.NewForbidden(deployments, "experiment", errors.New("exceeded quota: compute, requested: pods=1, used: pods=10, limited: pods=10")),
+			expected: false,
+		},
+		{
+			name:     "Namespace being terminated",
+			err:      apierrors.NewForbidden(deployments, "experiment", errors.New("unable to create new content in namespace experiments because it is being terminated")),
+			expected: false,
+		},
+		{
+			name:     "Impersonation denied to the user running woodpecker",
+			err:      apierrors.NewForbidden(schema.GroupResource{Resource: "serviceaccounts"}, "default", errors.New(`User "dev" cannot impersonate resource "serviceaccounts"`)),
+			expected: false,
+		},
+		{
+			name:     "Denied by an admission webhook",
+			err:      apierrors.NewBadRequest(`admission webhook "policy.example.com" denied the request: privileged containers are not allowed`),
+			expected: true,
+		},
+		{
+			name:     "Pods denied while waiting for readiness",
+			err:      &NotReadyError{Reason: ReasonAdmissionDenied},
+			expected: true,
+		},
+		{
+			name:     "Image can't be pulled",
+			err:      &NotReadyError{Reason: "ImagePullBackOff"},
+			expected: false,
+		},
+		{
+			name:     "Already exists",
+			err:      apierrors.NewAlreadyExists(deployments, "experiment"),
+			expected: false,
+		},
+		{
+			name:     "Other error",
+			err:      errors.New("connection refused"),
+			expected: false,
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			assert.Equal(t, test.expected, IsBlocked(test.err))
+		})
+	}
+}
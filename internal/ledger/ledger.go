@@ -0,0 +1,337 @@
+/*
+Copyright 2023 Operant AI
+*/
+package ledger
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+)
+
+// Version is the version of the on-disk run format written by this package
+const Version = 1
+
+// State is the lifecycle state of an experiment within a run
+type State string
+
+const (
+	Pending   State = "pending"
+	Running   State = "running"
+	Succeeded State = "succeeded"
+	Failed    State = "failed"
+	Blocked   State = "blocked"
+	Cleaned   State = "cleaned"
+)
+
+// ErrNoRuns is returned when the ledger doesn't hold any runs yet
+var ErrNoRuns = errors.New("no runs found, run experiments with woodpecker experiment run first")
+
+// Store persists runs as JSON files in a directory, one file per run
+type Store struct {
+	dir string
+}
+
+// Run is the record of a single woodpecker experiment run invocation
+type Run struct {
+	Version     int                    `json:"version"`
+	ID          string                 `json:"id"`
+	StartedAt   time.Time              `json:"startedAt"`
+	FinishedAt  *time.Time             `json:"finishedAt,omitempty"`
+	Experiments map[string]*Experiment `json:"experiments"`
+
+	mu    sync.Mutex
+	store *Store
+}
+
+// Experiment is the record of a single experiment within a Run
+type Experiment struct {
+	Type       string            `json:"type"`
+	State      State             `json:"state"`
+	StartedAt  *time.Time        `json:"startedAt,omitempty"`
+	FinishedAt *time.Time        `json:"finishedAt,omitempty"`
+	Error      string            `json:"error,omitempty"`
+	Results    []json.RawMessage `json:"results,omitempty"`
+	// Outcome is the latest verified outcome of the experiment
+	Outcome json.RawMessage `json:"outcome,omitempty"`
+}
+
+// DefaultDir returns the directory runs are stored in, $WOODPECKER_HOME/runs if set, or ~/.woodpecker/runs
+func DefaultDir() (string, error) {
+	if home, ok := os.LookupEnv("WOODPECKER_HOME"); ok && home != "" {
+		return filepath.Join(home, "runs"), nil
+	}
+	home, err := os.UserHomeDir()
+	if err != nil {
+		return "", fmt.Errorf("Could not determine the run ledger directory: %w", err)
+	}
+	return filepath.Join(home, ".woodpecker", "runs"), nil
+}
+
+// NewStore returns a Store which keeps runs in dir
+func NewStore(dir string) *Store {
+	return &Store{dir: dir}
+}
+
+// newRunID returns a run ID for a run started at the given time, IDs sort chronologically
+func newRunID(startedAt time.Time) (string, error) {
+	suffix := make([]byte, 3)
+	if _, err := rand.Read(suffix); err != nil {
+		return "", err
+	}
+	return fmt.Sprintf("%s-%s", startedAt.Format("20060102T150405.000000Z"), hex.EncodeToString(suffix)), nil
+}
+
+// Create starts a new run for the given experiments, keyed by name with their type as the value
+func (s *Store) Create(experiments map[string]string) (*Run, error) {
+	startedAt := time.Now().UTC()
+	id, err := newRunID(startedAt)
+	if err != nil {
+		return nil, fmt.Errorf("Could not generate run ID: %w", err)
+	}
+	run := &Run{
+		Version:     Version,
+		ID:          id,
+		StartedAt:   startedAt,
+		Experiments: make(map[string]*Experiment),
+		store:       s,
+	}
+	for name, experimentType := range experiments {
+		run.Experiments[name] = &Experiment{
+			Type:  experimentType,
+			State: Pending,
+		}
+	}
+	if err := run.save(); err != nil {
+		return nil, err
+	}
+	return run, nil
+}
+
+// Load loads the run with the given ID
+func (s *Store) Load(id string) (*Run, error) {
+	contents, err := os.ReadFile(s.path(id))
+	if err != nil {
+		if os.IsNotExist(err) {
+			return nil, fmt.Errorf("Run %s not found", id)
+		}
+		return nil, err
+	}
+	run := &Run{store: s}
+	if err := json.Unmarshal(contents, run); err != nil {
+		return nil, fmt.Errorf("Could not parse run %s: %w", id, err)
+	}
+	switch {
+	case run.Version == 0:
+		return nil, fmt.Errorf("Run %s is missing a format version", id)
+	case run.Version > Version:
+		return nil, fmt.Errorf("Run %s uses format version %d, this version of woodpecker supports up to %d", id, run.Version, Version)
+	}
+	if run.Experiments == nil {
+		run.Experiments = make(map[string]*Experiment)
+	}
+	return run, nil
+}
+
+// IDs returns the IDs of all stored runs, oldest first
+func (s *Store) IDs() ([]string, error) {
+	entries, err := os.ReadDir(s.dir)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return nil, nil
+		}
+		return nil, err
+	}
+	var ids []string
+	for _, entry := range entries {
+		if !entry.IsDir() && strings.HasSuffix(entry.Name(), ".json") {
+			ids = append(ids, strings.TrimSuffix(entry.Name(), ".json"))
+		}
+	}
+	sort.Strings(ids)
+	return ids, nil
+}
+
+// Latest loads the most recently started run
+func (s *Store) Latest() (*Run, error) {
+	ids, err := s.IDs()
+	if err != nil {
+		return nil, err
+	}
+	if len(ids) == 0 {
+		return nil, ErrNoRuns
+	}
+	return s.Load(ids[len(ids)-1])
+}
+
+// Open loads the run with the given ID, or the latest run if id is empty
+func (s *Store) Open(id string) (*Run, error) {
+	if id == "" {
+		return s.Latest()
+	}
+	return s.Load(id)
+}
+
+func (s *Store) path(id string) string {
+	return filepath.Join(s.dir, id+".json")
+}
+
+// save atomically writes the run to disk, callers must hold r.mu or own the run exclusively
+func (r *Run) save() error {
+	if err := os.MkdirAll(r.store.dir, 0700); err != nil {
+		return fmt.Errorf("Could not create run ledger directory: %w", err)
+	}
+	contents, err := json.MarshalIndent(r, "", "  ")
+	if err != nil {
+		return fmt.Errorf("Failed to marshal run %s: %w", r.ID, err)
+	}
+	tmp, err := os.CreateTemp(r.store.dir, r.ID+".*.tmp")
+	if err != nil {
+		return fmt.Errorf("Could not write run %s: %w", r.ID, err)
+	}
+	defer os.Remove(tmp.Name())
+	if _, err := tmp.Write(contents); err != nil {
+		tmp.Close()
+		return fmt.Errorf("Could not write run %s: %w", r.ID, err)
+	}
+	if err := tmp.Close(); err != nil {
+		return fmt.Errorf("Could not write run %s: %w", r.ID, err)
+	}
+	return os.Rename(tmp.Name(), r.store.path(r.ID))
+}
+
+// experiment returns the record for the named experiment, adding it if the run doesn't know about it yet
+func (r *Run) experiment(name string) *Experiment {
+	e, ok := r.Experiments[name]
+	if !ok {
+		e = &Experiment{State: Pending}
+		r.Experiments[name] = e
+	}
+	return e
+}
+
+// SetState moves the named experiment to the given state, recording err if it is not nil
+func (r *Run) SetState(name string, state State, err error) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	now := time.Now().UTC()
+	e := r.experiment(name)
+	e.State = state
+	switch state {
+	case Running:
+		e.StartedAt = &now
+		e.FinishedAt = nil
+		e.Error = ""
+	case Succeeded, Failed, Blocked:
+		e.FinishedAt = &now
+	}
+	if err != nil {
+		e.Error = err.Error()
+	}
+	return r.save()
+}
+
+// AddResult records a raw result payload for the named experiment
+func (r *Run) AddResult(name string, result []byte) error {
+	if !json.Valid(result) {
+		return fmt.Errorf("Result for experiment %s is not valid JSON", name)
+	}
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	e := r.experiment(name)
+	e.Results = append(e.Results, json.RawMessage(result))
+	return r.save()
+}
+
+// Results returns the raw result payloads recorded for the named experiment
+func (r *Run) Results(name string) [][]byte {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	var results [][]byte
+	if e, ok := r.Experiments[name]; ok {
+		for _, result := range e.Results {
+			results = append(results, []byte(result))
+		}
+	}
+	return results
+}
+
+// SetOutcome records the verified outcome of the named experiment, replacing any earlier one
+func (r *Run) SetOutcome(name string, outcome []byte) error {
+	if !json.Valid(outcome) {
+		return fmt.Errorf("Outcome for experiment %s is not valid JSON", name)
+	}
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	e := r.experiment(name)
+	e.Outcome = json.RawMessage(outcome)
+	return r.save()
+}
+
+// Outcome returns the verified outcome recorded for the named experiment, or nil if it wasn't verified
+func (r *Run) Outcome(name string) []byte {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if e, ok := r.Experiments[name]; ok && len(e.Outcome) > 0 {
+		return []byte(e.Outcome)
+	}
+	return nil
+}
+
+// State returns the state of the named experiment, and whether it is part of the run
+func (r *Run) State(name string) (State, bool) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	e, ok := r.Experiments[name]
+	if !ok {
+		return "", false
+	}
+	return e.State, true
+}
+
+// Has reports whether the named experiment is part of the run
+func (r *Run) Has(name string) bool {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	_, ok := r.Experiments[name]
+	return ok
+}
+
+// Finish marks the run as finished
+func (r *Run) Finish() error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	now := time.Now().UTC()
+	r.FinishedAt = &now
+	return r.save()
+}
+
+type contextKey struct{}
+
+// NewContext returns a copy of ctx carrying run
+func NewContext(ctx context.Context, run *Run) context.Context {
+	return context.WithValue(ctx, contextKey{}, run)
+}
+
+// FromContext returns the run carried by ctx, if any
+func FromContext(ctx context.Context) (*Run, bool) {
+	run, ok := ctx.Value(contextKey{}).(*Run)
+	return run, ok
+}
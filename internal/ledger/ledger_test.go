@@ -0,0 +1,137 @@
+package ledger
+
+import (
+	"context"
+	"errors"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestRunLifecycle(t *testing.T) {
+	store := NewStore(t.TempDir())
+
+	run, err := store.Create(map[string]string{"kube-exec": "kube-exec"})
+	require.NoError(t, err)
+	assert.Equal(t, Pending, run.Experiments["kube-exec"].State)
+
+	require.NoError(t, run.SetState("kube-exec", Running, nil))
+	require.NoError(t, run.AddResult("kube-exec", []byte(`{"stdout":"root:x:0:0"}`)))
+	require.NoError(t, run.SetState("kube-exec", Failed, errors.New("exec failed")))
+	require.NoError(t, run.Finish())
+
+	loaded, err := store.Load(run.ID)
+	require.NoError(t, err)
+	assert.Equal(t, Version, loaded.Version)
+	assert.NotNil(t, loaded.FinishedAt)
+
+	experiment := loaded.Experiments["kube-exec"]
+	assert.Equal(t, "kube-exec", experiment.Type)
+	assert.Equal(t, Failed, experiment.State)
+	assert.Equal(t, "exec failed", experiment.Error)
+	assert.NotNil(t, experiment.StartedAt)
+	assert.NotNil(t, experiment.FinishedAt)
+	results := loaded.Results("kube-exec")
+	require.Len(t, results, 1)
+	assert.JSONEq(t, `{"stdout":"root:x:0:0"}`, string(results[0]))
+
+	// Results of experiments with a shared name prefix don't leak into each other
+	assert.Empty(t, loaded.Results("kube"))
+}
+
+func TestAddResultRejectsInvalidJSON(t *testing.T) {
+	store := NewStore(t.TempDir())
+	run, err := store.Create(nil)
+	require.NoError(t, err)
+
+	assert.Error(t, run.AddResult("kube-exec", []byte("not json")))
+}
+
+func TestSetOutcome(t *testing.T) {
+	store := NewStore(t.TempDir())
+	run, err := store.Create(map[string]string{"kube-exec": "kube-exec"})
+	require.NoError(t, err)
+	assert.Nil(t, run.Outcome("kube-exec"))
+
+	// Verifying again replaces the earlier outcome
+	require.NoError(t, run.SetOutcome("kube-exec", []byte(`{"verdict":"fail"}`)))
+	require.NoError(t, run.SetOutcome("kube-exec", []byte(`{"verdict":"pass"}`)))
+	assert.Error(t, run.SetOutcome("kube-exec", []byte("not json")))
+
+	loaded, err := store.Load(run.ID)
+	require.NoError(t, err)
+	assert.JSONEq(t, `{"verdict":"pass"}`, string(loaded.Outcome("kube-exec")))
+}
+
+func TestOpen(t *testing.T) {
+	store := NewStore(t.TempDir())
+
+	_, err := store.Open("")
+	assert.ErrorIs(t, err, ErrNoRuns)
+
+	first, err := store.Create(nil)
+	require.NoError(t, err)
+	second, err := store.Create(nil)
+	require.NoError(t, err)
+
+	latest, err := store.Open("")
+	require.NoError(t, err)
+	assert.Equal(t, second.ID, latest.ID)
+
+	selected, err := store.Open(first.ID)
+	require.NoError(t, err)
+	assert.Equal(t, first.ID, selected.ID)
+
+	_, err = store.Open("does-not-exist")
+	assert.Error(t, err)
+}
+
+func TestLoadVersions(t *testing.T) {
+	tests := []struct {
+		name        string
+		contents    string
+		expectError bool
+	}{
+		{
+			name:     "Current version",
+			contents: `{"version": 1, "id": "run", "experiments": {}}`,
+		},
+		{
+			name:        "Missing version",
+			contents:    `{"id": "run", "experiments": {}}`,
+			expectError: true,
+		},
+		{
+			name:        "Newer version",
+			contents:    `{"version": 99, "id": "run", "experiments": {}}`,
+			expectError: true,
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			dir := t.TempDir()
+			require.NoError(t, os.WriteFile(filepath.Join(dir, "run.json"), []byte(test.contents), 0600))
+
+			_, err := NewStore(dir).Load("run")
+			if test.expectError {
+				assert.Error(t, err)
+			} else {
+				assert.NoError(t, err)
+			}
+		})
+	}
+}
+
+func TestContext(t *testing.T) {
+	_, ok := FromContext(context.Background())
+	assert.False(t, ok)
+
+	run := &Run{ID: "run"}
+	fromContext, ok := FromContext(NewContext(context.Background(), run))
+	assert.True(t, ok)
+	assert.Equal(t, run, fromContext)
+}
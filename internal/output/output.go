@@ -1,9 +1,13 @@
 package output
 
 import (
+	"bytes"
 	"encoding/json"
+	"encoding/xml"
 	"fmt"
+	"io"
 	"os"
+	"sync"
 
 	"github.com/charmbracelet/lipgloss"
 	ltable "github.com/charmbracelet/lipgloss/table"
@@ -20,32 +24,89 @@ const (
 	FatalColor   = lipgloss.Color("196")
 )
 
+// stderr serialises writes to os.Stderr so that concurrent writers don't interleave. Log lines go to stderr so
+// that documents written to stdout, such as -o json, stay valid when redirected.
+var stderr = &lockedWriter{w: os.Stderr}
+
+type lockedWriter struct {
+	mu sync.Mutex
+	w  io.Writer
+}
+
+func (l *lockedWriter) Write(p []byte) (int, error) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	return l.w.Write(p)
+}
+
+// Logger writes styled messages to an io.Writer
+type Logger struct {
+	w io.Writer
+}
+
+// NewLogger returns a Logger that writes to w
+func NewLogger(w io.Writer) *Logger {
+	return &Logger{w: w}
+}
+
+// Flush writes the contents of b to stderr in a single write, so output buffered
+// by a Logger is never interleaved with output from other goroutines
+func Flush(b *bytes.Buffer) {
+	if b.Len() == 0 {
+		return
+	}
+	_, _ = stderr.Write(b.Bytes())
+	b.Reset()
+}
+
+func (l *Logger) write(color lipgloss.Color, label, msg string, args ...interface{}) {
+	style := lipgloss.NewStyle().Foreground(color)
+	fmt.Fprintf(l.w, "%s %s\n", style.Render(label), fmt.Sprintf(msg, args...))
+}
+
+func (l *Logger) WriteInfo(msg string, args ...interface{}) {
+	l.write(InfoColor, "INFO", msg, args...)
+}
+
+func (l *Logger) WriteSuccess(msg string, args ...interface{}) {
+	l.write(SuccessColor, "SUCCESS", msg, args...)
+}
+
+func (l *Logger) WriteWarning(msg string, args ...interface{}) {
+	l.write(WarningColor, "WARN", msg, args...)
+}
+
+func (l *Logger) WriteError(msg string, args ...interface{}) {
+	l.write(ErrorColor, "ERROR", msg, args...)
+}
+
+var std = NewLogger(stderr)
+
 func WriteInfo(msg string, args ...interface{}) {
-	style := lipgloss.NewStyle().Foreground(InfoColor)
-	fmt.Printf("%s %s\n", style.Render("INFO"), fmt.Sprintf(msg, args...))
+	std.WriteInfo(msg, args...)
 }
 
 func WriteSuccess(msg string, args ...interface{}) {
-	style := lipgloss.NewStyle().Foreground(SuccessColor)
-	fmt.Printf("%s %s\n", style.Render("SUCCESS"), fmt.Sprintf(msg, args...))
+	std.WriteSuccess(msg, args...)
 }
 
 func WriteWarning(msg string, args ...interface{}) {
-	style := lipgloss.NewStyle().Foreground(WarningColor)
-	fmt.Printf("%s %s\n", style.Render("WARN"), fmt.Sprintf(msg, args...))
+	std.WriteWarning(msg, args...)
 }
 
 func WriteError(msg string, args ...interface{}) {
-	style := lipgloss.NewStyle().Foreground(ErrorColor)
-	fmt.Printf("%s %s\n", style.Render("ERROR"), fmt.Sprintf(msg, args...))
+	std.WriteError(msg, args...)
 }
 
+// WriteFatal writes an error and exits with status 2, the status for errors that stop woodpecker from doing its job
 func WriteFatal(msg string, args ...interface{}) {
-	style := lipgloss.NewStyle().Foreground(ErrorColor)
-	fmt.Printf("%s %s\n", style.Render("FATAL"), fmt.Sprintf(msg, args...))
-	os.Exit(1)
+	std.write(FatalColor, "FATAL", msg, args...)
+	os.Exit(2)
 }
 
+// defaultWidth is the width tables are rendered at when stdout isn't a terminal
+const defaultWidth = 160
+
 type table struct {
 	headers []string
 	rows    [][]string
@@ -65,8 +126,8 @@ func (t *table) AddRow(row []string) {
 func (t *table) Render() {
 	physicalWidth, _, err := term.GetSize(int(os.Stdout.Fd()))
 	if err != nil {
-		fmt.Println("Error getting terminal size:", err)
-		return
+		// Not a terminal, e.g. output redirected in CI
+		physicalWidth = defaultWidth
 	}
 
 	// Calculate max width for each column
@@ -123,3 +184,12 @@ func WriteYAML(output interface{}) {
 	}
 	fmt.Println(string(yamlOutput))
 }
+
+// WriteXML writes the given output as a pretty printed XML document to stdout
+func WriteXML(output interface{}) {
+	xmlOutput, err := xml.MarshalIndent(output, "", "    ")
+	if err != nil {
+		WriteError("Failed to marshal XML: %s", err)
+	}
+	fmt.Println(xml.Header + string(xmlOutput))
+}
@@ -0,0 +1,213 @@
+/*
+Copyright 2023 Operant AI
+*/
+package report
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"sort"
+	"strings"
+
+	"github.com/operantai/woodpecker/internal/output"
+	"github.com/operantai/woodpecker/internal/verifier"
+)
+
+// ChangeKind is how a test changed between two reports
+type ChangeKind string
+
+const (
+	// Regression is a test that got weaker, it missed an expectation it met before, or the attack got further
+	Regression ChangeKind = "regression"
+	// Improvement is a test that got stronger, it meets an expectation it missed before, or the attack got less far
+	Improvement ChangeKind = "improvement"
+	// Changed is a test whose observed behavior changed while it kept meeting or missing its expectation
+	Changed ChangeKind = "changed"
+	Added   ChangeKind = "added"
+	Removed ChangeKind = "removed"
+)
+
+// TestState is the state of a single test of an experiment in a report, the verdict is empty for results
+// saved before experiments declared an expectation
+type TestState struct {
+	Observed verifier.Behavior `json:"observed,omitempty" yaml:"observed,omitempty"`
+	Verdict  verifier.Verdict  `json:"verdict,omitempty" yaml:"verdict,omitempty"`
+}
+
+// Change is a test whose state differs between two reports
+type Change struct {
+	Experiment string     `json:"experiment" yaml:"experiment"`
+	Test       string     `json:"test" yaml:"test"`
+	Kind       ChangeKind `json:"kind" yaml:"kind"`
+	Base       *TestState `json:"base,omitempty" yaml:"base,omitempty"`
+	Head       *TestState `json:"head,omitempty" yaml:"head,omitempty"`
+}
+
+// Diff holds the tests that changed between a base and a head report
+type Diff struct {
+	Changes []Change `json:"changes" yaml:"changes"`
+	// Regressions counts the tests that regressed, and the added tests that miss their expectation
+	Regressions int `json:"regressions" yaml:"regressions"`
+}
+
+// Load reads a report saved with verify -o json, which also reads results saved before reports held a
+// summary and verdicts
+func Load(path string) (*Report, error) {
+	contents, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("Could not read report %s: %w", path, err)
+	}
+	r := &Report{}
+	if err := json.Unmarshal(contents, r); err != nil {
+		return nil, fmt.Errorf("Could not parse report %s: %w", path, err)
+	}
+	return r, nil
+}
+
+// behaviorStrength ranks behaviors by how far the attack got, the lower the better defended. An attack that
+// wasn't observed didn't get through either, although nothing refused it.
+var behaviorStrength = map[verifier.Behavior]int{
+	"":                0,
+	verifier.Blocked:  0,
+	verifier.Detected: 1,
+	verifier.Allowed:  2,
+}
+
+// Compare returns the tests that changed between the base and head reports, sorted by experiment and test
+func Compare(base, head *Report) *Diff {
+	baseStates := testStates(base)
+	headStates := testStates(head)
+
+	d := &Diff{Changes: []Change{}}
+	for key, b := range baseStates {
+		h, ok := headStates[key]
+		if !ok {
+			d.Changes = append(d.Changes, Change{Experiment: key.experiment, Test: key.test, Kind: Removed, Base: &b})
+			continue
+		}
+		if unchanged(b, h) {
+			continue
+		}
+		d.Changes = append(d.Changes, Change{Experiment: key.experiment, Test: key.test, Kind: changeKind(b, h), Base: &b, Head: &h})
+	}
+	for key, h := range headStates {
+		if _, ok := baseStates[key]; !ok {
+			d.Changes = append(d.Changes, Change{Experiment: key.experiment, Test: key.test, Kind: Added, Head: &h})
+		}
+	}
+
+	sort.Slice(d.Changes, func(i, j int) bool {
+		if d.Changes[i].Experiment != d.Changes[j].Experiment {
+			return d.Changes[i].Experiment < d.Changes[j].Experiment
+		}
+		return d.Changes[i].Test < d.Changes[j].Test
+	})
+	for _, c := range d.Changes {
+		if c.regressed() {
+			d.Regressions++
+		}
+	}
+	return d
+}
+
+// regressed reports whether a change counts as a regression, which includes new tests that miss their expectation
+func (c Change) regressed() bool {
+	return c.Kind == Regression || (c.Kind == Added && c.Head.Verdict == verifier.Failed)
+}
+
+type testKey struct {
+	experiment, test string
+}
+
+// testStates returns the state of every test in a report
+func testStates(r *Report) map[testKey]TestState {
+	states := make(map[testKey]TestState)
+	for _, outcome := range r.Results {
+		for _, test := range testNames(outcome) {
+			state := TestState{Observed: testObserved(outcome, test)}
+			if outcome.Verdict != "" {
+				state.Verdict = testVerdict(outcome, test)
+			}
+			states[testKey{outcome.Experiment, test}] = state
+		}
+	}
+	return states
+}
+
+// unchanged reports whether a test is in the same state in both reports, only comparing what was observed
+// when one of them has no verdicts
+func unchanged(base, head TestState) bool {
+	if base.Verdict == "" || head.Verdict == "" {
+		return base.Observed == head.Observed
+	}
+	return base == head
+}
+
+// changeKind classifies the change of a test. Verdicts decide when both reports have them, otherwise a change
+// is judged by how far the attack got.
+func changeKind(base, head TestState) ChangeKind {
+	if base.Verdict != "" && head.Verdict != "" {
+		// Accepted risks count as meeting their expectation
+		baseMet := base.Verdict == verifier.Pass || base.Verdict == verifier.Suppressed
+		headMet := head.Verdict == verifier.Pass || head.Verdict == verifier.Suppressed
+		switch {
+		case baseMet && !headMet:
+			return Regression
+		case !baseMet && headMet:
+			return Improvement
+		}
+		return Changed
+	}
+
+	baseStrength, baseKnown := behaviorStrength[base.Observed]
+	headStrength, headKnown := behaviorStrength[head.Observed]
+	switch {
+	case !baseKnown || !headKnown:
+		return Changed
+	case headStrength > baseStrength:
+		return Regression
+	case headStrength < baseStrength:
+		return Improvement
+	}
+	return Changed
+}
+
+// WriteDiff writes the diff to stdout in the given format, a table followed by a summary if the format is empty
+func WriteDiff(d *Diff, format string) error {
+	switch strings.ToLower(format) {
+	case FormatJSON:
+		output.WriteJSON(d)
+	case FormatYAML:
+		output.WriteYAML(d)
+	case "", FormatTable:
+		if len(d.Changes) == 0 {
+			output.WriteSuccess("No tests changed")
+			return nil
+		}
+		table := output.NewTable([]string{"Experiment", "Test", "Base", "Head", "Change"})
+		for _, c := range d.Changes {
+			table.AddRow([]string{c.Experiment, c.Test, c.Base.String(), c.Head.String(), string(c.Kind)})
+		}
+		table.Render()
+		if d.Regressions > 0 {
+			output.WriteWarning("%d test(s) regressed", d.Regressions)
+		} else {
+			output.WriteSuccess("No tests regressed")
+		}
+	default:
+		return fmt.Errorf("Unknown output format: %s", format)
+	}
+	return nil
+}
+
+// String describes the state of a test, or a dash if the test isn't part of the report
+func (s *TestState) String() string {
+	if s == nil {
+		return "-"
+	}
+	if s.Verdict == "" {
+		return observedText(s.Observed)
+	}
+	return fmt.Sprintf("%s (%s)", observedText(s.Observed), s.Verdict)
+}
@@ -0,0 +1,96 @@
+package report
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/operantai/woodpecker/internal/verifier"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// evaluated returns the outcome of an experiment with the given test results, evaluated against expect
+func evaluated(experiment string, expect verifier.Behavior, results map[string]string) *verifier.LegacyOutcome {
+	outcome := verifier.NewLegacy(experiment, "Experiment", "MITRE", "Tactic", "Technique").GetOutcome()
+	for test, result := range results {
+		outcome.Result[test] = result
+	}
+	outcome.Evaluate(expect)
+	return outcome
+}
+
+func TestCompare(t *testing.T) {
+	base := New("base", []*verifier.LegacyOutcome{
+		evaluated("privileged", verifier.Blocked, map[string]string{"hostPid": verifier.BlockedResult, "hostNetwork": verifier.BlockedResult}),
+		evaluated("kube-exec", verifier.Blocked, map[string]string{"kube-exec": verifier.Success}),
+		evaluated("leakage", verifier.Detected, map[string]string{"ssn": verifier.Success, "email": verifier.DetectedResult}),
+		evaluated("removed", verifier.Allowed, map[string]string{"test": verifier.Success}),
+	}, nil)
+	head := New("head", []*verifier.LegacyOutcome{
+		evaluated("privileged", verifier.Blocked, map[string]string{"hostPid": verifier.Success, "hostNetwork": verifier.BlockedResult}),
+		evaluated("kube-exec", verifier.Blocked, map[string]string{"kube-exec": verifier.BlockedResult}),
+		evaluated("leakage", verifier.Detected, map[string]string{"ssn": verifier.DetectedResult, "email": verifier.BlockedResult}),
+		evaluated("added", verifier.Allowed, map[string]string{"test": verifier.Success}),
+		evaluated("added-failing", verifier.Blocked, map[string]string{"test": verifier.Success}),
+	}, nil)
+
+	d := Compare(base, head)
+	var changes []string
+	for _, c := range d.Changes {
+		changes = append(changes, c.Experiment+"/"+c.Test+": "+string(c.Kind))
+	}
+	assert.Equal(t, []string{
+		"added/test: added",
+		"added-failing/test: added",
+		"kube-exec/kube-exec: improvement",
+		"leakage/email: changed",
+		"leakage/ssn: improvement",
+		"privileged/hostPid: regression",
+		"removed/test: removed",
+	}, changes)
+	// The added test that misses its expectation counts as a regression, the one that meets it doesn't
+	assert.Equal(t, 2, d.Regressions)
+
+	assert.Empty(t, Compare(head, head).Changes)
+}
+
+func TestCompareWithoutVerdicts(t *testing.T) {
+	// Results saved before experiments declared an expectation are compared by how far the attack got
+	legacy := func(result string) *Report {
+		outcome := verifier.NewLegacy("privileged", "Experiment", "MITRE", "Tactic", "Technique")
+		outcome.GetOutcome().Result["hostPid"] = result
+		return &Report{Results: []*verifier.LegacyOutcome{outcome.GetOutcome()}}
+	}
+
+	d := Compare(legacy(verifier.Fail), legacy(verifier.Success))
+	require.Len(t, d.Changes, 1)
+	assert.Equal(t, Regression, d.Changes[0].Kind)
+	assert.Equal(t, "not observed", d.Changes[0].Base.String())
+
+	d = Compare(legacy(verifier.Success), legacy(verifier.DetectedResult))
+	require.Len(t, d.Changes, 1)
+	assert.Equal(t, Improvement, d.Changes[0].Kind)
+
+	// Only what was observed is compared against results with verdicts
+	head := New("head", []*verifier.LegacyOutcome{evaluated("privileged", verifier.Blocked, map[string]string{"hostPid": verifier.Fail})}, nil)
+	assert.Empty(t, Compare(legacy(verifier.Fail), head).Changes)
+}
+
+func TestLoad(t *testing.T) {
+	dir := t.TempDir()
+	path := filepath.Join(dir, "results.json")
+	contents := `{"results": [{"experiment": "privileged", "result": {"hostPid": "fail"}}]}`
+	require.NoError(t, os.WriteFile(path, []byte(contents), 0600))
+
+	r, err := Load(path)
+	require.NoError(t, err)
+	require.Len(t, r.Results, 1)
+	assert.Equal(t, "privileged", r.Results[0].Experiment)
+
+	require.NoError(t, os.WriteFile(path, []byte("not json"), 0600))
+	_, err = Load(path)
+	assert.Error(t, err)
+	_, err = Load(filepath.Join(dir, "missing.json"))
+	assert.Error(t, err)
+}
@@ -0,0 +1,202 @@
+/*
+Copyright 2023 Operant AI
+*/
+package report
+
+import (
+	_ "embed"
+	"fmt"
+	"html/template"
+	"io"
+	"sort"
+	"time"
+
+	"github.com/operantai/woodpecker/internal/categories"
+	"github.com/operantai/woodpecker/internal/verifier"
+)
+
+// FormatHTML is the format of the report command's self-contained HTML reports
+const FormatHTML = "html"
+
+//go:embed report.html.tmpl
+var htmlTemplate string
+
+// TacticSummary counts the verdicts of the experiments of a single tactic
+type TacticSummary struct {
+	Framework   string
+	Tactic      string
+	Experiments int
+	Passed      int
+	Failed      int
+	Suppressed  int
+	// TechniquesTested is the number of distinct techniques of the tactic that were tested, out of Techniques
+	TechniquesTested int
+	Techniques       int
+}
+
+// TacticBreakdown returns the verdicts of the report grouped by tactic, in the order the MITRE and MITRE ATLAS
+// frameworks list their tactics. Tactics that aren't part of a known framework come last, in order of appearance.
+func TacticBreakdown(r *Report) []TacticSummary {
+	type key struct{ framework, tactic string }
+	var order []key
+	for _, framework := range []categories.Framework{categories.Mitre, categories.MitreAtlas} {
+		for _, tactic := range categories.Tactics(string(framework)) {
+			order = append(order, key{string(framework), tactic})
+		}
+	}
+
+	summaries := make(map[key]*TacticSummary)
+	tested := make(map[key]map[string]bool)
+	for _, outcome := range r.Results {
+		k := key{outcome.Framework, outcome.Tactic}
+		s, ok := summaries[k]
+		if !ok {
+			s = &TacticSummary{
+				Framework:  outcome.Framework,
+				Tactic:     outcome.Tactic,
+				Techniques: len(categories.Techniques(outcome.Framework, outcome.Tactic)),
+			}
+			summaries[k] = s
+			tested[k] = make(map[string]bool)
+			if s.Techniques == 0 {
+				order = append(order, k)
+			}
+		}
+		s.Experiments++
+		switch outcome.Verdict {
+		case verifier.Pass:
+			s.Passed++
+		case verifier.Suppressed:
+			s.Suppressed++
+		default:
+			s.Failed++
+		}
+		tested[k][outcome.Technique] = true
+		s.TechniquesTested = len(tested[k])
+	}
+
+	var breakdown []TacticSummary
+	for _, k := range order {
+		if s, ok := summaries[k]; ok {
+			breakdown = append(breakdown, *s)
+		}
+	}
+	return breakdown
+}
+
+// htmlReport is the data the HTML template is rendered with
+type htmlReport struct {
+	*Report
+	Generated   *time.Time
+	Tactics     []TacticSummary
+	Findings    []*verifier.LegacyOutcome
+	Experiments []htmlExperiment
+	Trends      []htmlTrend
+}
+
+type htmlExperiment struct {
+	*verifier.LegacyOutcome
+	CategoryID string
+	Tests      []htmlTest
+	// Evidence holds the outputs the verifier stored under a name that isn't one of the tests
+	Evidence []htmlEvidence
+	Errors   []string
+}
+
+type htmlTest struct {
+	Name        string
+	Observed    string
+	Verdict     verifier.Verdict
+	Suppression *verifier.Suppression
+	Evidence    string
+}
+
+type htmlEvidence struct {
+	Name string
+	Body string
+}
+
+type htmlTrend struct {
+	RunID     string
+	StartedAt *time.Time
+	Summary   verifier.Summary
+	// PassedPercent is the share of passed experiments, rounded down
+	PassedPercent int
+}
+
+// WriteHTML writes the report as a single HTML document with no external assets, along with the pass/fail
+// trend of the given history of reports
+func WriteHTML(w io.Writer, r *Report, history []*Report) error {
+	tmpl, err := template.New("report").Funcs(template.FuncMap{
+		"timestamp": func(t *time.Time) string {
+			if t == nil {
+				return "-"
+			}
+			return t.UTC().Format(time.RFC1123)
+		},
+		"date": func(t time.Time) string {
+			return t.Format("2006-01-02")
+		},
+	}).Parse(htmlTemplate)
+	if err != nil {
+		return fmt.Errorf("Could not parse HTML template: %w", err)
+	}
+
+	generated := time.Now().UTC()
+	data := htmlReport{
+		Report:    r,
+		Generated: &generated,
+		Tactics:   TacticBreakdown(r),
+		Findings:  Findings(r),
+	}
+	errs := make(map[string][]string)
+	for _, e := range r.Errors {
+		errs[e.Experiment] = append(errs[e.Experiment], e.Error)
+	}
+	for _, outcome := range r.Results {
+		data.Experiments = append(data.Experiments, newHTMLExperiment(outcome, errs[outcome.Experiment]))
+	}
+	for _, h := range history {
+		trend := htmlTrend{RunID: h.RunID, StartedAt: h.StartedAt, Summary: h.Summary}
+		if h.Summary.Experiments > 0 {
+			trend.PassedPercent = h.Summary.Passed * 100 / h.Summary.Experiments
+		}
+		data.Trends = append(data.Trends, trend)
+	}
+
+	if err := tmpl.Execute(w, data); err != nil {
+		return fmt.Errorf("Could not render HTML report: %w", err)
+	}
+	return nil
+}
+
+// newHTMLExperiment returns the tests and evidence of an outcome as shown in the HTML report
+func newHTMLExperiment(outcome *verifier.LegacyOutcome, errs []string) htmlExperiment {
+	e := htmlExperiment{LegacyOutcome: outcome, Errors: errs}
+	e.CategoryID, _ = categories.CategoryID(outcome.Framework, outcome.Tactic, outcome.Technique)
+
+	for _, test := range testNames(outcome) {
+		suppression, _ := outcome.TestSuppression(test)
+		e.Tests = append(e.Tests, htmlTest{
+			Name:        test,
+			Observed:    observedText(testObserved(outcome, test)),
+			Verdict:     testVerdict(outcome, test),
+			Suppression: suppression,
+			Evidence:    resultOutputs(outcome, test),
+		})
+	}
+
+	var names []string
+	for name := range outcome.ResultOutputs {
+		if _, ok := outcome.Result[name]; !ok {
+			names = append(names, name)
+		}
+	}
+	sort.Strings(names)
+	for _, name := range names {
+		if body := resultOutputs(outcome, name); body != "" {
+			e.Evidence = append(e.Evidence, htmlEvidence{Name: name, Body: body})
+		}
+	}
+	return e
+}
@@ -0,0 +1,123 @@
+package report
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"testing"
+
+	"github.com/operantai/woodpecker/internal/categories"
+	"github.com/operantai/woodpecker/internal/ledger"
+	"github.com/operantai/woodpecker/internal/verifier"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// recordOutcome records a verified outcome for an experiment of the run
+func recordOutcome(t *testing.T, run *ledger.Run, outcome *verifier.LegacyOutcome) {
+	contents, err := json.Marshal(outcome)
+	require.NoError(t, err)
+	require.NoError(t, run.SetOutcome(outcome.Experiment, contents))
+}
+
+func TestFromRun(t *testing.T) {
+	store := ledger.NewStore(t.TempDir())
+	run, err := store.Create(map[string]string{"kube-exec": "kube-exec", "host-path": "host-path-mount", "privileged": "privileged-container"})
+	require.NoError(t, err)
+
+	v := verifier.NewLegacy("kube-exec", "Execute a command in a pod", "MITRE", "Execution", "Exec Into Container")
+	v.Success("kube-exec")
+	v.StoreResultOutputs("kube-exec", map[string]string{"stdout": "root"})
+	v.GetOutcome().Evaluate(verifier.Blocked)
+	recordOutcome(t, run, v.GetOutcome())
+	require.NoError(t, run.SetState("host-path", ledger.Failed, errors.New("image can't be pulled")))
+	require.NoError(t, run.SetState("privileged", ledger.Blocked, errors.New("forbidden")))
+	require.NoError(t, run.Finish())
+
+	loaded, err := store.Load(run.ID)
+	require.NoError(t, err)
+	r, err := FromRun(loaded)
+	require.NoError(t, err)
+
+	assert.Equal(t, run.ID, r.RunID)
+	assert.NotNil(t, r.StartedAt)
+	assert.NotNil(t, r.FinishedAt)
+	require.Len(t, r.Results, 1)
+	assert.Equal(t, verifier.Failed, r.Results[0].Verdict)
+	assert.Equal(t, []interface{}{map[string]interface{}{"stdout": "root"}}, r.Results[0].ResultOutputs["kube-exec"])
+	// Blocked experiments aren't errors
+	assert.Equal(t, []Error{{Experiment: "host-path", Error: "image can't be pulled"}}, r.Errors)
+	assert.Equal(t, verifier.Summary{Experiments: 1, Failed: 1, Errors: 1, Tests: 1, TestsFailed: 1}, r.Summary)
+}
+
+func TestHistory(t *testing.T) {
+	store := ledger.NewStore(t.TempDir())
+	var ids []string
+	for i := 0; i < 4; i++ {
+		run, err := store.Create(map[string]string{"kube-exec": "kube-exec"})
+		require.NoError(t, err)
+		ids = append(ids, run.ID)
+		// The second run was never verified
+		if i == 1 {
+			continue
+		}
+		v := verifier.NewLegacy("kube-exec", "Execute a command in a pod", "MITRE", "Execution", "Exec Into Container")
+		v.Success("kube-exec")
+		v.GetOutcome().Evaluate(verifier.Allowed)
+		recordOutcome(t, run, v.GetOutcome())
+	}
+
+	history, err := History(store, 2)
+	require.NoError(t, err)
+	require.Len(t, history, 2)
+	assert.Equal(t, ids[2], history[0].RunID)
+	assert.Equal(t, ids[3], history[1].RunID)
+
+	history, err = History(store, 10)
+	require.NoError(t, err)
+	assert.Len(t, history, 3)
+}
+
+func TestTacticBreakdown(t *testing.T) {
+	exec := categories.MITRE.Execution.ExecIntoContainer
+	privileged := categories.MITRE.PrivilegeEscalation.PrivilegedContainer
+	leakage := categories.MITREATLAS.Exfiltration.LLMDataLeakage
+	outcomes := []*verifier.LegacyOutcome{
+		{Experiment: "leakage", Framework: string(categories.MitreAtlas), Tactic: leakage.Tactic, Technique: leakage.Technique, Verdict: verifier.Pass},
+		{Experiment: "privileged", Framework: string(categories.Mitre), Tactic: privileged.Tactic, Technique: privileged.Technique, Verdict: verifier.Failed},
+		{Experiment: "kube-exec", Framework: string(categories.Mitre), Tactic: exec.Tactic, Technique: exec.Technique, Verdict: verifier.Pass},
+		{Experiment: "kube-exec-again", Framework: string(categories.Mitre), Tactic: exec.Tactic, Technique: exec.Technique, Verdict: verifier.Failed},
+		{Experiment: "owasp", Framework: "OWASP", Tactic: "Injection", Technique: "Prompt Injection", Verdict: verifier.Pass},
+	}
+
+	breakdown := TacticBreakdown(New("run", outcomes, nil))
+	assert.Equal(t, []TacticSummary{
+		{Framework: "MITRE", Tactic: "Execution", Experiments: 2, Passed: 1, Failed: 1, TechniquesTested: 1, Techniques: 6},
+		{Framework: "MITRE", Tactic: "Privilege Escalation", Experiments: 1, Failed: 1, TechniquesTested: 1, Techniques: 4},
+		{Framework: "MITRE-ATLAS", Tactic: "Exfiltration", Experiments: 1, Passed: 1, TechniquesTested: 1, Techniques: 1},
+		{Framework: "OWASP", Tactic: "Injection", Experiments: 1, Passed: 1, TechniquesTested: 1},
+	}, breakdown)
+}
+
+func TestWriteHTML(t *testing.T) {
+	v := verifier.NewLegacy("kube-exec", "Execute a command in a pod", "MITRE", "Execution", "Exec Into Container")
+	v.Success("kube-exec")
+	v.StoreResultOutputs("kube-exec", map[string]string{"stdout": "<script>alert(1)</script>"})
+	v.GetOutcome().Evaluate(verifier.Blocked)
+	r := New("run", []*verifier.LegacyOutcome{v.GetOutcome()}, nil)
+	r.AddError("host-path", errors.New("image can't be pulled"))
+
+	var buf bytes.Buffer
+	require.NoError(t, WriteHTML(&buf, r, []*Report{r}))
+	html := buf.String()
+
+	assert.Contains(t, html, "<title>Woodpecker report - run</title>")
+	assert.Contains(t, html, "Exec Into Container")
+	assert.Contains(t, html, "image can&#39;t be pulled")
+	// Evidence is escaped
+	assert.Contains(t, html, "&lt;script&gt;alert(1)&lt;/script&gt;")
+	assert.NotContains(t, html, "<script>")
+	// The report doesn't load anything from elsewhere
+	assert.NotContains(t, html, "src=")
+	assert.NotContains(t, html, "<link")
+}
@@ -0,0 +1,177 @@
+/*
+Copyright 2023 Operant AI
+*/
+package report
+
+import (
+	"bytes"
+	"encoding/json"
+	"encoding/xml"
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/operantai/woodpecker/internal/verifier"
+)
+
+// JUnitTestSuites is the root of a JUnit XML report, with a testsuite per experiment
+type JUnitTestSuites struct {
+	XMLName  xml.Name         `xml:"testsuites"`
+	Name     string           `xml:"name,attr"`
+	Tests    int              `xml:"tests,attr"`
+	Failures int              `xml:"failures,attr"`
+	Errors   int              `xml:"errors,attr"`
+	Skipped  int              `xml:"skipped,attr"`
+	Suites   []JUnitTestSuite `xml:"testsuite"`
+}
+
+type JUnitTestSuite struct {
+	Name       string          `xml:"name,attr"`
+	Tests      int             `xml:"tests,attr"`
+	Failures   int             `xml:"failures,attr"`
+	Errors     int             `xml:"errors,attr"`
+	Skipped    int             `xml:"skipped,attr"`
+	Properties []JUnitProperty `xml:"properties>property,omitempty"`
+	TestCases  []JUnitTestCase `xml:"testcase"`
+}
+
+type JUnitProperty struct {
+	Name  string `xml:"name,attr"`
+	Value string `xml:"value,attr"`
+}
+
+type JUnitTestCase struct {
+	Name      string        `xml:"name,attr"`
+	ClassName string        `xml:"classname,attr"`
+	Failure   *JUnitFailure `xml:"failure,omitempty"`
+	Error     *JUnitFailure `xml:"error,omitempty"`
+	Skipped   *JUnitSkipped `xml:"skipped,omitempty"`
+}
+
+// JUnitSkipped marks a testcase that missed its expectation as an accepted risk
+type JUnitSkipped struct {
+	Message string `xml:"message,attr"`
+}
+
+// JUnitFailure is the failure or error of a testcase, its body holds the outputs stored by the verifier
+type JUnitFailure struct {
+	Message string `xml:"message,attr"`
+	Type    string `xml:"type,attr"`
+	Body    string `xml:",chardata"`
+}
+
+// NewJUnit converts the report to a JUnit XML report. Each experiment is a testsuite and each of its tests
+// a testcase, which fails when the test missed its expectation. Experiments with errors get an extra
+// testcase holding the error.
+func NewJUnit(r *Report) *JUnitTestSuites {
+	suites := &JUnitTestSuites{Name: toolName, Suites: []JUnitTestSuite{}}
+	if r.RunID != "" {
+		suites.Name = fmt.Sprintf("%s/%s", toolName, r.RunID)
+	}
+
+	index := make(map[string]int)
+	for _, outcome := range r.Results {
+		suite := JUnitTestSuite{
+			Name:       outcome.Experiment,
+			Properties: junitProperties(outcome),
+			TestCases:  []JUnitTestCase{},
+		}
+		for _, test := range testNames(outcome) {
+			testCase := JUnitTestCase{Name: test, ClassName: outcome.Experiment}
+			switch verdict := testVerdict(outcome, test); verdict {
+			case verifier.Pass:
+			case verifier.Suppressed:
+				suppression, _ := outcome.TestSuppression(test)
+				testCase.Skipped = &JUnitSkipped{
+					Message: fmt.Sprintf("Suppressed until %s: %s", suppression.Expires.Format("2006-01-02"), suppression.Justification),
+				}
+				suite.Skipped++
+			default:
+				message := fmt.Sprintf("The attack was %s, expected it to be %s", observedText(testObserved(outcome, test)), outcome.Expect)
+				if suppression, ok := outcome.TestSuppression(test); ok && suppression.Expired {
+					message = fmt.Sprintf("%s, and its suppression expired on %s", message, suppression.Expires.Format("2006-01-02"))
+				}
+				body := resultOutputs(outcome, test)
+				if outcome.Remediation != nil {
+					body = strings.TrimPrefix(body+"\n\n"+remediationText(outcome.Remediation), "\n\n")
+				}
+				testCase.Failure = &JUnitFailure{
+					Message: message,
+					Type:    string(verdict),
+					Body:    body,
+				}
+				suite.Failures++
+			}
+			suite.TestCases = append(suite.TestCases, testCase)
+		}
+		index[outcome.Experiment] = len(suites.Suites)
+		suites.Suites = append(suites.Suites, suite)
+	}
+
+	for _, e := range r.Errors {
+		i, ok := index[e.Experiment]
+		if !ok {
+			i = len(suites.Suites)
+			index[e.Experiment] = i
+			suites.Suites = append(suites.Suites, JUnitTestSuite{Name: e.Experiment, TestCases: []JUnitTestCase{}})
+		}
+		suites.Suites[i].TestCases = append(suites.Suites[i].TestCases, JUnitTestCase{
+			Name:      "Error",
+			ClassName: e.Experiment,
+			Error:     &JUnitFailure{Message: e.Error, Type: "error"},
+		})
+		suites.Suites[i].Errors++
+	}
+	sort.SliceStable(suites.Suites, func(i, j int) bool {
+		return suites.Suites[i].Name < suites.Suites[j].Name
+	})
+
+	for i := range suites.Suites {
+		suite := &suites.Suites[i]
+		suite.Tests = len(suite.TestCases)
+		suites.Tests += suite.Tests
+		suites.Failures += suite.Failures
+		suites.Errors += suite.Errors
+		suites.Skipped += suite.Skipped
+	}
+	return suites
+}
+
+// junitProperties returns the properties of an experiment's testsuite
+func junitProperties(outcome *verifier.LegacyOutcome) []JUnitProperty {
+	properties := []JUnitProperty{
+		{Name: "description", Value: outcome.Description},
+		{Name: "framework", Value: outcome.Framework},
+		{Name: "tactic", Value: outcome.Tactic},
+		{Name: "technique", Value: outcome.Technique},
+		{Name: "expect", Value: string(outcome.Expect)},
+	}
+	if outcome.Severity != "" {
+		properties = append(properties,
+			JUnitProperty{Name: "severity", Value: string(outcome.Severity)},
+			JUnitProperty{Name: "riskScore", Value: strconv.Itoa(outcome.RiskScore)},
+		)
+	}
+	if outcome.Source != "" {
+		properties = append(properties, JUnitProperty{Name: "source", Value: outcome.Source})
+	}
+	return properties
+}
+
+// resultOutputs returns the outputs the verifier stored for a test as indented JSON, or nothing if there are none
+func resultOutputs(outcome *verifier.LegacyOutcome, test string) string {
+	outputs, ok := outcome.ResultOutputs[test]
+	if !ok || len(outputs) == 0 {
+		return ""
+	}
+	// Outputs are escaped by the format they are written in, escaping them here would make stdout unreadable
+	var buf bytes.Buffer
+	encoder := json.NewEncoder(&buf)
+	encoder.SetEscapeHTML(false)
+	encoder.SetIndent("", "    ")
+	if err := encoder.Encode(outputs); err != nil {
+		return fmt.Sprintf("%v", outputs)
+	}
+	return strings.TrimSuffix(buf.String(), "\n")
+}
@@ -0,0 +1,80 @@
+package report
+
+import (
+	"encoding/xml"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/operantai/woodpecker/internal/verifier"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestNewJUnit(t *testing.T) {
+	missed := verifier.NewLegacy("kube-exec", "Execute a command in a pod", "MITRE", "Execution", "Exec Into Container")
+	missed.Success("whoami")
+	missed.Blocked("cat")
+	missed.StoreResultOutputs("whoami", map[string]string{"stdout": "root"})
+	missed.GetOutcome().Evaluate(verifier.Blocked)
+
+	blocked := verifier.NewLegacy("privileged", "Run a privileged container", "MITRE", "Privilege Escalation", "Privileged container").GetOutcome()
+	blocked.Observed = verifier.Blocked
+	blocked.Evaluate(verifier.Blocked)
+
+	r := New("run", []*verifier.LegacyOutcome{missed.GetOutcome(), blocked}, nil)
+	r.AddError("host-path", errors.New("connection refused"))
+	suites := NewJUnit(r)
+
+	assert.Equal(t, "woodpecker/run", suites.Name)
+	assert.Equal(t, 4, suites.Tests)
+	assert.Equal(t, 1, suites.Failures)
+	assert.Equal(t, 1, suites.Errors)
+	require.Len(t, suites.Suites, 3)
+
+	// Every test of an experiment is a testcase, failures hold the outputs stored for the test
+	exec := suites.Suites[1]
+	assert.Equal(t, "kube-exec", exec.Name)
+	assert.Contains(t, exec.Properties, JUnitProperty{Name: "technique", Value: "Exec Into Container"})
+	require.Len(t, exec.TestCases, 2)
+	assert.Equal(t, "cat", exec.TestCases[0].Name)
+	assert.Nil(t, exec.TestCases[0].Failure)
+	assert.Equal(t, "whoami", exec.TestCases[1].Name)
+	require.NotNil(t, exec.TestCases[1].Failure)
+	assert.Equal(t, "The attack was allowed, expected it to be blocked", exec.TestCases[1].Failure.Message)
+	assert.Contains(t, exec.TestCases[1].Failure.Body, `"stdout": "root"`)
+
+	// An experiment without results has a single Overall testcase
+	privileged := suites.Suites[2]
+	require.Len(t, privileged.TestCases, 1)
+	assert.Equal(t, "Overall", privileged.TestCases[0].Name)
+	assert.Nil(t, privileged.TestCases[0].Failure)
+
+	// An experiment that couldn't be run has an errored testcase
+	hostPath := suites.Suites[0]
+	assert.Equal(t, "host-path", hostPath.Name)
+	require.Len(t, hostPath.TestCases, 1)
+	assert.Equal(t, "connection refused", hostPath.TestCases[0].Error.Message)
+
+	contents, err := xml.Marshal(suites)
+	require.NoError(t, err)
+	assert.Contains(t, string(contents), `<testsuites name="woodpecker/run" tests="4" failures="1" errors="1" skipped="0">`)
+}
+
+func TestNewJUnitSuppressed(t *testing.T) {
+	v := verifier.NewLegacy("host-path", "Mount a host path", "MITRE", "Privilege Escalation", "Host Path Mount")
+	v.Success("logging")
+	v.Success("root")
+	v.GetOutcome().Evaluate(verifier.Blocked)
+	v.GetOutcome().Suppress("logging", &verifier.Suppression{Justification: "Log shipper", Expires: time.Date(2030, 1, 31, 0, 0, 0, 0, time.UTC)})
+	v.GetOutcome().Suppress("root", &verifier.Suppression{Justification: "Legacy", Expires: time.Date(2020, 1, 31, 0, 0, 0, 0, time.UTC), Expired: true})
+
+	suites := NewJUnit(New("run", []*verifier.LegacyOutcome{v.GetOutcome()}, nil))
+	assert.Equal(t, 1, suites.Skipped)
+	assert.Equal(t, 1, suites.Failures)
+	testCases := suites.Suites[0].TestCases
+	require.Len(t, testCases, 2)
+	assert.Equal(t, "Suppressed until 2030-01-31: Log shipper", testCases[0].Skipped.Message)
+	assert.Nil(t, testCases[0].Failure)
+	assert.Equal(t, "The attack was allowed, expected it to be blocked, and its suppression expired on 2020-01-31", testCases[1].Failure.Message)
+}
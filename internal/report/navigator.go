@@ -0,0 +1,165 @@
+/*
+Copyright 2023 Operant AI
+*/
+package report
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"sort"
+	"strings"
+
+	"github.com/operantai/woodpecker/internal/categories"
+	"github.com/operantai/woodpecker/internal/verifier"
+)
+
+// FormatNavigator is the format of the report command's MITRE ATT&CK Navigator layers
+const FormatNavigator = "navigator"
+
+const (
+	navigatorLayerVersion = "4.5"
+	navigatorVersion      = "5.1.0"
+	navigatorDomain       = "enterprise-attack"
+)
+
+// Coverage is how well the defenses of a cluster cover a technique, from the experiments testing it
+type Coverage string
+
+const (
+	TestedBlocked  Coverage = "tested-blocked"
+	TestedDetected Coverage = "tested-detected"
+	TestedAllowed  Coverage = "tested-allowed"
+	Untested       Coverage = "untested"
+)
+
+// coverageColors are the colors techniques are painted in, by coverage
+var coverageColors = map[Coverage]string{
+	TestedBlocked:  "#8ec843",
+	TestedDetected: "#ffe766",
+	TestedAllowed:  "#ff6666",
+	Untested:       "#d9d9d9",
+}
+
+// NavigatorLayer is a MITRE ATT&CK Navigator layer, see https://github.com/mitre-attack/attack-navigator
+type NavigatorLayer struct {
+	Name         string                `json:"name"`
+	Versions     NavigatorVersions     `json:"versions"`
+	Domain       string                `json:"domain"`
+	Description  string                `json:"description"`
+	Techniques   []NavigatorTechnique  `json:"techniques"`
+	LegendItems  []NavigatorLegendItem `json:"legendItems"`
+	HideDisabled bool                  `json:"hideDisabled"`
+}
+
+type NavigatorVersions struct {
+	Navigator string `json:"navigator"`
+	Layer     string `json:"layer"`
+}
+
+type NavigatorTechnique struct {
+	TechniqueID string              `json:"techniqueID"`
+	Tactic      string              `json:"tactic"`
+	Color       string              `json:"color"`
+	Comment     string              `json:"comment,omitempty"`
+	Enabled     bool                `json:"enabled"`
+	Metadata    []NavigatorMetadata `json:"metadata,omitempty"`
+}
+
+type NavigatorMetadata struct {
+	Name  string `json:"name"`
+	Value string `json:"value"`
+}
+
+type NavigatorLegendItem struct {
+	Label string `json:"label"`
+	Color string `json:"color"`
+}
+
+// NewNavigatorLayer returns an ATT&CK Navigator layer painting every technique of the MITRE framework by how
+// the experiments testing it fared. A technique is allowed if any attack on it got through, detected if all of
+// them were at least noticed, blocked if they were all refused, and untested if no experiment observed it.
+func NewNavigatorLayer(r *Report) *NavigatorLayer {
+	type key struct{ techniqueID, tactic string }
+	coverage := make(map[key]Coverage)
+	experiments := make(map[key][]string)
+	for _, outcome := range r.Results {
+		if outcome.Framework != string(categories.Mitre) {
+			continue
+		}
+		id, ok := categories.TechniqueID(outcome.Framework, outcome.Tactic, outcome.Technique)
+		if !ok || outcome.Observed == "" {
+			continue
+		}
+		k := key{id, outcome.Tactic}
+		coverage[k] = worstCoverage(coverage[k], observedCoverage(outcome.Observed))
+		experiments[k] = append(experiments[k], fmt.Sprintf("%s (%s)", outcome.Experiment, outcome.Observed))
+	}
+
+	layer := &NavigatorLayer{
+		Name:        fmt.Sprintf("%s coverage", toolName),
+		Versions:    NavigatorVersions{Navigator: navigatorVersion, Layer: navigatorLayerVersion},
+		Domain:      navigatorDomain,
+		Description: "Techniques tested by woodpecker experiments, colored by how the cluster responded",
+		Techniques:  []NavigatorTechnique{},
+	}
+	if r.RunID != "" {
+		layer.Name = fmt.Sprintf("%s coverage of run %s", toolName, r.RunID)
+	}
+	for _, tactic := range categories.Tactics(string(categories.Mitre)) {
+		for _, id := range categories.TechniqueIDs(string(categories.Mitre), tactic) {
+			k := key{id, tactic}
+			c, ok := coverage[k]
+			if !ok {
+				c = Untested
+			}
+			technique := NavigatorTechnique{
+				TechniqueID: id,
+				Tactic:      slug(tactic),
+				Color:       coverageColors[c],
+				Enabled:     true,
+				Metadata:    []NavigatorMetadata{{Name: "coverage", Value: string(c)}},
+			}
+			if names := experiments[k]; len(names) > 0 {
+				sort.Strings(names)
+				technique.Comment = "Tested by " + strings.Join(names, ", ")
+			}
+			layer.Techniques = append(layer.Techniques, technique)
+		}
+	}
+	for _, c := range []Coverage{TestedBlocked, TestedDetected, TestedAllowed, Untested} {
+		layer.LegendItems = append(layer.LegendItems, NavigatorLegendItem{Label: string(c), Color: coverageColors[c]})
+	}
+	return layer
+}
+
+// observedCoverage returns the coverage of a technique from the observed behavior of an attack on it
+func observedCoverage(observed verifier.Behavior) Coverage {
+	switch observed {
+	case verifier.Blocked:
+		return TestedBlocked
+	case verifier.Detected:
+		return TestedDetected
+	default:
+		return TestedAllowed
+	}
+}
+
+// worstCoverage returns the weaker of two coverages, ignoring an empty one
+func worstCoverage(a, b Coverage) Coverage {
+	rank := map[Coverage]int{TestedBlocked: 1, TestedDetected: 2, TestedAllowed: 3}
+	if rank[a] >= rank[b] {
+		return a
+	}
+	return b
+}
+
+// WriteNavigatorLayer writes the ATT&CK Navigator layer of the report as JSON
+func WriteNavigatorLayer(w io.Writer, r *Report) error {
+	encoder := json.NewEncoder(w)
+	encoder.SetIndent("", "    ")
+	if err := encoder.Encode(NewNavigatorLayer(r)); err != nil {
+		return fmt.Errorf("Could not write ATT&CK Navigator layer: %w", err)
+	}
+	return nil
+}
@@ -0,0 +1,59 @@
+package report
+
+import (
+	"testing"
+
+	"github.com/operantai/woodpecker/internal/categories"
+	"github.com/operantai/woodpecker/internal/verifier"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestNewNavigatorLayer(t *testing.T) {
+	privileged := categories.MITRE.PrivilegeEscalation.PrivilegedContainer
+	hostPath := categories.MITRE.PrivilegeEscalation.HostPathMount
+	exec := categories.MITRE.Execution.ExecIntoContainer
+	secrets := categories.MITRE.Credentials.ListK8sSecrets
+	clearLogs := categories.MITRE.DefenseEvasion.ClearContainerLogs
+	deleteEvents := categories.MITRE.DefenseEvasion.DeleteK8sEvents
+	leakage := categories.MITREATLAS.Exfiltration.LLMDataLeakage
+	outcomes := []*verifier.LegacyOutcome{
+		// Both experiments test T1611 in the Privilege Escalation tactic, the one that got through wins
+		{Experiment: "privileged", Framework: string(categories.Mitre), Tactic: privileged.Tactic, Technique: privileged.Technique, Observed: verifier.Blocked},
+		{Experiment: "host-path", Framework: string(categories.Mitre), Tactic: hostPath.Tactic, Technique: hostPath.Technique, Observed: verifier.Allowed},
+		{Experiment: "kube-exec", Framework: string(categories.Mitre), Tactic: exec.Tactic, Technique: exec.Technique, Observed: verifier.Blocked},
+		{Experiment: "list-secrets", Framework: string(categories.Mitre), Tactic: secrets.Tactic, Technique: secrets.Technique, Observed: verifier.Detected},
+		{Experiment: "clear-logs", Framework: string(categories.Mitre), Tactic: clearLogs.Tactic, Technique: clearLogs.Technique, Observed: verifier.Allowed},
+		{Experiment: "delete-events", Framework: string(categories.Mitre), Tactic: deleteEvents.Tactic, Technique: deleteEvents.Technique, Observed: verifier.Blocked},
+		{Experiment: "leakage", Framework: string(categories.MitreAtlas), Tactic: leakage.Tactic, Technique: leakage.Technique, Observed: verifier.Allowed},
+	}
+	layer := NewNavigatorLayer(New("run", outcomes, nil))
+
+	assert.Equal(t, "enterprise-attack", layer.Domain)
+	coverage := make(map[string]NavigatorTechnique)
+	for _, technique := range layer.Techniques {
+		coverage[technique.Tactic+"/"+technique.TechniqueID] = technique
+	}
+
+	escape := coverage["privilege-escalation/T1611"]
+	assert.Equal(t, coverageColors[TestedAllowed], escape.Color)
+	assert.Equal(t, "Tested by host-path (allowed), privileged (blocked)", escape.Comment)
+	assert.Equal(t, coverageColors[TestedBlocked], coverage["execution/T1609"].Color)
+	assert.Equal(t, coverageColors[TestedDetected], coverage["credential-access/T1552.007"].Color)
+
+	// Clearing logs and deleting events are separate indicator removal techniques, neither hides the other
+	assert.Equal(t, "Tested by clear-logs (allowed)", coverage["defense-evasion/T1070.002"].Comment)
+	assert.Equal(t, coverageColors[TestedAllowed], coverage["defense-evasion/T1070.002"].Color)
+	assert.Equal(t, "Tested by delete-events (blocked)", coverage["defense-evasion/T1070"].Comment)
+	assert.Equal(t, coverageColors[TestedBlocked], coverage["defense-evasion/T1070"].Color)
+
+	// The same technique in another tactic is untested
+	persistence := coverage["persistence/T1611"]
+	assert.Equal(t, coverageColors[Untested], persistence.Color)
+	assert.Empty(t, persistence.Comment)
+
+	// ATLAS techniques aren't part of an ATT&CK layer
+	for _, technique := range layer.Techniques {
+		require.NotEqual(t, "AML.T0057", technique.TechniqueID)
+	}
+}
@@ -0,0 +1,413 @@
+/*
+Copyright 2023 Operant AI
+*/
+package report
+
+import (
+	"encoding/json"
+	"fmt"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/operantai/woodpecker/internal/ledger"
+	"github.com/operantai/woodpecker/internal/output"
+	"github.com/operantai/woodpecker/internal/verifier"
+)
+
+// Output formats a Report can be written in
+const (
+	FormatTable = "table"
+	FormatJSON  = "json"
+	FormatYAML  = "yaml"
+	FormatSARIF = "sarif"
+	FormatJUnit = "junit"
+)
+
+// Report is the result of verifying the experiments of a run. It is collected once, and every output format
+// is rendered from it, so that the table, the summary and structured output always agree with each other.
+type Report struct {
+	RunID string `json:"runId,omitempty" yaml:"runId,omitempty"`
+	// StartedAt and FinishedAt are only known for reports loaded from the ledger
+	StartedAt  *time.Time                `json:"startedAt,omitempty" yaml:"startedAt,omitempty"`
+	FinishedAt *time.Time                `json:"finishedAt,omitempty" yaml:"finishedAt,omitempty"`
+	Results    []*verifier.LegacyOutcome `json:"results" yaml:"results"`
+	Errors     []Error                   `json:"errors,omitempty" yaml:"errors,omitempty"`
+	Summary    verifier.Summary          `json:"summary" yaml:"summary"`
+}
+
+// Error records an experiment that couldn't be run, verified or cleaned up
+type Error struct {
+	Experiment string `json:"experiment" yaml:"experiment"`
+	Error      string `json:"error" yaml:"error"`
+}
+
+// New returns a Report of the given evaluated outcomes and errors, sorted by experiment name
+func New(runID string, outcomes []*verifier.LegacyOutcome, errs []Error) *Report {
+	sort.Slice(outcomes, func(i, j int) bool {
+		return outcomes[i].Experiment < outcomes[j].Experiment
+	})
+	sort.SliceStable(errs, func(i, j int) bool {
+		return errs[i].Experiment < errs[j].Experiment
+	})
+	if outcomes == nil {
+		outcomes = []*verifier.LegacyOutcome{}
+	}
+
+	summary := verifier.Summarize(outcomes)
+	summary.Errors = countExperiments(errs)
+	return &Report{
+		RunID:   runID,
+		Results: outcomes,
+		Errors:  errs,
+		Summary: summary,
+	}
+}
+
+// FromRun returns the Report of a run stored in the ledger, made of the outcomes recorded when its experiments
+// were verified and the experiments that failed to run
+func FromRun(run *ledger.Run) (*Report, error) {
+	names := make([]string, 0, len(run.Experiments))
+	for name := range run.Experiments {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	var outcomes []*verifier.LegacyOutcome
+	var errs []Error
+	for _, name := range names {
+		if contents := run.Outcome(name); contents != nil {
+			outcome := &verifier.LegacyOutcome{}
+			if err := json.Unmarshal(contents, outcome); err != nil {
+				return nil, fmt.Errorf("Could not parse outcome of experiment %s in run %s: %w", name, run.ID, err)
+			}
+			outcomes = append(outcomes, outcome)
+		}
+		if e := run.Experiments[name]; e.State == ledger.Failed && e.Error != "" {
+			errs = append(errs, Error{Experiment: name, Error: e.Error})
+		}
+	}
+
+	r := New(run.ID, outcomes, errs)
+	startedAt := run.StartedAt
+	r.StartedAt = &startedAt
+	r.FinishedAt = run.FinishedAt
+	return r, nil
+}
+
+// History returns the Reports of the most recent runs in the store, up to limit and oldest first. Runs without
+// any verified experiments are left out, and runs that can't be loaded are skipped with a warning.
+func History(store *ledger.Store, limit int) ([]*Report, error) {
+	ids, err := store.IDs()
+	if err != nil {
+		return nil, fmt.Errorf("Could not list runs: %w", err)
+	}
+
+	var history []*Report
+	for i := len(ids) - 1; i >= 0 && len(history) < limit; i-- {
+		run, err := store.Load(ids[i])
+		if err != nil {
+			output.WriteWarning("Skipping run %s: %s", ids[i], err)
+			continue
+		}
+		r, err := FromRun(run)
+		if err != nil {
+			output.WriteWarning("Skipping run %s: %s", ids[i], err)
+			continue
+		}
+		if len(r.Results) > 0 {
+			history = append(history, r)
+		}
+	}
+	// Oldest first
+	for i, j := 0, len(history)-1; i < j; i, j = i+1, j-1 {
+		history[i], history[j] = history[j], history[i]
+	}
+	return history, nil
+}
+
+// AddError records an error for the named experiment
+func (r *Report) AddError(experiment string, err error) {
+	r.Errors = append(r.Errors, Error{Experiment: experiment, Error: err.Error()})
+	sort.SliceStable(r.Errors, func(i, j int) bool {
+		return r.Errors[i].Experiment < r.Errors[j].Experiment
+	})
+	r.Summary.Errors = countExperiments(r.Errors)
+}
+
+// countExperiments counts the distinct experiments with errors
+func countExperiments(errs []Error) int {
+	experiments := make(map[string]bool)
+	for _, e := range errs {
+		experiments[e.Experiment] = true
+	}
+	return len(experiments)
+}
+
+// ValidateFormat checks that a Report can be written in the given format, an empty format means a table
+func ValidateFormat(format string) error {
+	switch strings.ToLower(format) {
+	case "", FormatTable, FormatJSON, FormatYAML, FormatSARIF, FormatJUnit:
+		return nil
+	default:
+		return fmt.Errorf("Unknown output format: %s", format)
+	}
+}
+
+// Write writes the report to stdout in the given format, a table followed by a summary if the format is empty
+func Write(r *Report, format string) error {
+	if err := ValidateFormat(format); err != nil {
+		return err
+	}
+	switch strings.ToLower(format) {
+	case FormatJSON:
+		output.WriteJSON(r)
+	case FormatYAML:
+		output.WriteYAML(r)
+	case FormatSARIF:
+		output.WriteJSON(NewSARIF(r))
+	case FormatJUnit:
+		output.WriteXML(NewJUnit(r))
+	default:
+		WriteTable(r)
+		WriteSummary(r)
+	}
+	return nil
+}
+
+// WriteTable writes the results of the report as a table, showing each test result as a separate row
+func WriteTable(r *Report) {
+	table := output.NewTable([]string{"Experiment", "Description", "Framework", "Tactic", "Technique", "Severity", "Test", "Observed", "Expected", "Verdict"})
+	for _, row := range TableRows(r) {
+		table.AddRow(row)
+	}
+	table.Render()
+	WriteFootnotes(r)
+}
+
+// footnotes numbers the experiments of the report that carry a remediation, in the order of the table
+func footnotes(r *Report) map[string]int {
+	notes := make(map[string]int)
+	for _, outcome := range r.Results {
+		if outcome.Remediation != nil {
+			notes[outcome.Experiment] = len(notes) + 1
+		}
+	}
+	return notes
+}
+
+// WriteFootnotes writes the remediation of the experiments whose attacks got through, numbered as the
+// footnotes referenced by the verdicts in the table
+func WriteFootnotes(r *Report) {
+	notes := footnotes(r)
+	if len(notes) == 0 {
+		return
+	}
+	fmt.Println()
+	snippets := false
+	for _, outcome := range r.Results {
+		n, ok := notes[outcome.Experiment]
+		if !ok {
+			continue
+		}
+		fmt.Printf("[%d] %s: %s\n", n, outcome.Experiment, outcome.Remediation.Summary)
+		for _, control := range outcome.Remediation.Controls {
+			fmt.Printf("    - %s: %s\n", control.Kind, control.Description)
+			snippets = snippets || control.Snippet != ""
+		}
+	}
+	if snippets {
+		fmt.Println("Sample policies and manifests are part of the -o yaml and -o json output, and of reports")
+	}
+}
+
+// TableRows returns the rows of the results table, one per test of each experiment
+func TableRows(r *Report) [][]string {
+	notes := footnotes(r)
+	var rows [][]string
+	for _, outcome := range r.Results {
+		// If there are no specific test results, show overall experiment result
+		if len(outcome.Result) == 0 {
+			result := "No results"
+			if outcome.Observed != "" {
+				result = string(outcome.Observed)
+			}
+			rows = append(rows, []string{
+				outcome.Experiment,
+				outcome.Description,
+				outcome.Framework,
+				outcome.Tactic,
+				outcome.Technique,
+				string(outcome.Severity),
+				"Overall",
+				result,
+				string(outcome.Expect),
+				footnoted(verdictStatus(outcome.Verdict), outcome.Observed, notes[outcome.Experiment]),
+			})
+			continue
+		}
+
+		tests := make([]string, 0, len(outcome.Result))
+		for test := range outcome.Result {
+			tests = append(tests, test)
+		}
+		sort.Strings(tests)
+		for _, test := range tests {
+			verdict := outcome.TestVerdict(test)
+			observed := verifier.ResultBehavior(outcome.Result[test])
+			rows = append(rows, []string{
+				outcome.Experiment,
+				outcome.Description,
+				outcome.Framework,
+				outcome.Tactic,
+				outcome.Technique,
+				string(outcome.Severity),
+				test,
+				observedText(observed),
+				string(outcome.Expect),
+				footnoted(verdictStatus(verdict), observed, notes[outcome.Experiment]),
+			})
+		}
+	}
+	return rows
+}
+
+// testNames returns the sorted names of the tests of an outcome, an outcome without results has a single
+// Overall test
+func testNames(outcome *verifier.LegacyOutcome) []string {
+	if len(outcome.Result) == 0 {
+		return []string{"Overall"}
+	}
+	tests := make([]string, 0, len(outcome.Result))
+	for test := range outcome.Result {
+		tests = append(tests, test)
+	}
+	sort.Strings(tests)
+	return tests
+}
+
+// testVerdict returns the verdict of a test, the Overall test of an outcome without results has the
+// verdict of the outcome
+func testVerdict(outcome *verifier.LegacyOutcome, test string) verifier.Verdict {
+	if len(outcome.Result) == 0 {
+		return outcome.Verdict
+	}
+	return outcome.TestVerdict(test)
+}
+
+// testObserved returns the behavior observed by a test, the Overall test of an outcome without results has
+// the behavior observed for the outcome
+func testObserved(outcome *verifier.LegacyOutcome, test string) verifier.Behavior {
+	if result, ok := outcome.Result[test]; ok {
+		return verifier.ResultBehavior(result)
+	}
+	return outcome.Observed
+}
+
+// verdictStatus adds a status emoji to a verdict for better visual feedback
+func verdictStatus(verdict verifier.Verdict) string {
+	switch verdict {
+	case verifier.Pass:
+		return "✓ " + string(verdict)
+	case verifier.Suppressed:
+		return "~ " + string(verdict)
+	}
+	return "✗ " + string(verdict)
+}
+
+// remediationText describes a remediation as plain text, with the snippets of its controls
+func remediationText(remediation *verifier.Remediation) string {
+	var b strings.Builder
+	fmt.Fprintf(&b, "Remediation: %s", remediation.Summary)
+	for _, control := range remediation.Controls {
+		fmt.Fprintf(&b, "\n\n%s: %s", control.Kind, control.Description)
+		if control.Snippet != "" {
+			fmt.Fprintf(&b, "\n\n%s", control.Snippet)
+		}
+	}
+	return b.String()
+}
+
+// footnoted references the remediation footnote of an experiment from the verdict of a test whose attack got
+// through
+func footnoted(status string, observed verifier.Behavior, note int) string {
+	if note == 0 || (observed != verifier.Allowed && observed != verifier.Detected) {
+		return status
+	}
+	return fmt.Sprintf("%s [%d]", status, note)
+}
+
+// WriteSummary writes a summary of the verdicts in the report, the suppressions that applied to them, and the
+// experiments that had errors
+func WriteSummary(r *Report) {
+	s := r.Summary
+	fmt.Printf("\nSummary: %d total tests, %d passed, %d failed", s.Tests, s.TestsPassed, s.TestsFailed)
+	if s.TestsSuppressed > 0 {
+		fmt.Printf(", %d suppressed", s.TestsSuppressed)
+	}
+	fmt.Println()
+
+	for _, outcome := range r.Results {
+		for _, test := range sortedKeys(outcome.Suppressions) {
+			suppression := outcome.Suppressions[test]
+			name := outcome.Experiment
+			if test != "" {
+				name = fmt.Sprintf("%s test %s", outcome.Experiment, test)
+			}
+			expires := suppression.Expires.Format("2006-01-02")
+			if suppression.Expired {
+				output.WriteWarning("Suppression of experiment %s expired on %s: %s", name, expires, suppression.Justification)
+			} else {
+				output.WriteInfo("Suppressed experiment %s until %s: %s", name, expires, suppression.Justification)
+			}
+		}
+	}
+	for _, outcome := range Findings(r) {
+		severity := ""
+		if outcome.Severity != "" {
+			severity = fmt.Sprintf("%s severity, ", outcome.Severity)
+		}
+		output.WriteWarning("Experiment %s (%srisk %d): the attack was %s, expected it to be %s",
+			outcome.Experiment, severity, outcome.RiskScore, observedText(outcome.Observed), outcome.Expect)
+	}
+	for _, e := range r.Errors {
+		output.WriteError("Experiment %s: %s", e.Experiment, e.Error)
+	}
+	switch {
+	case s.TestsFailed == 0 && s.TestsSuppressed > 0:
+		output.WriteSuccess("All tests met their expectation or are accepted risks!")
+	case s.TestsFailed == 0:
+		output.WriteSuccess("All tests met their expectation!")
+	default:
+		output.WriteWarning("%d test(s) did not meet their expectation", s.TestsFailed)
+	}
+}
+
+// Findings returns the experiments of the report that missed their expectation, worst first by risk score
+// and then by severity
+func Findings(r *Report) []*verifier.LegacyOutcome {
+	var findings []*verifier.LegacyOutcome
+	for _, outcome := range r.Results {
+		if outcome.Verdict == verifier.Failed {
+			findings = append(findings, outcome)
+		}
+	}
+	sort.SliceStable(findings, func(i, j int) bool {
+		a, b := findings[i], findings[j]
+		if a.RiskScore != b.RiskScore {
+			return a.RiskScore > b.RiskScore
+		}
+		return a.Severity.AtLeast(b.Severity) && !b.Severity.AtLeast(a.Severity)
+	})
+	return findings
+}
+
+// sortedKeys returns the keys of the suppressions of an outcome, sorted
+func sortedKeys(suppressions map[string]*verifier.Suppression) []string {
+	keys := make([]string, 0, len(suppressions))
+	for key := range suppressions {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	return keys
+}
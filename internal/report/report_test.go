@@ -0,0 +1,89 @@
+package report
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/operantai/woodpecker/internal/verifier"
+	"github.com/stretchr/testify/assert"
+)
+
+func testOutcome(name string, expect verifier.Behavior, results map[string]string) *verifier.LegacyOutcome {
+	v := verifier.NewLegacy(name, "Description", "MITRE", "Tactic", "Technique")
+	outcome := v.GetOutcome()
+	for test, result := range results {
+		outcome.Result[test] = result
+	}
+	outcome.Evaluate(expect)
+	return outcome
+}
+
+func TestNew(t *testing.T) {
+	outcomes := []*verifier.LegacyOutcome{
+		testOutcome("b", verifier.Blocked, map[string]string{"hostPid": verifier.Success}),
+		testOutcome("a", verifier.Allowed, map[string]string{"hostPid": verifier.Success}),
+	}
+	errs := []Error{{Experiment: "c", Error: "connection refused"}}
+
+	r := New("run", outcomes, errs)
+	assert.Equal(t, "run", r.RunID)
+	assert.Equal(t, "a", r.Results[0].Experiment)
+	assert.Equal(t, "b", r.Results[1].Experiment)
+	assert.Equal(t, verifier.Summary{
+		Experiments: 2,
+		Passed:      1,
+		Failed:      1,
+		Errors:      1,
+		Tests:       2,
+		TestsPassed: 1,
+		TestsFailed: 1,
+	}, r.Summary)
+
+	// Multiple errors for the same experiment count once
+	r.AddError("c", errors.New("cleanup failed"))
+	r.AddError("a", errors.New("cleanup failed"))
+	assert.Equal(t, 2, r.Summary.Errors)
+	assert.Equal(t, "a", r.Errors[0].Experiment)
+}
+
+func TestTableRows(t *testing.T) {
+	blocked := testOutcome("blocked", verifier.Blocked, nil)
+	blocked.Observed = verifier.Blocked
+	blocked.Evaluate(verifier.Blocked)
+	privileged := testOutcome("privileged", verifier.Allowed, map[string]string{"hostPid": verifier.Success, "hostNetwork": verifier.Fail})
+	privileged.Score(verifier.Critical)
+	privileged.Remediation = &verifier.Remediation{Summary: "Enforce the baseline Pod Security Standard"}
+
+	r := New("run", []*verifier.LegacyOutcome{privileged, blocked}, nil)
+
+	rows := TableRows(r)
+	assert.Len(t, rows, 3)
+	assert.Equal(t, []string{"blocked", "Description", "MITRE", "Tactic", "Technique", "", "Overall", "blocked", "blocked", "✓ pass"}, rows[0])
+	assert.Equal(t, []string{"privileged", "Description", "MITRE", "Tactic", "Technique", "critical", "hostNetwork", "not observed", "allowed", "✗ fail"}, rows[1])
+	assert.Equal(t, []string{"privileged", "Description", "MITRE", "Tactic", "Technique", "critical", "hostPid", "allowed", "allowed", "✓ pass [1]"}, rows[2])
+}
+
+func TestFindings(t *testing.T) {
+	leakedEnv := testOutcome("leaked-env", verifier.Blocked, map[string]string{"env": verifier.Success})
+	leakedEnv.Score(verifier.Low)
+	clusterAdmin := testOutcome("cluster-admin", verifier.Blocked, map[string]string{"binding": verifier.Success})
+	clusterAdmin.Score(verifier.Critical)
+	detected := testOutcome("detected", verifier.Blocked, map[string]string{"exec": verifier.DetectedResult})
+	detected.Score(verifier.High)
+	passed := testOutcome("passed", verifier.Allowed, map[string]string{"exec": verifier.Success})
+	passed.Score(verifier.Critical)
+
+	r := New("run", []*verifier.LegacyOutcome{leakedEnv, clusterAdmin, detected, passed}, nil)
+	var names []string
+	for _, outcome := range Findings(r) {
+		names = append(names, outcome.Experiment)
+	}
+	assert.Equal(t, []string{"cluster-admin", "detected", "leaked-env"}, names)
+}
+
+func TestValidateFormat(t *testing.T) {
+	for _, format := range []string{"", "table", "json", "YAML"} {
+		assert.NoError(t, ValidateFormat(format))
+	}
+	assert.Error(t, ValidateFormat("xml"))
+}
@@ -0,0 +1,292 @@
+/*
+Copyright 2023 Operant AI
+*/
+package report
+
+import (
+	"fmt"
+	"regexp"
+	"sort"
+	"strings"
+
+	"github.com/operantai/woodpecker/internal/categories"
+	"github.com/operantai/woodpecker/internal/verifier"
+)
+
+const (
+	sarifVersion = "2.1.0"
+	sarifSchema  = "https://json.schemastore.org/sarif-2.1.0.json"
+	toolName     = "woodpecker"
+	toolURI      = "https://github.com/operantai/woodpecker"
+)
+
+// SARIF is the subset of a SARIF 2.1.0 log that woodpecker writes
+type SARIF struct {
+	Schema  string     `json:"$schema"`
+	Version string     `json:"version"`
+	Runs    []SARIFRun `json:"runs"`
+}
+
+type SARIFRun struct {
+	Tool              SARIFTool               `json:"tool"`
+	AutomationDetails *SARIFAutomationDetails `json:"automationDetails,omitempty"`
+	Invocations       []SARIFInvocation       `json:"invocations"`
+	Results           []SARIFResult           `json:"results"`
+}
+
+type SARIFTool struct {
+	Driver SARIFDriver `json:"driver"`
+}
+
+type SARIFDriver struct {
+	Name           string      `json:"name"`
+	InformationURI string      `json:"informationUri"`
+	Rules          []SARIFRule `json:"rules"`
+}
+
+type SARIFRule struct {
+	ID               string              `json:"id"`
+	Name             string              `json:"name"`
+	ShortDescription SARIFMessage        `json:"shortDescription"`
+	Help             SARIFMessage        `json:"help"`
+	Properties       SARIFRuleProperties `json:"properties"`
+}
+
+type SARIFRuleProperties struct {
+	Tags []string `json:"tags"`
+	// SecuritySeverity is the 0 to 10 score code scanning tools rank security findings by
+	SecuritySeverity string `json:"security-severity,omitempty"`
+}
+
+type SARIFAutomationDetails struct {
+	ID string `json:"id"`
+}
+
+type SARIFInvocation struct {
+	ExecutionSuccessful        bool                `json:"executionSuccessful"`
+	ToolExecutionNotifications []SARIFNotification `json:"toolExecutionNotifications,omitempty"`
+}
+
+type SARIFNotification struct {
+	Level   string       `json:"level"`
+	Message SARIFMessage `json:"message"`
+}
+
+type SARIFResult struct {
+	RuleID       string                `json:"ruleId"`
+	RuleIndex    int                   `json:"ruleIndex"`
+	Level        string                `json:"level"`
+	Message      SARIFMessage          `json:"message"`
+	Locations    []SARIFLocation       `json:"locations,omitempty"`
+	Suppressions []SARIFSuppression    `json:"suppressions,omitempty"`
+	Properties   SARIFResultProperties `json:"properties"`
+}
+
+// SARIFSuppression marks a result as an accepted risk, so that code scanning tools don't alert on it
+type SARIFSuppression struct {
+	Kind          string `json:"kind"`
+	Status        string `json:"status"`
+	Justification string `json:"justification"`
+}
+
+type SARIFResultProperties struct {
+	Experiment string            `json:"experiment"`
+	Test       string            `json:"test"`
+	Expect     verifier.Behavior `json:"expect"`
+	Observed   verifier.Behavior `json:"observed,omitempty"`
+	Severity   verifier.Severity `json:"severity,omitempty"`
+	RiskScore  int               `json:"riskScore"`
+}
+
+type SARIFMessage struct {
+	Text     string `json:"text"`
+	Markdown string `json:"markdown,omitempty"`
+}
+
+type SARIFLocation struct {
+	PhysicalLocation SARIFPhysicalLocation  `json:"physicalLocation"`
+	LogicalLocations []SARIFLogicalLocation `json:"logicalLocations,omitempty"`
+}
+
+type SARIFPhysicalLocation struct {
+	ArtifactLocation SARIFArtifactLocation `json:"artifactLocation"`
+}
+
+type SARIFArtifactLocation struct {
+	URI string `json:"uri"`
+}
+
+type SARIFLogicalLocation struct {
+	Name string `json:"name"`
+	Kind string `json:"kind"`
+}
+
+// securitySeverity maps severities to the scores code scanning tools bucket into the same severities
+var securitySeverity = map[verifier.Severity]string{
+	verifier.Low:      "3.0",
+	verifier.Medium:   "5.5",
+	verifier.High:     "8.0",
+	verifier.Critical: "9.5",
+}
+
+// nonSlug matches runs of characters that aren't allowed in a slug
+var nonSlug = regexp.MustCompile(`[^a-z0-9]+`)
+
+// slug lowercases s and replaces anything but letters and digits with dashes
+func slug(s string) string {
+	return strings.Trim(nonSlug.ReplaceAllString(strings.ToLower(s), "-"), "-")
+}
+
+// RuleID returns the SARIF rule ID of an experiment's technique, made of the MITRE ATT&CK or ATLAS ID of the
+// technique and its name, e.g. T1611/privileged-container. Techniques without an ID fall back to the ID of the
+// category they belong to.
+func RuleID(outcome *verifier.LegacyOutcome) string {
+	if id, ok := categories.TechniqueID(outcome.Framework, outcome.Tactic, outcome.Technique); ok {
+		return fmt.Sprintf("%s/%s", id, slug(outcome.Technique))
+	}
+	if id, ok := categories.CategoryID(outcome.Framework, outcome.Tactic, outcome.Technique); ok {
+		return fmt.Sprintf("%s/%s", id, slug(outcome.Technique))
+	}
+	return fmt.Sprintf("%s/%s/%s", slug(outcome.Framework), slug(outcome.Tactic), slug(outcome.Technique))
+}
+
+// NewSARIF converts the report to a SARIF log, with a result for every test that missed its expectation
+func NewSARIF(r *Report) *SARIF {
+	run := SARIFRun{
+		Tool: SARIFTool{
+			Driver: SARIFDriver{
+				Name:           toolName,
+				InformationURI: toolURI,
+				Rules:          []SARIFRule{},
+			},
+		},
+		Invocations: []SARIFInvocation{{ExecutionSuccessful: len(r.Errors) == 0}},
+		Results:     []SARIFResult{},
+	}
+	if r.RunID != "" {
+		run.AutomationDetails = &SARIFAutomationDetails{ID: fmt.Sprintf("%s/%s", toolName, r.RunID)}
+	}
+	for _, e := range r.Errors {
+		run.Invocations[0].ToolExecutionNotifications = append(run.Invocations[0].ToolExecutionNotifications, SARIFNotification{
+			Level:   "error",
+			Message: SARIFMessage{Text: fmt.Sprintf("Experiment %s: %s", e.Experiment, e.Error)},
+		})
+	}
+
+	ruleIndex := make(map[string]int)
+	for _, outcome := range r.Results {
+		tests := failedTests(outcome)
+		if len(tests) == 0 {
+			continue
+		}
+
+		id := RuleID(outcome)
+		index, ok := ruleIndex[id]
+		if !ok {
+			index = len(run.Tool.Driver.Rules)
+			ruleIndex[id] = index
+			run.Tool.Driver.Rules = append(run.Tool.Driver.Rules, SARIFRule{
+				ID:               id,
+				Name:             strings.ReplaceAll(outcome.Technique, " ", ""),
+				ShortDescription: SARIFMessage{Text: fmt.Sprintf("%s: %s", outcome.Tactic, outcome.Technique)},
+				Help:             sarifHelp(outcome),
+				Properties: SARIFRuleProperties{
+					Tags:             []string{"security", outcome.Framework, outcome.Tactic},
+					SecuritySeverity: securitySeverity[outcome.Severity],
+				},
+			})
+		}
+
+		for _, test := range tests {
+			observed := testObserved(outcome, test)
+			result := SARIFResult{
+				RuleID:    id,
+				RuleIndex: index,
+				Level:     "error",
+				Message: SARIFMessage{
+					Text: fmt.Sprintf("Experiment %s, test %s: the attack was %s, expected it to be %s", outcome.Experiment, test, observedText(observed), outcome.Expect),
+				},
+				Properties: SARIFResultProperties{
+					Experiment: outcome.Experiment,
+					Test:       test,
+					Expect:     outcome.Expect,
+					Observed:   observed,
+					Severity:   outcome.Severity,
+					RiskScore:  outcome.RiskScore,
+				},
+			}
+			if suppression, ok := outcome.TestSuppression(test); ok && !suppression.Expired {
+				result.Suppressions = []SARIFSuppression{{
+					Kind:          "external",
+					Status:        "accepted",
+					Justification: suppression.Justification,
+				}}
+			}
+			if outcome.Source != "" {
+				result.Locations = []SARIFLocation{{
+					PhysicalLocation: SARIFPhysicalLocation{ArtifactLocation: SARIFArtifactLocation{URI: outcome.Source}},
+					LogicalLocations: []SARIFLogicalLocation{{Name: outcome.Experiment, Kind: "module"}},
+				}}
+			}
+			run.Results = append(run.Results, result)
+		}
+	}
+
+	return &SARIF{
+		Schema:  sarifSchema,
+		Version: sarifVersion,
+		Runs:    []SARIFRun{run},
+	}
+}
+
+// sarifHelp returns the help of an experiment's rule, its description followed by its remediation if any
+func sarifHelp(outcome *verifier.LegacyOutcome) SARIFMessage {
+	if outcome.Remediation == nil {
+		return SARIFMessage{Text: outcome.Description}
+	}
+	var markdown strings.Builder
+	fmt.Fprintf(&markdown, "%s\n\n**Remediation:** %s\n", outcome.Description, outcome.Remediation.Summary)
+	for _, control := range outcome.Remediation.Controls {
+		fmt.Fprintf(&markdown, "\n- `%s`: %s\n", control.Kind, control.Description)
+		if control.Snippet != "" {
+			fmt.Fprintf(&markdown, "\n  ```yaml\n%s\n  ```\n", indent(control.Snippet, "  "))
+		}
+	}
+	return SARIFMessage{
+		Text:     fmt.Sprintf("%s\n\n%s", outcome.Description, remediationText(outcome.Remediation)),
+		Markdown: markdown.String(),
+	}
+}
+
+// indent prefixes every line of s
+func indent(s, prefix string) string {
+	return prefix + strings.ReplaceAll(s, "\n", "\n"+prefix)
+}
+
+// failedTests returns the sorted names of the tests of an outcome that missed their expectation, including
+// suppressed ones, an outcome without results that missed its expectation has a single Overall test
+func failedTests(outcome *verifier.LegacyOutcome) []string {
+	if len(outcome.Result) == 0 {
+		if outcome.Verdict == verifier.Pass {
+			return nil
+		}
+		return []string{"Overall"}
+	}
+	var tests []string
+	for test := range outcome.Result {
+		if outcome.TestVerdict(test) != verifier.Pass {
+			tests = append(tests, test)
+		}
+	}
+	sort.Strings(tests)
+	return tests
+}
+
+// observedText describes an observed behavior, which is empty when the experiment produced no results, or when a
+// test failed without the cluster refusing the attack
+func observedText(observed verifier.Behavior) string {
+	if observed == "" {
+		return "not observed"
+	}
+	return string(observed)
+}
@@ -0,0 +1,120 @@
+package report
+
+import (
+	"encoding/json"
+	"errors"
+	"testing"
+
+	"github.com/operantai/woodpecker/internal/categories"
+	"github.com/operantai/woodpecker/internal/verifier"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestRuleID(t *testing.T) {
+	privileged := &verifier.LegacyOutcome{
+		Framework: string(categories.Mitre),
+		Tactic:    categories.MITRE.PrivilegeEscalation.PrivilegedContainer.Tactic,
+		Technique: categories.MITRE.PrivilegeEscalation.PrivilegedContainer.Technique,
+	}
+	assert.Equal(t, "T1611/privileged-container", RuleID(privileged))
+
+	leakage := &verifier.LegacyOutcome{
+		Framework: string(categories.MitreAtlas),
+		Tactic:    categories.MITREATLAS.Exfiltration.LLMDataLeakage.Tactic,
+		Technique: categories.MITREATLAS.Exfiltration.LLMDataLeakage.Technique,
+	}
+	assert.Equal(t, "AML.T0057/llm-data-leakage", RuleID(leakage))
+
+	// Techniques without an ID fall back to the ID of their category
+	networking := &verifier.LegacyOutcome{
+		Framework: string(categories.Mitre),
+		Tactic:    categories.MITRE.LateralMovement.ClusterInternalNetworking.Tactic,
+		Technique: categories.MITRE.LateralMovement.ClusterInternalNetworking.Technique,
+	}
+	assert.Equal(t, "TA0008/cluster-internal-networking", RuleID(networking))
+
+	unknown := &verifier.LegacyOutcome{Framework: "OWASP", Tactic: "Injection", Technique: "Prompt Injection"}
+	assert.Equal(t, "owasp/injection/prompt-injection", RuleID(unknown))
+}
+
+func TestNewSARIF(t *testing.T) {
+	entry := categories.MITRE.PrivilegeEscalation.PrivilegedContainer
+	missed := verifier.NewLegacy("privileged", "Run a privileged container", string(categories.Mitre), entry.Tactic, entry.Technique)
+	missed.Success("hostPid")
+	missed.Blocked("hostNetwork")
+	missed.GetOutcome().Source = "experiments/privileged-container.yaml"
+	missed.GetOutcome().Evaluate(verifier.Blocked)
+
+	passed := verifier.NewLegacy("passed", "Run a privileged container", string(categories.Mitre), entry.Tactic, entry.Technique)
+	passed.Success("hostPid")
+	passed.GetOutcome().Evaluate(verifier.Allowed)
+
+	r := New("run", []*verifier.LegacyOutcome{missed.GetOutcome(), passed.GetOutcome()}, nil)
+	r.AddError("kube-exec", errors.New("connection refused"))
+	sarif := NewSARIF(r)
+
+	require.Len(t, sarif.Runs, 1)
+	run := sarif.Runs[0]
+	assert.Equal(t, "2.1.0", sarif.Version)
+	assert.Equal(t, "woodpecker/run", run.AutomationDetails.ID)
+	assert.False(t, run.Invocations[0].ExecutionSuccessful)
+	assert.Len(t, run.Invocations[0].ToolExecutionNotifications, 1)
+
+	require.Len(t, run.Tool.Driver.Rules, 1)
+	assert.Equal(t, "T1611/privileged-container", run.Tool.Driver.Rules[0].ID)
+	assert.Equal(t, "Run a privileged container", run.Tool.Driver.Rules[0].Help.Text)
+
+	// Only the test that got through when it should have been blocked is a result
+	require.Len(t, run.Results, 1)
+	result := run.Results[0]
+	assert.Equal(t, "T1611/privileged-container", result.RuleID)
+	assert.Equal(t, 0, result.RuleIndex)
+	assert.Equal(t, "error", result.Level)
+	assert.Equal(t, "hostPid", result.Properties.Test)
+	assert.Equal(t, verifier.Allowed, result.Properties.Observed)
+	assert.Equal(t, "experiments/privileged-container.yaml", result.Locations[0].PhysicalLocation.ArtifactLocation.URI)
+
+	contents, err := json.Marshal(sarif)
+	require.NoError(t, err)
+	assert.Contains(t, string(contents), `"$schema":"https://json.schemastore.org/sarif-2.1.0.json"`)
+}
+
+func TestNewSARIFRemediation(t *testing.T) {
+	entry := categories.MITRE.PrivilegeEscalation.PrivilegedContainer
+	missed := verifier.NewLegacy("privileged", "Run a privileged container", string(categories.Mitre), entry.Tactic, entry.Technique)
+	missed.Success("hostPid")
+	missed.GetOutcome().Evaluate(verifier.Blocked)
+	missed.GetOutcome().Score(verifier.Critical)
+	missed.GetOutcome().Remediate(&verifier.Remediation{
+		Summary: "Refuse privileged containers",
+		Controls: []verifier.Control{{
+			Kind:        verifier.PodSecurityAdmission,
+			Description: "Enforce the baseline Pod Security Standard",
+			Snippet:     "pod-security.kubernetes.io/enforce: baseline",
+		}},
+	})
+
+	run := NewSARIF(New("run", []*verifier.LegacyOutcome{missed.GetOutcome()}, nil)).Runs[0]
+	require.Len(t, run.Tool.Driver.Rules, 1)
+	rule := run.Tool.Driver.Rules[0]
+	assert.Equal(t, "9.5", rule.Properties.SecuritySeverity)
+	assert.Contains(t, rule.Help.Text, "Remediation: Refuse privileged containers")
+	assert.Contains(t, rule.Help.Markdown, "- `pod-security-admission`: Enforce the baseline Pod Security Standard")
+	assert.Contains(t, rule.Help.Markdown, "  ```yaml\n  pod-security.kubernetes.io/enforce: baseline\n  ```")
+}
+
+func TestNewSARIFSuppressed(t *testing.T) {
+	v := verifier.NewLegacy("host-path", "Mount a host path", "MITRE", "Privilege Escalation", "Host Path Mount")
+	v.Success("logging")
+	v.Success("root")
+	v.GetOutcome().Evaluate(verifier.Blocked)
+	v.GetOutcome().Suppress("logging", &verifier.Suppression{Justification: "Log shipper"})
+	v.GetOutcome().Suppress("root", &verifier.Suppression{Justification: "Legacy", Expired: true})
+
+	results := NewSARIF(New("run", []*verifier.LegacyOutcome{v.GetOutcome()}, nil)).Runs[0].Results
+	require.Len(t, results, 2)
+	// Suppressed results are still reported, marked as accepted
+	assert.Equal(t, []SARIFSuppression{{Kind: "external", Status: "accepted", Justification: "Log shipper"}}, results[0].Suppressions)
+	assert.Empty(t, results[1].Suppressions)
+}
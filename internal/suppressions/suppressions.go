@@ -0,0 +1,111 @@
+/*
+Copyright 2023 Operant AI
+*/
+package suppressions
+
+import (
+	"bytes"
+	"fmt"
+	"os"
+	"time"
+
+	"github.com/operantai/woodpecker/internal/verifier"
+	"gopkg.in/yaml.v3"
+)
+
+// dateFormat is the format of expiry dates in a suppression file
+const dateFormat = "2006-01-02"
+
+// File is a suppression file, listing the results that are accepted risks
+type File struct {
+	Suppressions []Suppression `yaml:"suppressions"`
+}
+
+// Suppression accepts the risk of an experiment's tests missing their expectation until it expires
+type Suppression struct {
+	// Experiment is the name of the experiment to suppress
+	Experiment string `yaml:"experiment"`
+	// Test is the test of the experiment to suppress, all tests of the experiment are suppressed if it is empty
+	Test string `yaml:"test"`
+	// Namespace only suppresses the experiment when it runs in the given namespace, if set
+	Namespace string `yaml:"namespace"`
+	// Expires is the last day the suppression applies on, as YYYY-MM-DD
+	Expires string `yaml:"expires"`
+	// Justification is why the risk is accepted
+	Justification string `yaml:"justification"`
+
+	expires time.Time
+}
+
+// Load reads and validates a suppression file
+func Load(path string) (*File, error) {
+	contents, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("Could not read suppression file: %w", err)
+	}
+	f, err := parse(contents)
+	if err != nil {
+		return nil, fmt.Errorf("Invalid suppression file %s: %w", path, err)
+	}
+	return f, nil
+}
+
+func parse(contents []byte) (*File, error) {
+	f := &File{}
+	decoder := yaml.NewDecoder(bytes.NewReader(contents))
+	decoder.KnownFields(true)
+	if err := decoder.Decode(f); err != nil {
+		return nil, err
+	}
+
+	for i := range f.Suppressions {
+		s := &f.Suppressions[i]
+		switch {
+		case s.Experiment == "":
+			return nil, fmt.Errorf("suppression %d is missing an experiment", i+1)
+		case s.Justification == "":
+			return nil, fmt.Errorf("suppression of experiment %s is missing a justification", s.Experiment)
+		case s.Expires == "":
+			return nil, fmt.Errorf("suppression of experiment %s is missing an expiry date", s.Experiment)
+		}
+		expires, err := time.Parse(dateFormat, s.Expires)
+		if err != nil {
+			return nil, fmt.Errorf("suppression of experiment %s has an invalid expiry date %q, must be YYYY-MM-DD", s.Experiment, s.Expires)
+		}
+		s.expires = expires
+	}
+	return f, nil
+}
+
+// Apply suppresses the tests of an evaluated outcome that match a suppression, for an experiment run in the
+// given namespace. Suppressions expire at the end of their expiry date in UTC, an expired suppression is only
+// flagged while the tests it matches still miss their expectation, and is dropped once they meet it.
+func (f *File) Apply(outcome *verifier.LegacyOutcome, namespace string, now time.Time) {
+	if f == nil {
+		return
+	}
+	for _, s := range f.Suppressions {
+		if s.Experiment != outcome.Experiment || (s.Namespace != "" && s.Namespace != namespace) {
+			continue
+		}
+		expired := !now.UTC().Before(s.expires.AddDate(0, 0, 1))
+		if expired && !missesExpectation(outcome, s.Test) {
+			continue
+		}
+		outcome.Suppress(s.Test, &verifier.Suppression{
+			Justification: s.Justification,
+			Expires:       s.expires,
+			Expired:       expired,
+		})
+	}
+}
+
+// missesExpectation reports whether a test of an evaluated outcome, or the whole experiment if test is empty,
+// missed its expectation, regardless of any suppression
+func missesExpectation(outcome *verifier.LegacyOutcome, test string) bool {
+	if test == "" {
+		return outcome.Observed == "" || !outcome.Observed.Satisfies(outcome.Expect)
+	}
+	result, ok := outcome.Result[test]
+	return !ok || !verifier.ResultBehavior(result).Satisfies(outcome.Expect)
+}
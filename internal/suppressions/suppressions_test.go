@@ -0,0 +1,137 @@
+package suppressions
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/operantai/woodpecker/internal/verifier"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestParse(t *testing.T) {
+	tests := []struct {
+		name        string
+		contents    string
+		expectError bool
+	}{
+		{
+			name: "Valid",
+			contents: `
+suppressions:
+  - experiment: host-path-mount
+    test: logging
+    namespace: logging
+    expires: 2030-01-31
+    justification: The log shipper reads container logs from the host`,
+		},
+		{
+			name: "Missing justification",
+			contents: `
+suppressions:
+  - experiment: host-path-mount
+    expires: 2030-01-31`,
+			expectError: true,
+		},
+		{
+			name: "Missing expiry date",
+			contents: `
+suppressions:
+  - experiment: host-path-mount
+    justification: Accepted`,
+			expectError: true,
+		},
+		{
+			name: "Invalid expiry date",
+			contents: `
+suppressions:
+  - experiment: host-path-mount
+    expires: 31/01/2030
+    justification: Accepted`,
+			expectError: true,
+		},
+		{
+			name: "Unknown field",
+			contents: `
+suppressions:
+  - experiment: host-path-mount
+    expiry: 2030-01-31
+    justification: Accepted`,
+			expectError: true,
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			_, err := parse([]byte(test.contents))
+			if test.expectError {
+				assert.Error(t, err)
+			} else {
+				assert.NoError(t, err)
+			}
+		})
+	}
+}
+
+func TestApply(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "suppressions.yaml")
+	require.NoError(t, os.WriteFile(path, []byte(`
+suppressions:
+  - experiment: host-path-mount
+    namespace: logging
+    expires: 2030-01-31
+    justification: The log shipper reads container logs from the host
+  - experiment: privileged-container
+    test: hostPid
+    expires: 2030-01-31
+    justification: Node exporter
+`), 0600))
+	f, err := Load(path)
+	require.NoError(t, err)
+
+	outcome := func(name string) *verifier.LegacyOutcome {
+		v := verifier.NewLegacy(name, "Experiment", "MITRE", "Tactic", "Technique")
+		v.Success("hostPid")
+		v.GetOutcome().Evaluate(verifier.Blocked)
+		return v.GetOutcome()
+	}
+	now := time.Date(2030, 1, 31, 23, 59, 0, 0, time.UTC)
+
+	hostPath := outcome("host-path-mount")
+	f.Apply(hostPath, "logging", now)
+	assert.Equal(t, verifier.Suppressed, hostPath.Verdict)
+	assert.Equal(t, "The log shipper reads container logs from the host", hostPath.Suppressions[""].Justification)
+
+	// Suppressions only apply in their namespace
+	elsewhere := outcome("host-path-mount")
+	f.Apply(elsewhere, "default", now)
+	assert.Equal(t, verifier.Failed, elsewhere.Verdict)
+	assert.Empty(t, elsewhere.Suppressions)
+
+	privileged := outcome("privileged-container")
+	f.Apply(privileged, "default", now)
+	assert.Equal(t, verifier.Suppressed, privileged.TestVerdict("hostPid"))
+
+	// Suppressions expire once their expiry date is over
+	expired := outcome("privileged-container")
+	f.Apply(expired, "default", now.Add(time.Minute))
+	assert.True(t, expired.Suppressions["hostPid"].Expired)
+	assert.Equal(t, verifier.Failed, expired.Verdict)
+
+	// Expired suppressions of tests that now meet their expectation are dropped rather than failing the run
+	v := verifier.NewLegacy("privileged-container", "Experiment", "MITRE", "Tactic", "Technique")
+	v.Blocked("hostPid")
+	fixed := v.GetOutcome()
+	fixed.Evaluate(verifier.Blocked)
+	f.Apply(fixed, "default", now.Add(time.Minute))
+	assert.Empty(t, fixed.Suppressions)
+	assert.Equal(t, verifier.Pass, fixed.Verdict)
+
+	// A missing suppression file suppresses nothing
+	var none *File
+	unsuppressed := outcome("privileged-container")
+	none.Apply(unsuppressed, "default", now)
+	assert.Equal(t, verifier.Failed, unsuppressed.Verdict)
+}
@@ -0,0 +1,37 @@
+package verifier
+
+// ControlKind is the kind of control a remediation puts in place
+type ControlKind string
+
+const (
+	PodSecurityAdmission ControlKind = "pod-security-admission"
+	Kyverno              ControlKind = "kyverno"
+	Gatekeeper           ControlKind = "gatekeeper"
+	RBAC                 ControlKind = "rbac"
+	NetworkPolicy        ControlKind = "network-policy"
+	// Configuration covers changes to workloads or the applications themselves
+	Configuration ControlKind = "configuration"
+)
+
+// Remediation is what to change so that the attack an experiment performs no longer goes through
+type Remediation struct {
+	Summary  string    `json:"summary" yaml:"summary"`
+	Controls []Control `json:"controls,omitempty" yaml:"controls,omitempty"`
+}
+
+// Control is a single change that remediates an attack, with a sample manifest or policy to start from
+type Control struct {
+	Kind        ControlKind `json:"kind" yaml:"kind"`
+	Description string      `json:"description" yaml:"description"`
+	Snippet     string      `json:"snippet,omitempty" yaml:"snippet,omitempty"`
+}
+
+// Remediate attaches the remediation to the outcome if the attack got through, whether it was expected to or not.
+// Evaluate must have been called first. Attacks that were blocked need no remediation.
+func (r *LegacyOutcome) Remediate(remediation *Remediation) {
+	if r.Observed != Allowed && r.Observed != Detected {
+		r.Remediation = nil
+		return
+	}
+	r.Remediation = remediation
+}
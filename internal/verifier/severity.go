@@ -0,0 +1,54 @@
+package verifier
+
+import "fmt"
+
+// Severity is how much damage the attack an experiment performs does when it goes through
+type Severity string
+
+const (
+	Low      Severity = "low"
+	Medium   Severity = "medium"
+	High     Severity = "high"
+	Critical Severity = "critical"
+)
+
+// severityWeights are the risk scores of attacks that went through, by severity
+var severityWeights = map[Severity]int{
+	Low:      25,
+	Medium:   50,
+	High:     75,
+	Critical: 100,
+}
+
+// ParseSeverity parses the severity of an experiment, an empty string is returned as is and means the
+// experiment's default severity
+func ParseSeverity(s string) (Severity, error) {
+	switch Severity(s) {
+	case "", Low, Medium, High, Critical:
+		return Severity(s), nil
+	default:
+		return "", fmt.Errorf("unknown severity %q, must be one of %s, %s, %s or %s", s, Low, Medium, High, Critical)
+	}
+}
+
+// AtLeast reports whether the severity is as high as other, an unknown severity is lower than any other
+func (s Severity) AtLeast(other Severity) bool {
+	return severityWeights[s] >= severityWeights[other]
+}
+
+// Score records the severity of the experiment, and derives its risk score from the severity and the behavior
+// observed by Evaluate. An attack that went through scores the full weight of its severity, one that was
+// detected half of it, and one that was blocked nothing. An attack whose behavior couldn't be observed scores
+// as if it went through.
+func (r *LegacyOutcome) Score(severity Severity) {
+	r.Severity = severity
+	weight := severityWeights[severity]
+	switch r.Observed {
+	case Blocked:
+		r.RiskScore = 0
+	case Detected:
+		r.RiskScore = weight / 2
+	default:
+		r.RiskScore = weight
+	}
+}
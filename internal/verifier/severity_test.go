@@ -0,0 +1,45 @@
+package verifier
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestParseSeverity(t *testing.T) {
+	severity, err := ParseSeverity("")
+	assert.NoError(t, err)
+	assert.Equal(t, Severity(""), severity)
+
+	severity, err = ParseSeverity("critical")
+	assert.NoError(t, err)
+	assert.Equal(t, Critical, severity)
+
+	_, err = ParseSeverity("severe")
+	assert.Error(t, err)
+}
+
+func TestScore(t *testing.T) {
+	tests := []struct {
+		name      string
+		severity  Severity
+		observed  Behavior
+		riskScore int
+	}{
+		{name: "Allowed critical attack", severity: Critical, observed: Allowed, riskScore: 100},
+		{name: "Detected critical attack", severity: Critical, observed: Detected, riskScore: 50},
+		{name: "Blocked critical attack", severity: Critical, observed: Blocked, riskScore: 0},
+		{name: "Allowed low attack", severity: Low, observed: Allowed, riskScore: 25},
+		{name: "Unobserved high attack", severity: High, riskScore: 75},
+		{name: "Unknown severity", observed: Allowed, riskScore: 0},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			outcome := &LegacyOutcome{Observed: test.observed}
+			outcome.Score(test.severity)
+			assert.Equal(t, test.severity, outcome.Severity)
+			assert.Equal(t, test.riskScore, outcome.RiskScore)
+		})
+	}
+}
@@ -0,0 +1,282 @@
+package verifier
+
+import (
+	"fmt"
+	"strconv"
+	"time"
+)
+
+// Behavior is how the cluster responded to the attack an experiment performs
+type Behavior string
+
+const (
+	// Allowed means the attack went through
+	Allowed Behavior = "allowed"
+	// Blocked means the attack was prevented, e.g. by RBAC or an admission controller
+	Blocked Behavior = "blocked"
+	// Detected means the attack went through but was noticed by a detection tool
+	Detected Behavior = "detected"
+)
+
+// ParseBehavior parses the expected behavior of an experiment, an empty string means Allowed
+func ParseBehavior(s string) (Behavior, error) {
+	switch Behavior(s) {
+	case "":
+		return Allowed, nil
+	case Allowed, Blocked, Detected:
+		return Behavior(s), nil
+	default:
+		return "", fmt.Errorf("unknown expectation %q, must be one of %s, %s or %s", s, Allowed, Blocked, Detected)
+	}
+}
+
+// Satisfies reports whether observing b meets the expectation. An attack that was blocked
+// outright also satisfies an expectation of it being detected.
+func (b Behavior) Satisfies(expect Behavior) bool {
+	return b == expect || (expect == Detected && b == Blocked)
+}
+
+// Verdict is whether an experiment observed the behavior it expected
+type Verdict string
+
+const (
+	// Pass means the observed behavior met the expectation
+	Pass Verdict = "pass"
+	// Failed means the observed behavior didn't meet the expectation, or couldn't be determined
+	Failed Verdict = "fail"
+	// Suppressed means the observed behavior didn't meet the expectation, but that is an accepted risk
+	Suppressed Verdict = "suppressed"
+)
+
+// Suppression accepts the risk of an experiment's tests missing their expectation until it expires
+type Suppression struct {
+	Justification string    `json:"justification" yaml:"justification"`
+	Expires       time.Time `json:"expires" yaml:"expires"`
+	// Expired suppressions no longer accept the risk, and fail the tests they suppressed
+	Expired bool `json:"expired,omitempty" yaml:"expired,omitempty"`
+}
+
+// ResultBehavior maps the result of a single test to the behavior it observed. Only a refusal by the cluster counts
+// as Blocked, any other failure, e.g. a command whose output didn't match, observed nothing and maps to "".
+func ResultBehavior(result string) Behavior {
+	switch result {
+	case Success:
+		return Allowed
+	case DetectedResult:
+		return Detected
+	case BlockedResult:
+		return Blocked
+	default:
+		return ""
+	}
+}
+
+// Evaluate records the expected behavior of the experiment, and derives the observed behavior and verdict
+// from its results. An Observed behavior that is already set, e.g. because the experiment was blocked
+// before it could produce results, is kept. The attack counts as allowed if any of its tests got through, and
+// as blocked only if all of them were refused. Otherwise nothing was observed, and the experiment fails.
+func (r *LegacyOutcome) Evaluate(expect Behavior) {
+	if expect == "" {
+		expect = Allowed
+	}
+	r.Expect = expect
+	if r.Observed == "" && len(r.Result) > 0 {
+		counts := make(map[Behavior]int)
+		for _, result := range r.Result {
+			counts[ResultBehavior(result)]++
+		}
+		switch {
+		case counts[Allowed] > 0:
+			r.Observed = Allowed
+		case counts[Detected] > 0:
+			r.Observed = Detected
+		case counts[Blocked] == len(r.Result):
+			r.Observed = Blocked
+		}
+	}
+
+	r.evaluateVerdict()
+}
+
+// Suppress suppresses a test of the experiment, or all of its tests if test is empty, and re-evaluates
+// the verdict of the experiment. Evaluate must have been called first.
+func (r *LegacyOutcome) Suppress(test string, s *Suppression) {
+	if r.Suppressions == nil {
+		r.Suppressions = make(map[string]*Suppression)
+	}
+	r.Suppressions[test] = s
+	r.evaluateVerdict()
+}
+
+// evaluateVerdict derives the verdict of the experiment from the observed behavior and its suppressions.
+// A failing experiment is suppressed if all of its failing tests are, and any expired suppression keeps it failed.
+// A passing experiment stays passed, whether or not its suppressions expired.
+func (r *LegacyOutcome) evaluateVerdict() {
+	r.Verdict = Failed
+	if r.Observed != "" && r.Observed.Satisfies(r.Expect) {
+		r.Verdict = Pass
+	}
+	if r.Verdict == Pass || len(r.Suppressions) == 0 {
+		return
+	}
+
+	for _, s := range r.Suppressions {
+		if s.Expired {
+			return
+		}
+	}
+	if len(r.Result) == 0 {
+		if r.suppression("") != nil {
+			r.Verdict = Suppressed
+		}
+		return
+	}
+	for test := range r.Result {
+		if r.TestVerdict(test) == Failed {
+			return
+		}
+	}
+	// Every test passed or is suppressed, but the experiment as a whole still missed its expectation, e.g. an
+	// attack expected to be allowed was blocked by every test. The missed expectation is what was suppressed.
+	r.Verdict = Suppressed
+}
+
+// suppression returns the suppression of a test, or of the whole experiment
+func (r *LegacyOutcome) suppression(test string) *Suppression {
+	if s, ok := r.Suppressions[test]; ok {
+		return s
+	}
+	return r.Suppressions[""]
+}
+
+// TestSuppression returns the suppression that applies to a test of the experiment, if any
+func (r *LegacyOutcome) TestSuppression(test string) (*Suppression, bool) {
+	s := r.suppression(test)
+	return s, s != nil
+}
+
+// TestVerdict returns the verdict of a single test of the experiment, Evaluate must have been called first
+func (r *LegacyOutcome) TestVerdict(test string) Verdict {
+	verdict := Failed
+	if result, ok := r.Result[test]; ok && ResultBehavior(result).Satisfies(r.Expect) {
+		verdict = Pass
+	}
+	s := r.suppression(test)
+	switch {
+	case s == nil, verdict == Pass:
+		return verdict
+	case s.Expired:
+		return Failed
+	}
+	return Suppressed
+}
+
+// Summary counts the verdicts of a set of experiments and of their individual tests
+type Summary struct {
+	Experiments int `json:"experiments" yaml:"experiments"`
+	Passed      int `json:"passed" yaml:"passed"`
+	Failed      int `json:"failed" yaml:"failed"`
+	// Suppressed counts experiments that missed their expectation as an accepted risk, they don't count as failed
+	Suppressed int `json:"suppressed" yaml:"suppressed"`
+	// Errors counts experiments that couldn't be run or verified, and so have no verdict
+	Errors      int `json:"errors" yaml:"errors"`
+	Tests       int `json:"tests" yaml:"tests"`
+	TestsPassed int `json:"testsPassed" yaml:"testsPassed"`
+	TestsFailed int `json:"testsFailed" yaml:"testsFailed"`
+	// TestsSuppressed counts tests that missed their expectation as an accepted risk
+	TestsSuppressed int `json:"testsSuppressed" yaml:"testsSuppressed"`
+	// FailedBySeverity counts the failed experiments of each severity
+	FailedBySeverity map[Severity]int `json:"failedBySeverity,omitempty" yaml:"failedBySeverity,omitempty"`
+}
+
+// Summarize counts the verdicts of evaluated outcomes. An experiment that was blocked before producing
+// any results counts as a single test.
+func Summarize(outcomes []*LegacyOutcome) Summary {
+	var s Summary
+	for _, outcome := range outcomes {
+		s.Experiments++
+		switch outcome.Verdict {
+		case Pass:
+			s.Passed++
+		case Suppressed:
+			s.Suppressed++
+		default:
+			s.Failed++
+			if outcome.Severity != "" {
+				if s.FailedBySeverity == nil {
+					s.FailedBySeverity = make(map[Severity]int)
+				}
+				s.FailedBySeverity[outcome.Severity]++
+			}
+		}
+
+		if len(outcome.Result) == 0 {
+			if outcome.Observed == "" {
+				continue
+			}
+			s.countTest(outcome.Verdict)
+			continue
+		}
+		for test := range outcome.Result {
+			s.countTest(outcome.TestVerdict(test))
+		}
+	}
+	return s
+}
+
+// countTest counts the verdict of a single test
+func (s *Summary) countTest(verdict Verdict) {
+	s.Tests++
+	switch verdict {
+	case Pass:
+		s.TestsPassed++
+	case Suppressed:
+		s.TestsSuppressed++
+	default:
+		s.TestsFailed++
+	}
+}
+
+// FailOn is the number of experiments missing their expectation at which a command fails, or the severity
+// at which a single one does
+type FailOn struct {
+	count    int
+	severity Severity
+}
+
+// ParseFailOn parses a failure threshold, either any, none, a severity, or the number of experiments
+// that have to miss their expectation for a command to fail
+func ParseFailOn(s string) (FailOn, error) {
+	switch s {
+	case "", "any":
+		return FailOn{count: 1}, nil
+	case "none":
+		return FailOn{}, nil
+	}
+	if severity, err := ParseSeverity(s); err == nil {
+		return FailOn{count: 1, severity: severity}, nil
+	}
+	count, err := strconv.Atoi(s)
+	if err != nil || count < 1 {
+		return FailOn{}, fmt.Errorf("invalid failure threshold %q, must be any, none, a severity or a positive number of experiments", s)
+	}
+	return FailOn{count: count}, nil
+}
+
+// Exceeded reports whether enough experiments in the summary missed their expectation to fail. With a severity
+// threshold only experiments at least that severe count.
+func (f FailOn) Exceeded(s Summary) bool {
+	if f.count == 0 {
+		return false
+	}
+	if f.severity == "" {
+		return s.Failed >= f.count
+	}
+	failed := 0
+	for severity, count := range s.FailedBySeverity {
+		if severity.AtLeast(f.severity) {
+			failed += count
+		}
+	}
+	return failed >= f.count
+}
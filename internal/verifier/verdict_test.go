@@ -0,0 +1,314 @@
+package verifier
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestEvaluate(t *testing.T) {
+	tests := []struct {
+		name             string
+		expect           Behavior
+		results          map[string]string
+		observed         Behavior
+		expectedObserved Behavior
+		expectedVerdict  Verdict
+	}{
+		{
+			name:             "Attack allowed as expected",
+			expect:           Allowed,
+			results:          map[string]string{"hostPid": Success, "hostNetwork": Fail},
+			expectedObserved: Allowed,
+			expectedVerdict:  Pass,
+		},
+		{
+			name:             "Attack allowed when expected to be blocked",
+			expect:           Blocked,
+			results:          map[string]string{"hostPid": Success, "hostNetwork": Fail},
+			expectedObserved: Allowed,
+			expectedVerdict:  Failed,
+		},
+		{
+			name:             "Every test blocked",
+			expect:           Blocked,
+			results:          map[string]string{"hostPid": BlockedResult, "hostNetwork": BlockedResult},
+			expectedObserved: Blocked,
+			expectedVerdict:  Pass,
+		},
+		{
+			name:             "Failed tests don't count as blocked",
+			expect:           Blocked,
+			results:          map[string]string{"hostPid": BlockedResult, "exec": Fail},
+			expectedObserved: "",
+			expectedVerdict:  Failed,
+		},
+		{
+			name:             "Blocked before producing results",
+			expect:           Blocked,
+			observed:         Blocked,
+			expectedObserved: Blocked,
+			expectedVerdict:  Pass,
+		},
+		{
+			name:             "Blocked satisfies detected",
+			expect:           Detected,
+			results:          map[string]string{"exec": BlockedResult},
+			expectedObserved: Blocked,
+			expectedVerdict:  Pass,
+		},
+		{
+			name:             "Detected doesn't satisfy blocked",
+			expect:           Blocked,
+			results:          map[string]string{"exec": DetectedResult},
+			expectedObserved: Detected,
+			expectedVerdict:  Failed,
+		},
+		{
+			name:            "No results",
+			expect:          Allowed,
+			expectedVerdict: Failed,
+		},
+		{
+			name:             "Expectation defaults to allowed",
+			results:          map[string]string{"exec": Success},
+			expectedObserved: Allowed,
+			expectedVerdict:  Pass,
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			v := NewLegacy("experiment", "Experiment", "MITRE", "Tactic", "Technique")
+			outcome := v.GetOutcome()
+			outcome.Observed = test.observed
+			for name, result := range test.results {
+				outcome.Result[name] = result
+			}
+
+			outcome.Evaluate(test.expect)
+			assert.Equal(t, test.expectedObserved, outcome.Observed)
+			assert.Equal(t, test.expectedVerdict, outcome.Verdict)
+		})
+	}
+}
+
+func TestTestVerdict(t *testing.T) {
+	v := NewLegacy("experiment", "Experiment", "MITRE", "Tactic", "Technique")
+	v.Success("hostPid")
+	v.Blocked("hostNetwork")
+	v.Fail("exec")
+	outcome := v.GetOutcome()
+	outcome.Evaluate(Blocked)
+
+	assert.Equal(t, Failed, outcome.TestVerdict("hostPid"))
+	assert.Equal(t, Pass, outcome.TestVerdict("hostNetwork"))
+	assert.Equal(t, Failed, outcome.TestVerdict("exec"))
+	assert.Equal(t, Failed, outcome.TestVerdict("unknown"))
+}
+
+func TestSuppress(t *testing.T) {
+	accepted := func() *Suppression { return &Suppression{Justification: "Accepted"} }
+	expired := func() *Suppression { return &Suppression{Justification: "Accepted", Expired: true} }
+
+	tests := []struct {
+		name                 string
+		expect               Behavior
+		results              map[string]string
+		suppressions         map[string]*Suppression
+		expectedVerdict      Verdict
+		expectedTestVerdicts map[string]Verdict
+	}{
+		{
+			name:                 "Failing test suppressed",
+			expect:               Blocked,
+			results:              map[string]string{"hostPid": Success, "hostNetwork": BlockedResult},
+			suppressions:         map[string]*Suppression{"hostPid": accepted()},
+			expectedVerdict:      Suppressed,
+			expectedTestVerdicts: map[string]Verdict{"hostPid": Suppressed, "hostNetwork": Pass},
+		},
+		{
+			name:                 "Another failing test isn't suppressed",
+			expect:               Blocked,
+			results:              map[string]string{"hostPid": Success, "hostNetwork": Success},
+			suppressions:         map[string]*Suppression{"hostPid": accepted()},
+			expectedVerdict:      Failed,
+			expectedTestVerdicts: map[string]Verdict{"hostPid": Suppressed, "hostNetwork": Failed},
+		},
+		{
+			name:                 "Whole experiment suppressed",
+			expect:               Blocked,
+			results:              map[string]string{"hostPid": Success, "hostNetwork": Success},
+			suppressions:         map[string]*Suppression{"": accepted()},
+			expectedVerdict:      Suppressed,
+			expectedTestVerdicts: map[string]Verdict{"hostPid": Suppressed, "hostNetwork": Suppressed},
+		},
+		{
+			name:                 "Passing test stays passed",
+			expect:               Blocked,
+			results:              map[string]string{"hostPid": BlockedResult},
+			suppressions:         map[string]*Suppression{"": accepted()},
+			expectedVerdict:      Pass,
+			expectedTestVerdicts: map[string]Verdict{"hostPid": Pass},
+		},
+		{
+			name:                 "Expired suppression fails",
+			expect:               Blocked,
+			results:              map[string]string{"hostPid": Success},
+			suppressions:         map[string]*Suppression{"hostPid": expired()},
+			expectedVerdict:      Failed,
+			expectedTestVerdicts: map[string]Verdict{"hostPid": Failed},
+		},
+		{
+			name:                 "Expired suppression of a passing test stays passed",
+			expect:               Blocked,
+			results:              map[string]string{"hostPid": BlockedResult, "hostNetwork": BlockedResult},
+			suppressions:         map[string]*Suppression{"hostPid": expired()},
+			expectedVerdict:      Pass,
+			expectedTestVerdicts: map[string]Verdict{"hostPid": Pass, "hostNetwork": Pass},
+		},
+		{
+			name:            "Experiment without results suppressed",
+			expect:          Allowed,
+			suppressions:    map[string]*Suppression{"": accepted()},
+			expectedVerdict: Suppressed,
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			outcome := NewLegacy("experiment", "Experiment", "MITRE", "Tactic", "Technique").GetOutcome()
+			for name, result := range test.results {
+				outcome.Result[name] = result
+			}
+			outcome.Evaluate(test.expect)
+			for name, s := range test.suppressions {
+				outcome.Suppress(name, s)
+			}
+
+			assert.Equal(t, test.expectedVerdict, outcome.Verdict)
+			for name, verdict := range test.expectedTestVerdicts {
+				assert.Equal(t, verdict, outcome.TestVerdict(name), "test %s", name)
+			}
+		})
+	}
+}
+
+func TestParseBehavior(t *testing.T) {
+	behavior, err := ParseBehavior("")
+	assert.NoError(t, err)
+	assert.Equal(t, Allowed, behavior)
+
+	behavior, err = ParseBehavior("blocked")
+	assert.NoError(t, err)
+	assert.Equal(t, Blocked, behavior)
+
+	behavior, err = ParseBehavior("detected")
+	assert.NoError(t, err)
+	assert.Equal(t, Detected, behavior)
+
+	_, err = ParseBehavior("prevented")
+	assert.Error(t, err)
+}
+
+func TestSummarize(t *testing.T) {
+	passed := NewLegacy("passed", "Experiment", "MITRE", "Tactic", "Technique")
+	passed.Success("hostPid")
+	passed.Success("hostNetwork")
+	passed.GetOutcome().Evaluate(Allowed)
+
+	missed := NewLegacy("missed", "Experiment", "MITRE", "Tactic", "Technique")
+	missed.Success("hostPid")
+	missed.Blocked("hostNetwork")
+	missed.GetOutcome().Evaluate(Blocked)
+
+	blocked := NewLegacy("blocked", "Experiment", "MITRE", "Tactic", "Technique").GetOutcome()
+	blocked.Observed = Blocked
+	blocked.Evaluate(Blocked)
+
+	suppressed := NewLegacy("suppressed", "Experiment", "MITRE", "Tactic", "Technique")
+	suppressed.Success("hostPid")
+	suppressed.GetOutcome().Evaluate(Blocked)
+	suppressed.GetOutcome().Suppress("", &Suppression{Justification: "Accepted"})
+
+	summary := Summarize([]*LegacyOutcome{passed.GetOutcome(), missed.GetOutcome(), blocked, suppressed.GetOutcome()})
+	assert.Equal(t, Summary{
+		Experiments:     4,
+		Passed:          2,
+		Failed:          1,
+		Suppressed:      1,
+		Tests:           6,
+		TestsPassed:     4,
+		TestsFailed:     1,
+		TestsSuppressed: 1,
+	}, summary)
+}
+
+func TestFailOn(t *testing.T) {
+	tests := []struct {
+		name        string
+		failOn      string
+		failed      int
+		bySeverity  map[Severity]int
+		expectError bool
+		exceeded    bool
+	}{
+		{name: "Any with no failures", failOn: "any", failed: 0, exceeded: false},
+		{name: "Any with a failure", failOn: "any", failed: 1, exceeded: true},
+		{name: "Defaults to any", failOn: "", failed: 1, exceeded: true},
+		{name: "None", failOn: "none", failed: 5, exceeded: false},
+		{name: "Below count", failOn: "3", failed: 2, exceeded: false},
+		{name: "At count", failOn: "3", failed: 3, exceeded: true},
+		{name: "Zero count", failOn: "0", expectError: true},
+		{name: "Severity with a failure that severe", failOn: "high", failed: 2, bySeverity: map[Severity]int{High: 1, Low: 1}, exceeded: true},
+		{name: "Severity with a more severe failure", failOn: "high", failed: 1, bySeverity: map[Severity]int{Critical: 1}, exceeded: true},
+		{name: "Severity with less severe failures", failOn: "high", failed: 2, bySeverity: map[Severity]int{Medium: 2}, exceeded: false},
+		{name: "Unknown threshold", failOn: "some", expectError: true},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			failOn, err := ParseFailOn(test.failOn)
+			if test.expectError {
+				assert.Error(t, err)
+				return
+			}
+			assert.NoError(t, err)
+			assert.Equal(t, test.exceeded, failOn.Exceeded(Summary{Failed: test.failed, FailedBySeverity: test.bySeverity}))
+		})
+	}
+}
+
+func TestRemediate(t *testing.T) {
+	remediation := &Remediation{Summary: "Enforce the baseline Pod Security Standard"}
+
+	tests := []struct {
+		name              string
+		expect            Behavior
+		succeeded         bool
+		expectRemediation bool
+	}{
+		{name: "Expected blocked, observed allowed", expect: Blocked, succeeded: true, expectRemediation: true},
+		{name: "Expected blocked, observed blocked", expect: Blocked, succeeded: false, expectRemediation: false},
+		{name: "Expected allowed, observed allowed", expect: Allowed, succeeded: true, expectRemediation: true},
+		{name: "Expected allowed, observed blocked", expect: Allowed, succeeded: false, expectRemediation: false},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			v := NewLegacy("privileged", "Experiment", "MITRE", "Tactic", "Technique")
+			if test.succeeded {
+				v.Success("hostPid")
+			} else {
+				v.Fail("hostPid")
+			}
+			v.GetOutcome().Evaluate(test.expect)
+			v.GetOutcome().Remediate(remediation)
+			if test.expectRemediation {
+				assert.Equal(t, remediation, v.GetOutcome().Remediation)
+			} else {
+				assert.Nil(t, v.GetOutcome().Remediation)
+			}
+		})
+	}
+}
@@ -10,6 +10,10 @@ const (
 	Success = "success"
 	// Fail is the string used to represent a failed experiment
 	Fail = "fail"
+	// BlockedResult is the string used to represent an experiment whose attack was refused by the cluster
+	BlockedResult = "blocked"
+	// DetectedResult is the string used to represent an experiment whose attack went through but was detected
+	DetectedResult = "detected"
 )
 
 // Generic Verifier that can handle any type
@@ -89,6 +93,24 @@ func (v *Verifier[T]) Fail(experiment string) {
 	}
 }
 
+// Blocked marks an experiment as blocked
+func (v *Verifier[T]) Blocked(experiment string) {
+	if experiment == "" {
+		v.outcome.Result[v.outcome.Experiment] = BlockedResult
+	} else {
+		v.outcome.Result[experiment] = BlockedResult
+	}
+}
+
+// Detected marks an experiment as detected
+func (v *Verifier[T]) Detected(experiment string) {
+	if experiment == "" {
+		v.outcome.Result[v.outcome.Experiment] = DetectedResult
+	} else {
+		v.outcome.Result[experiment] = DetectedResult
+	}
+}
+
 // GetOutcome returns the typed Outcome of the Verifier
 func (v *Verifier[T]) GetOutcome() *Outcome[T] {
 	return v.outcome
@@ -98,8 +120,8 @@ func (v *Verifier[T]) GetOutcome() *Outcome[T] {
 func (r *Outcome[T]) GetResultString() string {
 	b := new(bytes.Buffer)
 	for name, result := range r.Result {
-		if result == Success {
-			fmt.Fprintf(b, "%s: %s\n", name, Success)
+		if result == Success || result == DetectedResult || result == BlockedResult {
+			fmt.Fprintf(b, "%s: %s\n", name, result)
 			continue
 		}
 		fmt.Fprintf(b, "%s: %s\n", name, Fail)
@@ -129,6 +151,19 @@ type LegacyOutcome struct {
 	Technique     string                   `json:"technique" yaml:"technique"`
 	Result        map[string]string        `json:"result" yaml:"result"`
 	ResultOutputs map[string][]interface{} `json:"result_outputs" yaml:"resultOutputs"`
+	Expect        Behavior                 `json:"expect,omitempty" yaml:"expect,omitempty"`
+	Observed      Behavior                 `json:"observed,omitempty" yaml:"observed,omitempty"`
+	Verdict       Verdict                  `json:"verdict,omitempty" yaml:"verdict,omitempty"`
+	// Source is the file the experiment was defined in
+	Source string `json:"source,omitempty" yaml:"source,omitempty"`
+	// Suppressions accept the risk of tests missing their expectation, keyed by test, or by an empty
+	// string for all tests of the experiment
+	Suppressions map[string]*Suppression `json:"suppressions,omitempty" yaml:"suppressions,omitempty"`
+	// Severity and RiskScore rank the experiment against the others, the higher the score the worse the finding
+	Severity  Severity `json:"severity,omitempty" yaml:"severity,omitempty"`
+	RiskScore int      `json:"riskScore" yaml:"riskScore"`
+	// Remediation is what to change so the attack no longer goes through, only set if the attack got through
+	Remediation *Remediation `json:"remediation,omitempty" yaml:"remediation,omitempty"`
 }
 
 func NewLegacy(experiment, description, framework, tactic, technique string) *LegacyVerifier {
@@ -165,6 +200,22 @@ func (v *LegacyVerifier) Fail(experiment string) {
 	}
 }
 
+func (v *LegacyVerifier) Blocked(experiment string) {
+	if experiment == "" {
+		v.outcome.Result[v.outcome.Experiment] = BlockedResult
+	} else {
+		v.outcome.Result[experiment] = BlockedResult
+	}
+}
+
+func (v *LegacyVerifier) Detected(experiment string) {
+	if experiment == "" {
+		v.outcome.Result[v.outcome.Experiment] = DetectedResult
+	} else {
+		v.outcome.Result[experiment] = DetectedResult
+	}
+}
+
 func (v *LegacyVerifier) GetOutcome() *LegacyOutcome {
 	return v.outcome
 }
@@ -172,8 +223,8 @@ func (v *LegacyVerifier) GetOutcome() *LegacyOutcome {
 func (r *LegacyOutcome) GetResultString() string {
 	b := new(bytes.Buffer)
 	for name, result := range r.Result {
-		if result == Success {
-			fmt.Fprintf(b, "%s: %s\n", name, Success)
+		if result == Success || result == DetectedResult || result == BlockedResult {
+			fmt.Fprintf(b, "%s: %s\n", name, result)
 			continue
 		}
 		fmt.Fprintf(b, "%s: %s\n", name, Fail)